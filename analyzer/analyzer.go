@@ -0,0 +1,360 @@
+// Package analyzer provides a [golang.org/x/tools/go/analysis] analyzer that reports common misuse
+// of [github.com/glenvan/ttl/v2] at build time, rather than leaving it to be discovered at runtime
+// as a goroutine leak or a deadlock.
+//
+// The analyzer reports:
+//
+//   - a Map that is assigned to a local variable and never closed, when it was not given a context
+//     that could stop its pruning goroutine;
+//   - a call to a method of a Map from within a [ttl.Map.Range], [ttl.Map.RangeContext],
+//     [ttl.Map.DeleteFunc] or [ttl.Map.DeleteFuncContext] callback on the same Map, which deadlocks
+//     because the Map's lock is held while the callback runs;
+//   - a constant zero or negative prune interval passed to [ttl.NewMap] or [ttl.NewMapContext],
+//     which disables background pruning.
+//
+// It can be run standalone using the ttlvet command in the cmd/ttlvet directory, or combined with
+// other analyzers using a multichecker.
+package analyzer
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const ttlPath = "github.com/glenvan/ttl/v2"
+
+// Analyzer reports common misuse of ttl.Map.
+var Analyzer = &analysis.Analyzer{
+	Name:     "ttlvet",
+	Doc:      "report common misuse of github.com/glenvan/ttl/v2 maps",
+	URL:      "https://pkg.go.dev/github.com/glenvan/ttl/v2/analyzer",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// lockingCallbacks are the Map methods that hold the Map's lock while calling their callback.
+var lockingCallbacks = map[string]bool{
+	"Range":             true,
+	"RangeContext":      true,
+	"DeleteFunc":        true,
+	"DeleteFuncContext": true,
+}
+
+// lockFreeMethods are the Map methods that may safely be called while the Map's lock is held.
+var lockFreeMethods = map[string]bool{
+	"Close":        true,
+	"AlsoCancelOn": true,
+	"Length":       true,
+}
+
+// closers are the Map methods that stop, or arrange to stop, the Map's pruning goroutine.
+var closers = map[string]bool{
+	"Close":        true,
+	"CloseWait":    true,
+	"AlsoCancelOn": true,
+}
+
+// pruneIntervalArg is the index of the prune interval argument of the positional constructors.
+var pruneIntervalArg = map[string]int{
+	"NewMap":        2,
+	"NewMapContext": 3,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+
+		if fn := ttlFunc(pass, call); fn != nil {
+			checkPruneInterval(pass, call, fn)
+			return
+		}
+
+		if method, recv := mapMethod(pass, call); lockingCallbacks[method] {
+			checkCallback(pass, call, method, recv)
+		}
+	})
+
+	insp.Preorder([]ast.Node{(*ast.FuncDecl)(nil)}, func(n ast.Node) {
+		if fd := n.(*ast.FuncDecl); fd.Body != nil {
+			checkUnclosed(pass, fd.Body)
+		}
+	})
+
+	return nil, nil
+}
+
+// ttlFunc returns the package-level function of the ttl package called by call, or nil.
+func ttlFunc(pass *analysis.Pass, call *ast.CallExpr) *types.Func {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != ttlPath {
+		return nil
+	}
+
+	if fn.Type().(*types.Signature).Recv() != nil {
+		return nil
+	}
+
+	return fn
+}
+
+// mapMethod returns the name of the ttl.Map method called by call and the expression it is called
+// on, or an empty name if call is not a call to a ttl.Map method.
+func mapMethod(pass *analysis.Pass, call *ast.CallExpr) (string, ast.Expr) {
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return "", nil
+	}
+
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || !isMap(fn.Type().(*types.Signature).Recv()) {
+		return "", nil
+	}
+
+	return fn.Name(), sel.X
+}
+
+// isMap reports whether v is a ttl.Map or a pointer to one.
+func isMap(v *types.Var) bool {
+	if v == nil {
+		return false
+	}
+
+	t := v.Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == ttlPath && obj.Name() == "Map"
+}
+
+// checkPruneInterval reports a constant, non-positive prune interval passed to a positional
+// constructor.
+func checkPruneInterval(pass *analysis.Pass, call *ast.CallExpr, fn *types.Func) {
+	i, ok := pruneIntervalArg[fn.Name()]
+	if !ok || i >= len(call.Args) {
+		return
+	}
+
+	if nonPositive(pass, call.Args[i]) {
+		pass.Reportf(call.Args[i].Pos(),
+			"non-positive prune interval passed to ttl.%s disables background pruning, so expired "+
+				"items are never removed unless they are deleted or stored over; use ttl.New with "+
+				"ttl.WithPruneInterval(0) if this is intended", fn.Name())
+	}
+}
+
+// nonPositive reports whether expr is a constant that is zero or negative.
+func nonPositive(pass *analysis.Pass, expr ast.Expr) bool {
+	tv, ok := pass.TypesInfo.Types[expr]
+	if !ok || tv.Value == nil {
+		return false
+	}
+
+	return constant.Sign(tv.Value) <= 0
+}
+
+// checkCallback reports calls, within the callback passed to a locking method such as Range, to
+// methods of the same Map that would deadlock.
+func checkCallback(pass *analysis.Pass, call *ast.CallExpr, method string, recv ast.Expr) {
+	if len(call.Args) == 0 {
+		return
+	}
+
+	lit, ok := ast.Unparen(call.Args[len(call.Args)-1]).(*ast.FuncLit)
+	if !ok {
+		return
+	}
+
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.GoStmt:
+			// Running the operation in a new goroutine is the documented workaround
+			return false
+
+		case *ast.CallExpr:
+			inner, innerRecv := mapMethod(pass, n)
+			if inner == "" || lockFreeMethods[inner] || !sameExpr(pass, recv, innerRecv) {
+				return true
+			}
+
+			pass.Reportf(n.Pos(),
+				"call to %s.%s inside the %s callback deadlocks because %s holds the Map's lock; "+
+					"call it from a new goroutine or after %s returns",
+				types.ExprString(innerRecv), inner, method, method, method)
+		}
+
+		return true
+	})
+}
+
+// sameExpr reports whether a and b refer to the same Map. Identifiers are compared by the object
+// they refer to and other expressions are compared textually.
+func sameExpr(pass *analysis.Pass, a, b ast.Expr) bool {
+	a, b = ast.Unparen(a), ast.Unparen(b)
+
+	if ai, ok := a.(*ast.Ident); ok {
+		bi, ok := b.(*ast.Ident)
+		return ok && pass.TypesInfo.ObjectOf(ai) == pass.TypesInfo.ObjectOf(bi)
+	}
+
+	return types.ExprString(a) == types.ExprString(b)
+}
+
+// checkUnclosed reports Maps that are constructed and assigned to a local variable in body, are
+// never closed and do not escape body.
+func checkUnclosed(pass *analysis.Pass, body *ast.BlockStmt) {
+	type candidate struct {
+		call *ast.CallExpr
+		lhs  *ast.Ident
+	}
+
+	var candidates []candidate
+
+	add := func(lhs ast.Expr, rhs ast.Expr) {
+		id, ok := lhs.(*ast.Ident)
+		if !ok || id.Name == "_" {
+			return
+		}
+
+		if call, ok := ast.Unparen(rhs).(*ast.CallExpr); ok && leaksWithoutClose(pass, call) {
+			candidates = append(candidates, candidate{call: call, lhs: id})
+		}
+	}
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if len(n.Lhs) == len(n.Rhs) {
+				for i := range n.Lhs {
+					add(n.Lhs[i], n.Rhs[i])
+				}
+			} else if len(n.Rhs) == 1 {
+				// m, err := ttl.NewMapWithConfig(...)
+				add(n.Lhs[0], n.Rhs[0])
+			}
+
+		case *ast.ValueSpec:
+			if len(n.Names) == len(n.Values) {
+				for i := range n.Names {
+					add(n.Names[i], n.Values[i])
+				}
+			} else if len(n.Values) == 1 {
+				add(n.Names[0], n.Values[0])
+			}
+		}
+
+		return true
+	})
+
+	for _, c := range candidates {
+		obj := pass.TypesInfo.ObjectOf(c.lhs)
+		if obj == nil || obj.Parent() == obj.Pkg().Scope() {
+			continue
+		}
+
+		if !closedOrEscapes(pass, body, obj, c.lhs) {
+			pass.Reportf(c.call.Pos(),
+				"ttl map %s is never closed and its pruning goroutine will leak; call %s.Close or "+
+					"provide a context that is cancelled", c.lhs.Name, c.lhs.Name)
+		}
+	}
+}
+
+// leaksWithoutClose reports whether call constructs a Map that starts a pruning goroutine which
+// is only stopped by closing the Map.
+func leaksWithoutClose(pass *analysis.Pass, call *ast.CallExpr) bool {
+	fn := ttlFunc(pass, call)
+	if fn == nil {
+		return false
+	}
+
+	switch fn.Name() {
+	case "NewMap":
+		return len(call.Args) <= pruneIntervalArg["NewMap"] ||
+			!nonPositive(pass, call.Args[pruneIntervalArg["NewMap"]])
+
+	case "NewMapContext", "NewMapWithConfig":
+		return len(call.Args) > 0 && isBackground(pass, call.Args[0])
+
+	case "New":
+		for _, arg := range call.Args {
+			if opt, ok := ast.Unparen(arg).(*ast.CallExpr); ok {
+				if f := ttlFunc(pass, opt); f != nil && f.Name() == "WithContext" {
+					return false
+				}
+			}
+		}
+		return call.Ellipsis == 0
+	}
+
+	return false
+}
+
+// isBackground reports whether expr is a call to context.Background or context.TODO, neither of
+// which is ever cancelled.
+func isBackground(pass *analysis.Pass, expr ast.Expr) bool {
+	call, ok := ast.Unparen(expr).(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != "context" {
+		return false
+	}
+
+	return fn.Name() == "Background" || fn.Name() == "TODO"
+}
+
+// closedOrEscapes reports whether the Map held by obj is closed within body, or is used in a way
+// that lets it escape body (for example, by being returned, passed to a function, or assigned to
+// something else), in which case it may be closed elsewhere.
+func closedOrEscapes(pass *analysis.Pass, body *ast.BlockStmt, obj types.Object, def *ast.Ident) bool {
+	selected := make(map[*ast.Ident]bool)
+	closed := false
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+
+		if id, ok := ast.Unparen(sel.X).(*ast.Ident); ok && pass.TypesInfo.ObjectOf(id) == obj {
+			selected[id] = true
+			closed = closed || closers[sel.Sel.Name]
+		}
+
+		return true
+	})
+
+	if closed {
+		return true
+	}
+
+	escapes := false
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if ok && id != def && !selected[id] && pass.TypesInfo.Uses[id] == obj {
+			escapes = true
+		}
+
+		return !escapes
+	})
+
+	return escapes
+}
@@ -0,0 +1,15 @@
+// Command ttlvet reports common misuse of github.com/glenvan/ttl/v2 maps.
+//
+// Usage:
+//
+//	go run github.com/glenvan/ttl/v2/analyzer/cmd/ttlvet ./...
+package main
+
+import (
+	"github.com/glenvan/ttl/v2/analyzer"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
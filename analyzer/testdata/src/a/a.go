@@ -0,0 +1,99 @@
+package a
+
+import (
+	"context"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+)
+
+var global = ttl.NewMap[string, int](time.Minute, 0, time.Second, true)
+
+func unclosed() {
+	m := ttl.NewMap[string, int](time.Minute, 0, time.Second, true) // want `ttl map m is never closed`
+	m.Store("a", 1)
+
+	n := ttl.New[string, int](ttl.WithTTL(time.Minute)) // want `ttl map n is never closed`
+	n.Store("a", 1)
+
+	b := ttl.NewMapContext[string, int](context.Background(), time.Minute, 0, time.Second, true) // want `ttl map b is never closed`
+	b.Store("a", 1)
+
+	c, _ := ttl.NewMapWithConfig[string, int](context.TODO(), ttl.Config{}) // want `ttl map c is never closed`
+	c.Store("a", 1)
+}
+
+func closed(ctx context.Context) *ttl.Map[string, int] {
+	m := ttl.NewMap[string, int](time.Minute, 0, time.Second, true)
+	defer m.Close()
+
+	n := ttl.NewMap[string, int](time.Minute, 0, time.Second, true)
+	n.AlsoCancelOn(ctx)
+
+	withContext := ttl.New[string, int](ttl.WithContext(ctx))
+	withContext.Store("a", 1)
+
+	mapContext := ttl.NewMapContext[string, int](ctx, time.Minute, 0, time.Second, true)
+	mapContext.Store("a", 1)
+
+	returned := ttl.NewMap[string, int](time.Minute, 0, time.Second, true)
+	return returned
+}
+
+func passed() {
+	m := ttl.NewMap[string, int](time.Minute, 0, time.Second, true)
+	use(m)
+
+	var assigned *ttl.Map[string, int]
+	assigned = ttl.NewMap[string, int](time.Minute, 0, time.Second, true)
+	global = assigned
+}
+
+func use(*ttl.Map[string, int]) {}
+
+func pruneInterval() {
+	m := ttl.NewMap[string, int](time.Minute, 0, 0, true) // want `non-positive prune interval passed to ttl.NewMap`
+	m.Store("a", 1)
+
+	const interval = -time.Second
+	n := ttl.NewMapContext[string, int](context.Background(), time.Minute, 0, interval, true) // want `non-positive prune interval passed to ttl.NewMapContext`
+	defer n.Close()
+
+	var d time.Duration
+	o := ttl.NewMap[string, int](time.Minute, 0, d, true)
+	defer o.Close()
+}
+
+func callbacks(ctx context.Context, other *ttl.Map[string, int]) {
+	m := ttl.NewMap[string, int](time.Minute, 0, time.Second, true)
+	defer m.Close()
+
+	m.Range(func(key string, value int) bool {
+		m.Store(key, value+1) // want `call to m.Store inside the Range callback deadlocks`
+		m.Load(key)           // want `call to m.Load inside the Range callback deadlocks`
+		other.Store(key, value)
+
+		go m.Delete(key)
+		go func() {
+			m.Delete(key)
+		}()
+
+		return true
+	})
+
+	m.DeleteFunc(func(key string, value int) bool {
+		_, ok := m.Load(key + "x") // want `call to m.Load inside the DeleteFunc callback deadlocks`
+		return ok && m.Length() > 1
+	})
+
+	_ = m.RangeContext(ctx, func(key string, value int) bool {
+		m.Close()
+		return true
+	})
+
+	s := struct{ m *ttl.Map[string, int] }{m: other}
+	s.m.Range(func(key string, value int) bool {
+		s.m.Delete(key) // want `call to s.m.Delete inside the Range callback deadlocks`
+		return true
+	})
+}
@@ -0,0 +1,60 @@
+// Package ttl is a minimal stand-in for github.com/glenvan/ttl/v2, declaring only what the
+// analyzer tests need.
+package ttl
+
+import (
+	"context"
+	"time"
+)
+
+type Map[K comparable, V any] struct{}
+
+type Option func()
+
+type Config struct{}
+
+func NewMap[K comparable, V any](
+	defaultTTL time.Duration,
+	length int,
+	pruneInterval time.Duration,
+	refreshOnLoad bool,
+	opts ...Option,
+) *Map[K, V] {
+	return nil
+}
+
+func NewMapContext[K comparable, V any](
+	ctx context.Context,
+	defaultTTL time.Duration,
+	length int,
+	pruneInterval time.Duration,
+	refreshOnLoad bool,
+	opts ...Option,
+) *Map[K, V] {
+	return nil
+}
+
+func New[K comparable, V any](opts ...Option) *Map[K, V] { return nil }
+
+func NewMapWithConfig[K comparable, V any](ctx context.Context, config Config) (*Map[K, V], error) {
+	return nil, nil
+}
+
+func WithContext(ctx context.Context) Option { return nil }
+
+func WithTTL(TTL time.Duration) Option { return nil }
+
+func (m *Map[K, V]) Close()                            {}
+func (m *Map[K, V]) CloseWait()                        {}
+func (m *Map[K, V]) AlsoCancelOn(ctx context.Context)  {}
+func (m *Map[K, V]) Length() int                       { return 0 }
+func (m *Map[K, V]) Load(key K) (value V, ok bool)     { return }
+func (m *Map[K, V]) Store(key K, value V)              {}
+func (m *Map[K, V]) Delete(key K)                      {}
+func (m *Map[K, V]) Range(f func(key K, value V) bool) {}
+
+func (m *Map[K, V]) DeleteFunc(del func(key K, value V) bool) {}
+
+func (m *Map[K, V]) RangeContext(ctx context.Context, f func(key K, value V) bool) error {
+	return nil
+}
@@ -0,0 +1,64 @@
+package ttl
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// background runs the prune goroutine of a container that is not built on a [Map], such as a
+// [List], and stops it when the container is closed or its context is done.
+type background struct {
+	closed atomic.Bool
+	stop   chan bool
+	done   chan struct{}
+}
+
+func newBackground() background {
+	return background{
+		stop: make(chan bool),
+		done: make(chan struct{}),
+	}
+}
+
+// start starts a goroutine that calls prune with the time of the clock of o every prune interval
+// of o, until the container is closed or the context of o is done. If background pruning is
+// disabled, no goroutine is started.
+func (b *background) start(o options, prune func(now time.Time)) {
+	if o.pruneInterval <= 0 {
+		close(b.done)
+		return
+	}
+
+	// The ticker is created before the goroutine starts, like the prune ticker of a Map
+	ticker := o.clock.NewTicker(o.jitteredPruneInterval())
+
+	go func() {
+		defer close(b.done)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-o.ctx.Done():
+				b.close()
+				return
+			case <-b.stop:
+				return
+			case <-ticker.C():
+				prune(o.clock.Now())
+			}
+		}
+	}()
+}
+
+// close stops the prune goroutine. It may be called multiple times.
+func (b *background) close() {
+	if b.closed.CompareAndSwap(false, true) {
+		close(b.stop)
+	}
+}
+
+// closeWait stops the prune goroutine, then waits for it to exit.
+func (b *background) closeWait() {
+	b.close()
+	<-b.done
+}
@@ -0,0 +1,36 @@
+package ttl
+
+import (
+	"time"
+)
+
+// TTLValue is a value along with its time to live, as stored by [Map.StoreManyWithTTL].
+type TTLValue[V any] struct {
+	Value V
+
+	// TTL is the time to live of the value. A zero or negative TTL means it never expires.
+	TTL time.Duration
+}
+
+// StoreMany stores each of entries as [Map.Store] would, acquiring the lock on the [Map] once for
+// the whole batch rather than once per entry, for example to warm the [Map] up. Eviction, callbacks
+// and write-through happen once every entry has been stored. StoreMany is safe for concurrent use.
+func (m *Map[K, V]) StoreMany(entries map[K]V) {
+	m.lock()
+	defer m.unlock()
+
+	for key, value := range entries {
+		m.storeLocked(key, value)
+	}
+}
+
+// StoreManyWithTTL is like [Map.StoreMany], storing each of entries with its own time to live as
+// [Map.StoreWithTTL] would. StoreManyWithTTL is safe for concurrent use.
+func (m *Map[K, V]) StoreManyWithTTL(entries map[K]TTLValue[V]) {
+	m.lock()
+	defer m.unlock()
+
+	for key, e := range entries {
+		m.storeWithTTLLocked(key, e.Value, e.TTL)
+	}
+}
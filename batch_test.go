@@ -0,0 +1,90 @@
+package ttl_test
+
+import (
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestStoreMany() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	m := ttl.New[int, int](ttl.WithClock(clock), ttl.WithTTL(time.Minute), ttl.WithPruneInterval(0))
+	defer m.Close()
+
+	entries := make(map[int]int)
+	for i := 0; i < 100; i++ {
+		entries[i] = i * 10
+	}
+
+	m.StoreWithTTL(0, -1, time.Hour)
+	m.StoreMany(entries)
+	s.Equal(100, m.Length())
+
+	v, ok := m.Load(42)
+	s.True(ok)
+	s.Equal(420, v)
+
+	// Existing keys keep their TTL, as with Store
+	remaining, _ := m.TTL(0)
+	s.Equal(time.Hour, remaining)
+	remaining, _ = m.TTL(1)
+	s.Equal(time.Minute, remaining)
+
+	m.StoreManyWithTTL(map[int]ttl.TTLValue[int]{
+		1:   {Value: 11, TTL: time.Second},
+		200: {Value: 2000},
+	})
+
+	remaining, _ = m.TTL(1)
+	s.Equal(time.Second, remaining)
+	remaining, _ = m.TTL(200)
+	s.Equal(ttl.NoExpiry, remaining)
+
+	m.StoreMany(nil)
+	s.Equal(101, m.Length())
+}
+
+func (s *MapTestSuite) TestStoreManyEvicts() {
+	var evicted int
+
+	m := ttl.New[int, int](
+		ttl.WithMaxEntries(10),
+		ttl.WithPruneInterval(0),
+		ttl.WithOnRemove(func(_ int, _ int, reason ttl.RemovalReason) {
+			if reason == ttl.ReasonEvicted {
+				evicted++
+			}
+		}))
+	defer m.Close()
+
+	entries := make(map[int]int)
+	for i := 0; i < 100; i++ {
+		entries[i] = i
+	}
+
+	m.StoreMany(entries)
+	s.Equal(10, m.Length())
+	s.Equal(90, evicted)
+}
+
+func (s *MapTestSuite) TestShardedStoreMany() {
+	m := ttl.NewShardedMap[int, int](ttl.WithShards(4), ttl.WithPruneInterval(0))
+	defer m.Close()
+
+	entries := make(map[int]int)
+	ttlEntries := make(map[int]ttl.TTLValue[int])
+	for i := 0; i < 100; i++ {
+		entries[i] = i
+		ttlEntries[i+100] = ttl.TTLValue[int]{Value: i, TTL: time.Hour}
+	}
+
+	m.StoreMany(entries)
+	m.StoreManyWithTTL(ttlEntries)
+	s.Equal(200, m.Length())
+
+	v, ok := m.Load(150)
+	s.True(ok)
+	s.Equal(50, v)
+}
@@ -0,0 +1,310 @@
+// Package boltstore provides a [ttl.Store] backed by a bucket of a [bbolt] database, so that the
+// values stored in a [ttl.Map] can be written through to a local file and used to warm the Map
+// when a process restarts:
+//
+//	db, err := bolt.Open("cache.db", 0o600, nil)
+//	...
+//	store, err := boltstore.New[string, Session](db, "sessions")
+//	...
+//	m := ttl.New[string, Session](ttl.WithWriteThrough[string, Session](store))
+//	defer m.Close()
+//
+//	err = m.Warm(ctx, store)
+//
+// The in-memory Map remains the read path; the database is only read by [ttl.Map.Warm].
+//
+// [bbolt]: https://pkg.go.dev/go.etcd.io/bbolt
+package boltstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Codec encodes keys or values of type T to bytes for storage.
+type Codec[T any] interface {
+	Marshal(v T) ([]byte, error)
+	Unmarshal(data []byte, v *T) error
+}
+
+// GobCodec is a [Codec] using encoding/gob. It is the default for both keys and values.
+type GobCodec[T any] struct{}
+
+func (GobCodec[T]) Marshal(v T) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (GobCodec[T]) Unmarshal(data []byte, v *T) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// JSONCodec is a [Codec] using encoding/json.
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Marshal(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec[T]) Unmarshal(data []byte, v *T) error {
+	return json.Unmarshal(data, v)
+}
+
+// Option configures a [Store].
+type Option func(*options)
+
+type options struct {
+	keys   any // Codec[K]
+	values any // Codec[V]
+	clock  ttl.Clock
+}
+
+// WithKeyCodec sets the [Codec] used to encode keys. Keys must always encode to the same bytes, so
+// a codec for a key type containing maps should not be used. The default is [GobCodec].
+//
+// codec must use the same key type as the [Store], otherwise New panics.
+func WithKeyCodec[K any](codec Codec[K]) Option {
+	return func(o *options) {
+		o.keys = codec
+	}
+}
+
+// WithValueCodec sets the [Codec] used to encode values. The default is [GobCodec].
+//
+// codec must use the same value type as the [Store], otherwise New panics.
+func WithValueCodec[V any](codec Codec[V]) Option {
+	return func(o *options) {
+		o.values = codec
+	}
+}
+
+// WithClock sets the clock used to expire entries, which should be the same clock as the Map's.
+// The default is the system clock.
+func WithClock(clock ttl.Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// Store is a [ttl.RangeStore] that stores entries in a bucket of a bbolt database. Each entry is
+// stored with its expiration time; expired entries are ignored by Get and Range, and removed by
+// Prune. Writes from concurrent goroutines are combined into a single transaction using
+// [bolt.DB.Batch]. Store is safe for concurrent use.
+type Store[K comparable, V any] struct {
+	db     *bolt.DB
+	bucket []byte
+	keys   Codec[K]
+	values Codec[V]
+	clock  ttl.Clock
+}
+
+// New returns a Store that keeps its entries in the named bucket of db, creating the bucket if it
+// does not exist. The caller remains responsible for closing db, after any Map writing to the
+// Store has been closed.
+func New[K comparable, V any](db *bolt.DB, bucket string, opts ...Option) (*Store[K, V], error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := &Store[K, V]{
+		db:     db,
+		bucket: []byte(bucket),
+		keys:   GobCodec[K]{},
+		values: GobCodec[V]{},
+		clock:  o.clock,
+	}
+
+	if o.keys != nil {
+		s.keys = typedOption[Codec[K]]("WithKeyCodec", o.keys)
+	}
+
+	if o.values != nil {
+		s.values = typedOption[Codec[V]]("WithValueCodec", o.values)
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(s.bucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// typedOption asserts that the option named name was given a value of type T, panicking with a
+// descriptive message if it was not.
+func typedOption[T any](name string, v any) T {
+	t, ok := v.(T)
+	if !ok {
+		panic(fmt.Sprintf("boltstore: %s given %T, which does not match the Store's type", name, v))
+	}
+
+	return t
+}
+
+func (s *Store[K, V]) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+
+	return s.clock.Now()
+}
+
+// Get implements [ttl.Store].
+func (s *Store[K, V]) Get(ctx context.Context, key K) (value V, TTL time.Duration, ok bool, err error) {
+	k, err := s.keys.Marshal(key)
+	if err != nil {
+		return
+	}
+
+	err = s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(s.bucket).Get(k)
+		if data == nil {
+			return nil
+		}
+
+		var live bool
+		if TTL, live = s.remaining(data); !live {
+			return nil
+		}
+
+		ok = true
+		return s.values.Unmarshal(data[8:], &value)
+	})
+
+	return
+}
+
+// Set implements [ttl.Store].
+func (s *Store[K, V]) Set(ctx context.Context, key K, value V, TTL time.Duration) error {
+	k, err := s.keys.Marshal(key)
+	if err != nil {
+		return err
+	}
+
+	v, err := s.values.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	var expireAt int64
+	if TTL > 0 {
+		expireAt = s.now().Add(TTL).UnixNano()
+	}
+
+	data := binary.BigEndian.AppendUint64(make([]byte, 0, 8+len(v)), uint64(expireAt))
+	data = append(data, v...)
+
+	return s.db.Batch(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).Put(k, data)
+	})
+}
+
+// Delete implements [ttl.Store].
+func (s *Store[K, V]) Delete(ctx context.Context, key K) error {
+	k, err := s.keys.Marshal(key)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Batch(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).Delete(k)
+	})
+}
+
+// Range implements [ttl.RangeStore]. f is called within a read-only transaction, so it must not
+// write to the Store.
+func (s *Store[K, V]) Range(ctx context.Context, f func(key K, value V, TTL time.Duration) bool) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(s.bucket).Cursor()
+
+		for k, data := c.First(); k != nil; k, data = c.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			TTL, live := s.remaining(data)
+			if !live {
+				continue
+			}
+
+			var key K
+			if err := s.keys.Unmarshal(k, &key); err != nil {
+				return err
+			}
+
+			var value V
+			if err := s.values.Unmarshal(data[8:], &value); err != nil {
+				return err
+			}
+
+			if !f(key, value, TTL) {
+				return nil
+			}
+		}
+
+		return nil
+	})
+}
+
+// Prune deletes the expired entries from the Store, returning the number deleted.
+func (s *Store[K, V]) Prune(ctx context.Context) (pruned int, err error) {
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(s.bucket).Cursor()
+
+		for k, data := c.First(); k != nil; {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if _, live := s.remaining(data); live {
+				k, data = c.Next()
+				continue
+			}
+
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			pruned++
+
+			// Deleting moves the cursor to the next entry
+			k, data = c.Seek(k)
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// remaining returns the time to live remaining for an encoded entry, which is zero if it never
+// expires, and whether it has not expired.
+func (s *Store[K, V]) remaining(data []byte) (TTL time.Duration, live bool) {
+	if len(data) < 8 {
+		return 0, false
+	}
+
+	expireAt := int64(binary.BigEndian.Uint64(data))
+	if expireAt == 0 {
+		return 0, true
+	}
+
+	TTL = time.Unix(0, expireAt).Sub(s.now())
+
+	return TTL, TTL > 0
+}
@@ -0,0 +1,114 @@
+package boltstore_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/boltstore"
+	"github.com/glenvan/ttl/v2/ttltest"
+	"github.com/stretchr/testify/suite"
+	bolt "go.etcd.io/bbolt"
+)
+
+type StoreTestSuite struct {
+	suite.Suite
+
+	db    *bolt.DB
+	clock *ttltest.Clock
+}
+
+func TestStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(StoreTestSuite))
+}
+
+func (s *StoreTestSuite) SetupTest() {
+	db, err := bolt.Open(filepath.Join(s.T().TempDir(), "cache.db"), 0o600, nil)
+	s.Require().NoError(err)
+
+	s.db = db
+	s.clock = ttltest.NewClock(time.Unix(1700000000, 0))
+}
+
+func (s *StoreTestSuite) TearDownTest() {
+	s.NoError(s.db.Close())
+}
+
+func (s *StoreTestSuite) TestSetGetDelete() {
+	ctx := context.Background()
+
+	store, err := boltstore.New[string, []int](s.db, "test", boltstore.WithClock(s.clock))
+	s.Require().NoError(err)
+
+	s.NoError(store.Set(ctx, "a", []int{1, 2}, time.Minute))
+	s.NoError(store.Set(ctx, "b", []int{3}, 0))
+
+	v, TTL, ok, err := store.Get(ctx, "a")
+	s.NoError(err)
+	s.True(ok)
+	s.Equal([]int{1, 2}, v)
+	s.Equal(time.Minute, TTL)
+
+	s.clock.Advance(time.Minute)
+
+	_, _, ok, err = store.Get(ctx, "a")
+	s.NoError(err)
+	s.False(ok)
+
+	_, TTL, ok, _ = store.Get(ctx, "b")
+	s.True(ok)
+	s.Zero(TTL)
+
+	pruned, err := store.Prune(ctx)
+	s.NoError(err)
+	s.Equal(1, pruned)
+
+	s.NoError(store.Delete(ctx, "b"))
+	_, _, ok, _ = store.Get(ctx, "b")
+	s.False(ok)
+}
+
+func (s *StoreTestSuite) TestWarmAfterRestart() {
+	ctx := context.Background()
+
+	store, err := boltstore.New[int, string](s.db, "test",
+		boltstore.WithClock(s.clock),
+		boltstore.WithKeyCodec[int](boltstore.JSONCodec[int]{}))
+	s.Require().NoError(err)
+
+	tm := ttl.New[int, string](
+		ttl.WithClock(s.clock),
+		ttl.WithTTL(time.Hour),
+		ttl.WithPruneInterval(0),
+		ttl.WithWriteThrough[int, string](store))
+
+	for i := 0; i < 100; i++ {
+		tm.Store(i, "value")
+	}
+	tm.StoreWithTTL(100, "short", time.Second)
+	tm.Delete(0)
+	tm.Close()
+
+	s.clock.Advance(time.Minute)
+
+	restarted := ttl.New[int, string](
+		ttl.WithClock(s.clock),
+		ttl.WithPruneInterval(0),
+		ttl.WithWriteThrough[int, string](store))
+	defer restarted.Close()
+
+	s.NoError(restarted.Warm(ctx, store))
+	s.Equal(99, restarted.Length())
+
+	remaining, ok := restarted.TTL(1)
+	s.True(ok)
+	s.Equal(59*time.Minute, remaining)
+}
+
+func (s *StoreTestSuite) TestCodecTypeMismatch() {
+	s.Panics(func() {
+		_, _ = boltstore.New[int, string](s.db, "test", boltstore.WithKeyCodec[string](boltstore.JSONCodec[string]{}))
+	})
+}
@@ -0,0 +1,18 @@
+module github.com/glenvan/ttl/v2/boltstore
+
+go 1.21
+
+require (
+	github.com/glenvan/ttl/v2 v2.1.0
+	github.com/stretchr/testify v1.8.4
+	go.etcd.io/bbolt v1.3.10
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/glenvan/ttl/v2 => ../
@@ -0,0 +1,63 @@
+package ttl
+
+import (
+	"time"
+)
+
+// Clock is the source of time used by a [Map] to expire items and schedule background pruning. The
+// default measures time using the system's monotonic clock, so that expiry is not affected by
+// changes to the wall clock. Tests may provide a fake clock using [WithClock] so that they can
+// advance time instead of sleeping; see ttltest.Clock.
+//
+// A Clock must be safe for concurrent use.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTicker returns a [Ticker] that ticks every d, like [time.NewTicker].
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks of a [Clock] at intervals, like [time.Ticker].
+type Ticker interface {
+	// C returns the channel on which the ticks are delivered.
+	C() <-chan time.Time
+
+	// Stop turns off the ticker. No more ticks are sent after Stop returns.
+	Stop()
+}
+
+// processStart is the time at which the package was initialized, which the system clock measures
+// time from.
+var processStart = time.Now()
+
+// systemClock is a Clock that uses the system clock. Now is measured from processStart using the
+// monotonic clock, so a wall clock step (for example, by NTP or an administrator) cannot expire
+// every item at once, or stop items from expiring. The times it reports therefore drift from the
+// wall clock by the total of any steps since the process started.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return processStart.Add(time.Since(processStart))
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+type systemTicker struct {
+	t *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t systemTicker) Stop() {
+	t.t.Stop()
+}
+
+// now returns the current time of the map's clock, expressed in Unix nanoseconds.
+func (m *Map[K, V]) now() int64 {
+	return m.clock.Now().UnixNano()
+}
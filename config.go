@@ -0,0 +1,95 @@
+package ttl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidConfig is the error wrapped by every [ConfigError].
+var ErrInvalidConfig = errors.New("ttl: invalid configuration")
+
+// ConfigError describes an invalid field in a [Config]. It wraps [ErrInvalidConfig].
+type ConfigError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("ttl: invalid %s: %s", e.Field, e.Reason)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return ErrInvalidConfig
+}
+
+// Config describes a [Map] for [NewMapWithConfig]. The zero value of each field selects its
+// default.
+type Config struct {
+	// TTL is the default time to live of items in the [Map]. Defaults to [DefaultTTL]. Must not
+	// be negative.
+	TTL time.Duration
+
+	// PruneInterval is how often expired items are removed. Defaults to [DefaultPruneInterval].
+	// Must not be negative.
+	PruneInterval time.Duration
+
+	// Capacity is the number of items the [Map] initially allocates space for. Must not be
+	// negative.
+	Capacity int
+
+	// DisablePruning disables background pruning, in which case PruneInterval is ignored.
+	DisablePruning bool
+
+	// DisableRefreshOnLoad stops [Map.Load] from extending the lifetime of the items it loads.
+	DisableRefreshOnLoad bool
+
+	// Options configures any additional behaviour. They are applied after the fields above.
+	Options []Option
+}
+
+// Validate reports the first invalid field of c as a [*ConfigError], or nil if c is valid.
+func (c *Config) Validate() error {
+	switch {
+	case c.TTL < 0:
+		return &ConfigError{"TTL", fmt.Sprintf("%s is negative", c.TTL)}
+	case c.PruneInterval < 0:
+		return &ConfigError{"PruneInterval", fmt.Sprintf("%s is negative", c.PruneInterval)}
+	case c.Capacity < 0:
+		return &ConfigError{"Capacity", fmt.Sprintf("%d is negative", c.Capacity)}
+	}
+
+	return nil
+}
+
+// NewMapWithConfig validates config and returns a new [Map] configured by it, filling in defaults
+// for any zero fields. If config is invalid, NewMapWithConfig returns a [*ConfigError] and no
+// [Map].
+//
+// If ctx is cancelled, the pruning process will automatically stop as if [Map.Close] had been
+// called. [Map] objects returned by NewMapWithConfig must otherwise be closed with [Map.Close]
+// when they're no longer needed.
+func NewMapWithConfig[K comparable, V any](ctx context.Context, config Config) (*Map[K, V], error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	opts := []Option{
+		WithContext(ctx),
+		WithCapacity(config.Capacity),
+		WithRefreshOnLoad(!config.DisableRefreshOnLoad),
+	}
+
+	if config.TTL > 0 {
+		opts = append(opts, WithTTL(config.TTL))
+	}
+
+	if config.DisablePruning {
+		opts = append(opts, WithPruneInterval(0))
+	} else if config.PruneInterval > 0 {
+		opts = append(opts, WithPruneInterval(config.PruneInterval))
+	}
+
+	return New[K, V](append(opts, config.Options...)...), nil
+}
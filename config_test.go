@@ -0,0 +1,84 @@
+package ttl_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+)
+
+func (s *MapTestSuite) TestNewMapWithConfig() {
+	tm, err := ttl.NewMapWithConfig[string, int](context.Background(), ttl.Config{
+		TTL:                  s.maxTTL,
+		PruneInterval:        s.pruneInterval,
+		Capacity:             s.startSize,
+		DisableRefreshOnLoad: true,
+	})
+	if !s.NoError(err) {
+		return
+	}
+	defer tm.Close()
+
+	tm.Store("key", 1)
+
+	doneCh := make(chan struct{})
+
+	go func() {
+		for start := time.Now(); time.Since(start) < s.sleepTime; {
+			time.Sleep(50 * time.Millisecond)
+			tm.Load("key")
+		}
+		close(doneCh)
+	}()
+
+	<-doneCh
+
+	s.Zero(tm.Length())
+}
+
+func (s *MapTestSuite) TestNewMapWithConfigDefaults() {
+	tm, err := ttl.NewMapWithConfig[string, int](context.Background(), ttl.Config{})
+	if !s.NoError(err) {
+		return
+	}
+	defer tm.Close()
+
+	tm.Store("key", 1)
+
+	remaining, ok := tm.TTL("key")
+	if s.True(ok) {
+		s.Greater(remaining, ttl.DefaultTTL-time.Second)
+	}
+}
+
+func (s *MapTestSuite) TestNewMapWithConfigInvalid() {
+	for _, config := range []ttl.Config{
+		{TTL: -time.Second},
+		{PruneInterval: -time.Second},
+		{Capacity: -1},
+	} {
+		tm, err := ttl.NewMapWithConfig[string, int](context.Background(), config)
+		s.Nil(tm)
+		s.ErrorIs(err, ttl.ErrInvalidConfig)
+
+		var configErr *ttl.ConfigError
+		s.ErrorAs(err, &configErr)
+	}
+}
+
+func (s *MapTestSuite) TestNewMapWithConfigDisablePruning() {
+	tm, err := ttl.NewMapWithConfig[string, int](context.Background(), ttl.Config{
+		TTL:            s.pruneInterval,
+		DisablePruning: true,
+	})
+	if !s.NoError(err) {
+		return
+	}
+	defer tm.Close()
+
+	tm.Store("key", 1)
+
+	time.Sleep(2 * s.pruneInterval)
+
+	s.Equal(1, tm.Length())
+}
@@ -0,0 +1,238 @@
+package ttl
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Counter counts events per key over a sliding window: each contribution made with
+// [Counter.Incr] is counted until the TTL of the Counter has elapsed since it was made, so
+// [Counter.Count] returns the total of the last TTL. This is the core of rate limiting and abuse
+// detection.
+//
+// The window of each key is divided into buckets (see [WithCounterBuckets]), so the memory used by
+// a key does not grow with the number of contributions, and a contribution stops being counted up
+// to one bucket's width before its TTL has elapsed. Keys whose count falls to zero are removed by
+// the prune pass. A TTL of zero or less means contributions are counted forever.
+//
+// A Counter is configured using the same [Option]s as a [Map]. [WithContext], [WithTTL],
+// [WithPruneInterval], [WithPruneJitter], [WithClock], [WithCapacity] and [WithCounterBuckets]
+// apply; other options are ignored.
+//
+// Counter is safe for concurrent use.
+type Counter[K comparable] struct {
+	clock   Clock
+	width   int64 // the width of a bucket in nanoseconds, or zero if counts never expire
+	buckets int
+
+	mtx    sync.Mutex
+	counts map[K]*counterEntry
+
+	bg background
+}
+
+// counterEntry is the sliding window of a key.
+type counterEntry struct {
+	buckets []int64 // ring of counts, indexed by bucket number modulo its length
+	latest  int64   // the number of the most recent bucket, in bucket widths since the Unix epoch
+	total   int64   // the sum of buckets
+}
+
+// NewCounter returns a new, empty [Counter] configured by opts, like [New].
+func NewCounter[K comparable](opts ...Option) *Counter[K] {
+	o := defaultOptions()
+	o.apply(opts)
+	o.logMisconfiguration()
+
+	c := &Counter[K]{
+		clock:   o.clock,
+		buckets: max(o.buckets, 1),
+		counts:  make(map[K]*counterEntry, max(o.capacity, 0)),
+		bg:      newBackground(),
+	}
+
+	if o.ttl > 0 {
+		c.width = max(int64(o.ttl)/int64(c.buckets), 1)
+	} else {
+		c.buckets = 1
+	}
+
+	c.bg.start(o, func(now time.Time) {
+		c.prune(now)
+	})
+
+	return c
+}
+
+// Incr adds n to the count of key, returning its new count. n may be negative, to undo an earlier
+// contribution. Incr is safe for concurrent use.
+func (c *Counter[K]) Incr(key K, n int64) int64 {
+	bucket := c.bucket(c.clock.Now())
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	e, ok := c.counts[key]
+	if !ok {
+		e = &counterEntry{buckets: make([]int64, c.buckets), latest: bucket}
+		c.counts[key] = e
+	}
+
+	e.advance(bucket)
+	e.buckets[bucket%int64(len(e.buckets))] += n
+	e.total += n
+
+	return e.total
+}
+
+// Count returns the count of key over the last TTL, or zero if the key has no count. Count is safe
+// for concurrent use.
+func (c *Counter[K]) Count(key K) int64 {
+	bucket := c.bucket(c.clock.Now())
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	e, ok := c.counts[key]
+	if !ok {
+		return 0
+	}
+
+	e.advance(bucket)
+
+	return e.total
+}
+
+// Reset removes the count of key. Reset is safe for concurrent use.
+func (c *Counter[K]) Reset(key K) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	delete(c.counts, key)
+}
+
+// Length returns the number of keys in the [Counter], including any whose count has fallen to zero
+// but which have not been pruned yet. Length is safe for concurrent use.
+func (c *Counter[K]) Length() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return len(c.counts)
+}
+
+// Range calls f sequentially for each key with a non-zero count, with that count. If f returns
+// false, Range stops the iteration. Range iterates over a copy of the counts taken when it is
+// called, so f may modify the [Counter]. Range is safe for concurrent use.
+func (c *Counter[K]) Range(f func(key K, count int64) bool) {
+	_ = c.RangeContext(context.Background(), f)
+}
+
+// RangeContext is like [Counter.Range], but stops and returns ctx.Err() if ctx is done before the
+// iteration completes.
+func (c *Counter[K]) RangeContext(ctx context.Context, f func(key K, count int64) bool) error {
+	type count struct {
+		key   K
+		count int64
+	}
+
+	bucket := c.bucket(c.clock.Now())
+
+	c.mtx.Lock()
+
+	counts := make([]count, 0, len(c.counts))
+	for key, e := range c.counts {
+		if e.advance(bucket); e.total != 0 {
+			counts = append(counts, count{key, e.total})
+		}
+	}
+
+	c.mtx.Unlock()
+
+	for _, kc := range counts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !f(kc.key, kc.count) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Prune removes the keys whose count has fallen to zero, returning the number removed. Prune is
+// safe for concurrent use.
+func (c *Counter[K]) Prune() int {
+	return c.prune(c.clock.Now())
+}
+
+// Clear removes the counts of every key. Clear is safe for concurrent use.
+func (c *Counter[K]) Clear() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	clear(c.counts)
+}
+
+// Close stops the [Counter] from pruning in the background. If Close is not called on a [Counter]
+// after it's no longer needed, its prune goroutine will leak (unless the context has been
+// cancelled). Close may be called multiple times.
+func (c *Counter[K]) Close() {
+	c.bg.close()
+}
+
+// CloseWait is like [Counter.Close], but also waits for the prune goroutine to exit.
+func (c *Counter[K]) CloseWait() {
+	c.bg.closeWait()
+}
+
+// prune removes the keys whose count has fallen to zero as of now.
+func (c *Counter[K]) prune(now time.Time) (pruned int) {
+	bucket := c.bucket(now)
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for key, e := range c.counts {
+		if e.advance(bucket); e.total == 0 {
+			delete(c.counts, key)
+			pruned++
+		}
+	}
+
+	return
+}
+
+// bucket returns the number of the bucket that holds contributions made at now.
+func (c *Counter[K]) bucket(now time.Time) int64 {
+	if c.width == 0 {
+		return 0
+	}
+
+	return now.UnixNano() / c.width
+}
+
+// advance moves the window of e forward to bucket, discarding the counts of the buckets that have
+// left it.
+func (e *counterEntry) advance(bucket int64) {
+	n := int64(len(e.buckets))
+
+	if bucket <= e.latest {
+		return
+	}
+
+	if bucket-e.latest >= n {
+		clear(e.buckets)
+		e.total = 0
+	} else {
+		for b := e.latest + 1; b <= bucket; b++ {
+			i := b % n
+			e.total -= e.buckets[i]
+			e.buckets[i] = 0
+		}
+	}
+
+	e.latest = bucket
+}
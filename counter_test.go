@@ -0,0 +1,68 @@
+package ttl_test
+
+import (
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestCounter() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	c := ttl.NewCounter[string](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0),
+		ttl.WithCounterBuckets(6))
+	defer c.Close()
+
+	s.Equal(int64(1), c.Incr("a", 1))
+	s.Equal(int64(3), c.Incr("a", 2))
+	c.Incr("b", 5)
+
+	clock.Advance(30 * time.Second)
+	s.Equal(int64(4), c.Incr("a", 1))
+	s.Equal(int64(5), c.Count("b"))
+
+	// The first contributions leave the window after a minute
+	clock.Advance(30 * time.Second)
+	s.Equal(int64(1), c.Count("a"))
+	s.Zero(c.Count("b"))
+
+	counts := make(map[string]int64)
+	c.Range(func(key string, count int64) bool {
+		counts[key] = count
+		return true
+	})
+	s.Equal(map[string]int64{"a": 1}, counts)
+
+	s.Equal(1, c.Prune())
+	s.Equal(1, c.Length())
+
+	clock.Advance(time.Hour)
+	s.Zero(c.Count("a"))
+	s.Equal(1, c.Prune())
+
+	c.Incr("c", 1)
+	c.Reset("c")
+	s.Zero(c.Count("c"))
+
+	c.Incr("d", 1)
+	c.Clear()
+	s.Zero(c.Length())
+}
+
+func (s *MapTestSuite) TestCounterWithoutTTL() {
+	c := ttl.NewCounter[int](
+		ttl.WithTTL(0),
+		ttl.WithPruneInterval(0))
+	defer c.Close()
+
+	for i := 0; i < 10; i++ {
+		c.Incr(i%2, 1)
+	}
+
+	s.Equal(int64(5), c.Count(0))
+	s.Equal(int64(-5), c.Incr(1, -10))
+}
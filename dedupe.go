@@ -0,0 +1,69 @@
+package ttl
+
+import (
+	"time"
+)
+
+// Deduper records the keys it has seen for the TTL, so that duplicates of a message or request
+// (for example, a webhook delivered more than once, or a retried request carrying an idempotency
+// key) can be detected and dropped.
+//
+// A Deduper is configured using the same [Option]s as a [Map]. Unless opts include
+// [WithRefreshOnLoad], a key is remembered for the TTL from when it was first seen, however often
+// it is seen again.
+//
+// Deduper is safe for concurrent use.
+type Deduper[K comparable] struct {
+	m *Map[K, struct{}]
+}
+
+// NewDeduper returns a new [Deduper] configured by opts, like [New].
+func NewDeduper[K comparable](opts ...Option) *Deduper[K] {
+	return &Deduper[K]{
+		m: New[K, struct{}](append([]Option{WithRefreshOnLoad(false)}, opts...)...),
+	}
+}
+
+// FirstSeen records key and reports whether it had not been seen within the TTL. The check and the
+// record are atomic, so of concurrent calls with the same new key, exactly one returns true.
+// FirstSeen is safe for concurrent use.
+func (d *Deduper[K]) FirstSeen(key K) bool {
+	_, seen := d.m.LoadOrStore(key, struct{}{})
+	return !seen
+}
+
+// Seen reports whether key has been seen within the TTL, without recording it. Seen is safe for
+// concurrent use.
+func (d *Deduper[K]) Seen(key K) bool {
+	_, ok := d.m.LoadPassive(key)
+	return ok
+}
+
+// TTL returns the time remaining before key is forgotten, as well as a bool indicating whether it
+// has been seen, like [Map.TTL]. TTL is safe for concurrent use.
+func (d *Deduper[K]) TTL(key K) (remaining time.Duration, ok bool) {
+	return d.m.TTL(key)
+}
+
+// Forget forgets key, so that it is new again, for example if handling the message it identifies
+// failed and it should be accepted when it is retried. Forget is safe for concurrent use.
+func (d *Deduper[K]) Forget(key K) {
+	d.m.Delete(key)
+}
+
+// Length returns the number of keys remembered by the [Deduper], like [Map.Length]. Length is safe
+// for concurrent use.
+func (d *Deduper[K]) Length() int {
+	return d.m.Length()
+}
+
+// Close stops the [Deduper] from pruning, like [Map.Close].
+func (d *Deduper[K]) Close() {
+	d.m.Close()
+}
+
+// CloseWait is like [Deduper.Close], but also waits for the prune goroutine to exit, like
+// [Map.CloseWait].
+func (d *Deduper[K]) CloseWait() {
+	d.m.CloseWait()
+}
@@ -0,0 +1,67 @@
+package ttl_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestDeduper() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	d := ttl.NewDeduper[string](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0))
+	defer d.Close()
+
+	s.False(d.Seen("a"))
+	s.True(d.FirstSeen("a"))
+	s.False(d.FirstSeen("a"))
+	s.True(d.Seen("a"))
+
+	// Seeing a key again does not extend how long it is remembered
+	clock.Advance(30 * time.Second)
+	s.False(d.FirstSeen("a"))
+	remaining, ok := d.TTL("a")
+	s.True(ok)
+	s.Equal(30*time.Second, remaining)
+
+	clock.Advance(30 * time.Second)
+	s.True(d.FirstSeen("a"))
+
+	d.Forget("a")
+	s.True(d.FirstSeen("a"))
+
+	// Exactly one of concurrent calls with a new key sees it first
+	var first atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if d.FirstSeen("b") {
+				first.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	s.Equal(int64(1), first.Load())
+}
+
+func (s *MapTestSuite) TestLoadOrStore() {
+	tm := s.newShardedMap()
+	defer tm.Close()
+
+	actual, loaded := tm.LoadOrStore(1, 10)
+	s.False(loaded)
+	s.Equal(10, actual)
+
+	actual, loaded = tm.LoadOrStore(1, 20)
+	s.True(loaded)
+	s.Equal(10, actual)
+}
@@ -0,0 +1,216 @@
+package ttl
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// errNotConstructed is returned when decoding into a Map that was not created by a constructor,
+// such as a new Map allocated by encoding/json or encoding/gob for a nil pointer.
+var errNotConstructed = errors.New("ttl: cannot decode into a Map that was not created by a constructor")
+
+// encodedEntry is the encoding of a single entry of a [Map], shared by its JSON and gob encodings.
+// TTL and Remaining are encoded in nanoseconds. Remaining is omitted for entries that never expire.
+type encodedEntry[K comparable, V any] struct {
+	Key       K             `json:"key"`
+	Value     V             `json:"value"`
+	TTL       time.Duration `json:"ttl"`
+	Remaining time.Duration `json:"remaining,omitempty"`
+	Policy    RefreshPolicy `json:"policy,omitempty"`
+	Pinned    bool          `json:"pinned,omitempty"`
+}
+
+// encodedMap is the encoding of a [Map].
+type encodedMap[K comparable, V any] struct {
+	Entries []encodedEntry[K, V] `json:"entries"`
+}
+
+// encodeSnapshot returns the entries of s along with the time to live each had remaining when s
+// was taken.
+func encodeSnapshot[K comparable, V any](s Snapshot[K, V]) encodedMap[K, V] {
+	em := encodedMap[K, V]{Entries: make([]encodedEntry[K, V], 0, len(s.Entries))}
+
+	for _, e := range s.Entries {
+		em.Entries = append(em.Entries, encodeEntry(e, s.Taken))
+	}
+
+	return em
+}
+
+// encodeEntry returns e along with the time to live it had remaining when its snapshot was taken.
+func encodeEntry[K comparable, V any](e SnapshotEntry[K, V], taken time.Time) encodedEntry[K, V] {
+	ee := encodedEntry[K, V]{
+		Key:    e.Key,
+		Value:  e.Value,
+		TTL:    e.TTL,
+		Policy: e.Policy,
+		Pinned: e.Pinned,
+	}
+
+	if e.TTL > 0 {
+		ee.Remaining = e.TTL - taken.Sub(e.LastAccess)
+	}
+
+	return ee
+}
+
+// decodeSnapshot returns a Snapshot of em taken at now, such that restoring it with RebaseResume
+// gives each entry the time to live it had remaining when it was encoded.
+func decodeSnapshot[K comparable, V any](em encodedMap[K, V], now time.Time) Snapshot[K, V] {
+	s := Snapshot[K, V]{
+		Taken:   now,
+		Entries: make([]SnapshotEntry[K, V], 0, len(em.Entries)),
+	}
+
+	for _, ee := range em.Entries {
+		s.Entries = append(s.Entries, decodeEntry(ee, now))
+	}
+
+	return s
+}
+
+// decodeEntry returns ee as an entry of a snapshot taken at now.
+func decodeEntry[K comparable, V any](ee encodedEntry[K, V], now time.Time) SnapshotEntry[K, V] {
+	e := SnapshotEntry[K, V]{
+		Key:        ee.Key,
+		Value:      ee.Value,
+		TTL:        ee.TTL,
+		LastAccess: now,
+		Policy:     ee.Policy,
+		Pinned:     ee.Pinned,
+	}
+
+	if ee.TTL > 0 {
+		e.LastAccess = now.Add(ee.Remaining - ee.TTL)
+	}
+
+	return e
+}
+
+// restoreEncoded restores em into the Map with RebaseResume.
+func (m *Map[K, V]) restoreEncoded(em encodedMap[K, V]) {
+	m.Restore(decodeSnapshot(em, m.clock.Now()), RebasePolicy{Mode: RebaseResume})
+}
+
+// restoreEncoded is like [Map.restoreEncoded].
+func (s *ShardedMap[K, V]) restoreEncoded(em encodedMap[K, V]) {
+	s.Restore(decodeSnapshot(em, s.shards[0].clock.Now()), RebasePolicy{Mode: RebaseResume})
+}
+
+// MarshalJSON implements [json.Marshaler]. It encodes the unexpired entries of the [Map] as a JSON
+// object holding an array of entries, each with its key, value, TTL and the time to live it has
+// remaining, so that the [Map] can be saved and later restored with [Map.UnmarshalJSON]. Keys and
+// values are encoded using encoding/json, so they may be of any type it supports, and need not be
+// strings.
+//
+// Like [Map.Snapshot], MarshalJSON does not update the last access time of any entry.
+func (m *Map[K, V]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(encodeSnapshot(m.Snapshot()))
+}
+
+// UnmarshalJSON implements [json.Unmarshaler]. It stores the entries encoded by [Map.MarshalJSON]
+// in the [Map], each with the time to live it had remaining when it was encoded, counted from the
+// time of the call. Decoded entries replace any existing entries with the same key, and entries
+// that had expired are skipped.
+//
+// The [Map] must have been created by a constructor such as [New], so a *Map field must be
+// initialized before decoding into it.
+func (m *Map[K, V]) UnmarshalJSON(data []byte) error {
+	if m.m == nil {
+		return errNotConstructed
+	}
+
+	var em encodedMap[K, V]
+	if err := json.Unmarshal(data, &em); err != nil {
+		return err
+	}
+
+	m.restoreEncoded(em)
+
+	return nil
+}
+
+// MarshalJSON is like [Map.MarshalJSON].
+func (s *ShardedMap[K, V]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(encodeSnapshot(s.Snapshot()))
+}
+
+// UnmarshalJSON is like [Map.UnmarshalJSON].
+func (s *ShardedMap[K, V]) UnmarshalJSON(data []byte) error {
+	if len(s.shards) == 0 {
+		return errNotConstructed
+	}
+
+	var em encodedMap[K, V]
+	if err := json.Unmarshal(data, &em); err != nil {
+		return err
+	}
+
+	s.restoreEncoded(em)
+
+	return nil
+}
+
+// GobEncode implements [gob.GobEncoder]. It encodes the unexpired entries of the [Map] like
+// [Map.MarshalJSON], so that the [Map] can be sent over gob-based RPC or saved with existing gob
+// tooling and later restored with [Map.GobDecode]. Keys and values are encoded using encoding/gob,
+// so interface types held in them must be registered with [gob.Register].
+func (m *Map[K, V]) GobEncode() ([]byte, error) {
+	return gobEncode(encodeSnapshot(m.Snapshot()))
+}
+
+// GobDecode implements [gob.GobDecoder]. It stores the entries encoded by [Map.GobEncode] in the
+// [Map] like [Map.UnmarshalJSON], so the [Map] must have been created by a constructor such as
+// [New].
+func (m *Map[K, V]) GobDecode(data []byte) error {
+	if m.m == nil {
+		return errNotConstructed
+	}
+
+	em, err := gobDecode[K, V](data)
+	if err != nil {
+		return err
+	}
+
+	m.restoreEncoded(em)
+
+	return nil
+}
+
+// GobEncode is like [Map.GobEncode].
+func (s *ShardedMap[K, V]) GobEncode() ([]byte, error) {
+	return gobEncode(encodeSnapshot(s.Snapshot()))
+}
+
+// GobDecode is like [Map.GobDecode].
+func (s *ShardedMap[K, V]) GobDecode(data []byte) error {
+	if len(s.shards) == 0 {
+		return errNotConstructed
+	}
+
+	em, err := gobDecode[K, V](data)
+	if err != nil {
+		return err
+	}
+
+	s.restoreEncoded(em)
+
+	return nil
+}
+
+func gobEncode[K comparable, V any](em encodedMap[K, V]) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(em); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func gobDecode[K comparable, V any](data []byte) (em encodedMap[K, V], err error) {
+	err = gob.NewDecoder(bytes.NewReader(data)).Decode(&em)
+	return
+}
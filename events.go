@@ -0,0 +1,203 @@
+package ttl
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Entry is a key and value of a [Map], along with the state of its time to live, as returned by
+// [Map.Entries]. The entries sent on the channel returned by [Map.Expired] only have their Key and
+// Value set.
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+
+	// Remaining is the time to live remaining for the entry, or [NoExpiry] if it never expires.
+	Remaining time.Duration
+
+	// LastAccess is the time from which the entry's TTL is counted, as in [EntryInfo].
+	LastAccess time.Time
+
+	// Created is the time the key was first stored, as in [EntryInfo].
+	Created time.Time
+}
+
+// expiredStream is the buffered channel of expired entries returned by [Map.Expired]. It is shared
+// by the shards of a [ShardedMap].
+type expiredStream[K comparable, V any] struct {
+	mtx     sync.Mutex // guards sending on and closing ch
+	ch      chan Entry[K, V]
+	closed  bool
+	dropped atomic.Uint64
+}
+
+func newExpiredStream[K comparable, V any](size int) *expiredStream[K, V] {
+	return &expiredStream[K, V]{ch: make(chan Entry[K, V], size)}
+}
+
+// send sends e without blocking, dropping it if the channel's buffer is full or the channel has
+// been closed.
+func (s *expiredStream[K, V]) send(e Entry[K, V]) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.ch <- e:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// close closes the channel. It may be called multiple times.
+func (s *expiredStream[K, V]) close() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+// Expired returns a channel that receives the key and value of each entry that is removed from the
+// [Map] because it expired, so that they can be persisted or audited. It returns nil, which blocks
+// forever when received from, unless the [Map] was created using [WithExpiredChannel].
+//
+// Entries are sent without blocking, so a slow receiver never delays the prune pass. If the
+// channel's buffer is full when an entry expires, the entry is dropped and counted in
+// [Stats].ExpiredDropped. The channel is closed when the [Map] is closed, after which no more
+// entries are sent.
+func (m *Map[K, V]) Expired() <-chan Entry[K, V] {
+	if m.expired == nil {
+		return nil
+	}
+
+	return m.expired.ch
+}
+
+// watchBuffer is the size of the buffer of each channel returned by [Map.Watch].
+const watchBuffer = 16
+
+// EventKind identifies the kind of change to a key reported by an [Event].
+type EventKind int
+
+const (
+	// EventStored means a value was stored to the key, either adding it or replacing its value.
+	EventStored EventKind = iota
+
+	// EventRemoved means the key was removed from the [Map]. The reason it was removed is given
+	// by [Event].Reason.
+	EventRemoved
+)
+
+// Event is a change to a key watched using [Map.Watch].
+type Event[V any] struct {
+	Kind EventKind
+
+	// Value is the value stored for EventStored, or the value removed for EventRemoved.
+	Value V
+
+	// Reason is the reason the key was removed. It is only meaningful for EventRemoved.
+	Reason RemovalReason
+}
+
+// Watch returns a channel that receives an [Event] each time a value is stored to key or key is
+// removed from the [Map], along with a function that stops watching and closes the channel. The
+// cancel function must be called when the channel is no longer needed, even if the [Map] has been
+// closed. It may be called multiple times.
+//
+// Events are sent without blocking. Each channel buffers up to 16 events, and if it is full the
+// oldest event is dropped to make room, so a slow receiver always sees the most recent changes.
+// Stores that are coalesced (see [WithWriteCoalescing]) do not change the value and are not
+// reported. The old key of a [Map.Rename] is reported as removed with [ReasonDeleted].
+//
+// Watch is safe for concurrent use.
+func (m *Map[K, V]) Watch(key K) (events <-chan Event[V], cancel func()) {
+	ch := make(chan Event[V], watchBuffer)
+
+	m.lock()
+	if m.watchers == nil {
+		m.watchers = make(map[K][]chan Event[V])
+	}
+	m.watchers[key] = append(m.watchers[key], ch)
+	m.unlock()
+
+	var once sync.Once
+
+	cancel = func() {
+		once.Do(func() {
+			m.lock()
+			defer m.unlock()
+
+			watchers := m.watchers[key]
+			for i, w := range watchers {
+				if w == ch {
+					watchers = append(watchers[:i], watchers[i+1:]...)
+					break
+				}
+			}
+
+			if len(watchers) == 0 {
+				delete(m.watchers, key)
+			} else {
+				m.watchers[key] = watchers
+			}
+
+			close(ch)
+		})
+	}
+
+	return ch, cancel
+}
+
+// notifyLocked sends e to the watchers of key, dropping the oldest event of any watcher whose
+// buffer is full. The caller must hold the write lock.
+func (m *Map[K, V]) notifyLocked(key K, e Event[V]) {
+	if len(m.watchers) == 0 {
+		return
+	}
+
+	for _, ch := range m.watchers[key] {
+		for sent := false; !sent; {
+			select {
+			case ch <- e:
+				sent = true
+			default:
+				select {
+				case <-ch:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// storedLocked records that a value is being stored to key, so that its cost can be computed, its
+// watchers sent the value and the value written to the store once it has been written, when the
+// write lock is released. The caller must hold the write lock.
+func (m *Map[K, V]) storedLocked(key K) {
+	if m.costOf != nil || len(m.watchers[key]) > 0 {
+		m.stored = append(m.stored, key)
+	}
+
+	if m.store != nil {
+		m.writes = append(m.writes, write[K, V]{key: key})
+	}
+}
+
+// notifyStoredLocked sends the values stored to the keys recorded by storedLocked to their
+// watchers. The caller must hold the write lock.
+func (m *Map[K, V]) notifyStoredLocked() {
+	for _, key := range m.stored {
+		if it, ok := m.m[key]; ok {
+			m.notifyLocked(key, Event[V]{Kind: EventStored, Value: it.value})
+		}
+	}
+
+	m.stored = nil
+}
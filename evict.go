@@ -0,0 +1,145 @@
+package ttl
+
+// AdmissionPolicy determines whether a new key is stored in a [Map] that is full, as limited by
+// [WithMaxEntries].
+type AdmissionPolicy int
+
+const (
+	// AdmitAll always stores a new key, evicting an existing entry to make room for it.
+	AdmitAll AdmissionPolicy = iota
+
+	// AdmitTinyLFU estimates how often each key is loaded and stored using a compact frequency
+	// sketch, in the manner of TinyLFU. A new key is only stored if it has been used more often
+	// than the entry that would be evicted to make room for it, so a burst of keys that are used
+	// once does not evict frequently used entries. Rejected keys are reported as evicted. The
+	// sketch ages its counts periodically, so keys that become popular are admitted in time.
+	AdmitTinyLFU
+)
+
+const (
+	// evictionSamples is the number of entries sampled to choose each entry to evict.
+	evictionSamples = 5
+
+	// sketchMinEntries is the smallest number of entries the frequency sketch of a map bounded only
+	// by cost is sized for.
+	sketchMinEntries = 1024
+)
+
+// bounded reports whether the capacity of the map is limited by [WithMaxEntries] or
+// [WithMaxCost].
+func (m *Map[K, V]) bounded() bool {
+	return m.maxEntries > 0 || m.maxCost > 0
+}
+
+// resize sets the number of entries the map may hold as for [WithMaxEntries], evicting entries
+// until it is within the new limit, and returns the number evicted.
+func (m *Map[K, V]) resize(maxEntries int) (evicted int) {
+	m.lock()
+	m.maxEntries = maxEntries
+
+	return m.unlock()
+}
+
+// overCapacityLocked reports whether the map holds more entries, or a greater total cost, than it
+// is limited to. The caller must hold the write lock.
+func (m *Map[K, V]) overCapacityLocked() bool {
+	return (m.maxEntries > 0 && len(m.m) > m.maxEntries) || (m.maxCost > 0 && m.totalCost > m.maxCost)
+}
+
+// costStoredLocked updates the cost of the values stored while the write lock was held. The
+// caller must hold the write lock.
+func (m *Map[K, V]) costStoredLocked() {
+	for _, key := range m.stored {
+		if it, ok := m.m[key]; ok {
+			cost := m.costOf(key, it.value)
+			m.totalCost += cost - it.cost
+			it.cost = cost
+		}
+	}
+}
+
+// addedLocked records that key has been added to the map, so that it is a candidate for admission
+// when the map is over capacity and is indexed by prefix and for sampling. The caller must hold the
+// write lock.
+func (m *Map[K, V]) addedLocked(key K) {
+	if m.prefixes != nil {
+		m.prefixes.insert(key)
+	}
+
+	if m.samples != nil {
+		m.samples.insert(key)
+	}
+
+	if m.bounded() {
+		m.added = append(m.added, key)
+	}
+}
+
+// evictLocked evicts entries until the map is within its capacity, returning the number evicted.
+// If an admission policy is set, each key added while the lock was held is only kept if it is used
+// more often than the entry that would be evicted in its place. The caller must hold the write
+// lock.
+func (m *Map[K, V]) evictLocked() (evicted int) {
+	added := m.added
+	m.added = nil
+
+	for m.overCapacityLocked() {
+		victim, expired, ok := m.sampleVictimLocked()
+		if !ok {
+			// Every entry is pinned
+			return
+		}
+
+		if expired {
+			m.removeLocked(victim, ReasonExpired)
+			continue
+		}
+
+		if m.sketch != nil && len(added) > 0 {
+			candidate := added[len(added)-1]
+			added = added[:len(added)-1]
+
+			if _, ok := m.m[candidate]; ok && m.sketch.frequency(candidate) <= m.sketch.frequency(victim) {
+				m.removeLocked(candidate, ReasonEvicted)
+				evicted++
+				continue
+			}
+		}
+
+		m.removeLocked(victim, ReasonEvicted)
+		evicted++
+	}
+
+	return
+}
+
+// sampleVictimLocked chooses an entry to evict by sampling entries of the map and returning the
+// key of the least recently accessed, in the manner of Redis' approximated LRU. If a sampled entry
+// has expired, it is returned immediately along with expired set to true. Pinned entries are never
+// chosen. The caller must hold the write lock.
+func (m *Map[K, V]) sampleVictimLocked() (victim K, expired bool, ok bool) {
+	now := m.now()
+	oldest := int64(0)
+	sampled := 0
+
+	// Map iteration starts at a random position, so this is a random sample
+	for key, it := range m.m {
+		if it.pinned {
+			continue
+		}
+
+		if it.expired(now) {
+			return key, true, true
+		}
+
+		if lastAccess := it.lastAccess.Load(); !ok || lastAccess < oldest {
+			victim, oldest, ok = key, lastAccess, true
+		}
+
+		if sampled++; sampled == evictionSamples {
+			break
+		}
+	}
+
+	return
+}
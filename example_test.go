@@ -53,6 +53,23 @@ func ExampleMap() {
 	// ttl.Map length: 0
 }
 
+func ExampleNew() {
+	tm := ttl.New[string, int](
+		ttl.WithTTL(30*time.Second),
+		ttl.WithPruneInterval(2*time.Second),
+		ttl.WithRefreshOnLoad(false))
+	defer tm.Close()
+
+	tm.Store("answer", 42)
+
+	value, ok := tm.Load("answer")
+	if ok {
+		fmt.Println(value)
+	}
+	// Output:
+	// 42
+}
+
 func ExampleMap_Load() {
 	tm := ttl.NewMap[string, string](30*time.Second, 0, 2*time.Second, true)
 	defer tm.Close()
@@ -0,0 +1,135 @@
+package ttl
+
+import (
+	"container/heap"
+	"time"
+)
+
+// ExpiryList orders keys by their expiration time, so that the keys due to expire can be found
+// without scanning every key. It is the building block for pruning that only touches expired
+// entries, and can be used directly to build custom caches.
+//
+// Setting, removing and popping a key are O(log n). An ExpiryList is not safe for concurrent use.
+type ExpiryList[K comparable] struct {
+	h     expiryHeap[K]
+	index map[K]*expiryEntry[K]
+}
+
+type expiryEntry[K comparable] struct {
+	key      K
+	expireAt int64 // Unix nanoseconds
+	index    int   // position in the heap
+}
+
+// NewExpiryList returns an empty [ExpiryList].
+func NewExpiryList[K comparable]() *ExpiryList[K] {
+	return &ExpiryList[K]{
+		index: make(map[K]*expiryEntry[K]),
+	}
+}
+
+// Length returns the number of keys in the list.
+func (l *ExpiryList[K]) Length() int {
+	return len(l.h)
+}
+
+// Set adds key to the list with the given expiration time, or moves it if it is already present.
+func (l *ExpiryList[K]) Set(key K, expireAt time.Time) {
+	l.set(key, expireAt.UnixNano())
+}
+
+func (l *ExpiryList[K]) set(key K, expireAt int64) {
+	if e, ok := l.index[key]; ok {
+		e.expireAt = expireAt
+		heap.Fix(&l.h, e.index)
+		return
+	}
+
+	e := &expiryEntry[K]{key: key, expireAt: expireAt}
+	l.index[key] = e
+	heap.Push(&l.h, e)
+}
+
+// Remove removes key from the list, returning false if it was not present.
+func (l *ExpiryList[K]) Remove(key K) bool {
+	e, ok := l.index[key]
+	if !ok {
+		return false
+	}
+
+	heap.Remove(&l.h, e.index)
+	delete(l.index, key)
+
+	return true
+}
+
+// ExpireAt returns the expiration time of key, as well as a bool indicating whether it was found.
+func (l *ExpiryList[K]) ExpireAt(key K) (time.Time, bool) {
+	e, ok := l.index[key]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, e.expireAt), true
+}
+
+// Peek returns the key that expires first and its expiration time without removing it. ok is false
+// if the list is empty.
+func (l *ExpiryList[K]) Peek() (key K, expireAt time.Time, ok bool) {
+	if len(l.h) == 0 {
+		return
+	}
+
+	return l.h[0].key, time.Unix(0, l.h[0].expireAt), true
+}
+
+// PopExpired removes and returns the keys whose expiration time is at or before now, soonest
+// first. If limit is positive, at most limit keys are removed.
+func (l *ExpiryList[K]) PopExpired(now time.Time, limit int) []K {
+	return l.popExpired(now.UnixNano(), limit)
+}
+
+func (l *ExpiryList[K]) popExpired(now int64, limit int) (keys []K) {
+	for len(l.h) > 0 && l.h[0].expireAt <= now && (limit <= 0 || len(keys) < limit) {
+		e := heap.Pop(&l.h).(*expiryEntry[K])
+		delete(l.index, e.key)
+		keys = append(keys, e.key)
+	}
+
+	return
+}
+
+// Clear removes all keys from the list.
+func (l *ExpiryList[K]) Clear() {
+	l.h = nil
+	clear(l.index)
+}
+
+// expiryHeap implements heap.Interface as a min-heap of expiration times.
+type expiryHeap[K comparable] []*expiryEntry[K]
+
+func (h expiryHeap[K]) Len() int { return len(h) }
+
+func (h expiryHeap[K]) Less(i, j int) bool { return h[i].expireAt < h[j].expireAt }
+
+func (h expiryHeap[K]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap[K]) Push(x any) {
+	e := x.(*expiryEntry[K])
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *expiryHeap[K]) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+
+	return e
+}
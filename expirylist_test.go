@@ -0,0 +1,130 @@
+package ttl_test
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/glenvan/ttl/v2"
+)
+
+type ExpiryListTestSuite struct {
+	suite.Suite
+
+	now time.Time
+}
+
+func (s *ExpiryListTestSuite) SetupTest() {
+	s.now = time.Unix(1700000000, 0)
+}
+
+func TestExpiryListTestSuite(t *testing.T) {
+	suite.Run(t, new(ExpiryListTestSuite))
+}
+
+func (s *ExpiryListTestSuite) TestPopExpiredInOrder() {
+	l := ttl.NewExpiryList[string]()
+
+	l.Set("c", s.now.Add(3*time.Second))
+	l.Set("a", s.now.Add(1*time.Second))
+	l.Set("d", s.now.Add(4*time.Second))
+	l.Set("b", s.now.Add(2*time.Second))
+
+	s.Equal(4, l.Length())
+
+	key, expireAt, ok := l.Peek()
+	if s.True(ok) {
+		s.Equal("a", key)
+		s.True(expireAt.Equal(s.now.Add(time.Second)))
+	}
+
+	s.Empty(l.PopExpired(s.now, 0))
+	s.Equal([]string{"a", "b", "c"}, l.PopExpired(s.now.Add(3*time.Second), 0))
+	s.Equal(1, l.Length())
+}
+
+func (s *ExpiryListTestSuite) TestPopExpiredLimit() {
+	l := ttl.NewExpiryList[int]()
+
+	for i := 0; i < 10; i++ {
+		l.Set(i, s.now.Add(time.Duration(i)*time.Second))
+	}
+
+	s.Equal([]int{0, 1, 2}, l.PopExpired(s.now.Add(time.Hour), 3))
+	s.Equal([]int{3, 4, 5}, l.PopExpired(s.now.Add(time.Hour), 3))
+	s.Equal(4, l.Length())
+}
+
+func (s *ExpiryListTestSuite) TestSetMovesAndRemove() {
+	l := ttl.NewExpiryList[string]()
+
+	l.Set("a", s.now.Add(time.Second))
+	l.Set("b", s.now.Add(2*time.Second))
+	l.Set("a", s.now.Add(3*time.Second))
+
+	s.Equal(2, l.Length())
+
+	expireAt, ok := l.ExpireAt("a")
+	if s.True(ok) {
+		s.True(expireAt.Equal(s.now.Add(3 * time.Second)))
+	}
+
+	key, _, _ := l.Peek()
+	s.Equal("b", key)
+
+	s.True(l.Remove("b"))
+	s.False(l.Remove("b"))
+
+	_, ok = l.ExpireAt("b")
+	s.False(ok)
+
+	l.Clear()
+	s.Zero(l.Length())
+
+	_, _, ok = l.Peek()
+	s.False(ok)
+}
+
+func (s *ExpiryListTestSuite) TestRandomized() {
+	r := rand.New(rand.NewSource(1))
+	l := ttl.NewExpiryList[int]()
+	want := make(map[int]time.Time)
+
+	for i := 0; i < 5000; i++ {
+		key := r.Intn(200)
+
+		if r.Intn(4) == 0 {
+			_, present := want[key]
+			s.Equal(present, l.Remove(key))
+			delete(want, key)
+			continue
+		}
+
+		at := s.now.Add(time.Duration(r.Intn(10000)) * time.Millisecond)
+		l.Set(key, at)
+		want[key] = at
+	}
+
+	s.Equal(len(want), l.Length())
+
+	deadline := s.now.Add(5 * time.Second)
+	popped := l.PopExpired(deadline, 0)
+
+	var expected []int
+	for key, at := range want {
+		if !at.After(deadline) {
+			expected = append(expected, key)
+		}
+	}
+
+	s.True(slices.IsSortedFunc(popped, func(a, b int) int {
+		return want[a].Compare(want[b])
+	}))
+
+	slices.Sort(popped)
+	slices.Sort(expected)
+	s.Equal(expected, popped)
+}
@@ -0,0 +1,4 @@
+package ttl
+
+// SystemClock exposes the default [Clock] to the tests in package ttl_test.
+var SystemClock Clock = systemClock{}
@@ -0,0 +1,40 @@
+package ttl
+
+import (
+	"expvar"
+)
+
+// PublishExpvar publishes the statistics of m, which may be a [Map] or a [ShardedMap], as an
+// [expvar] variable with the given name, so that they are served at /debug/vars along with the
+// process' other variables. The statistics are collected by calling m.Stats each time the variable
+// is read. The variable holds the length, the hit, miss and store counts (which require
+// [WithStats]) and the hit ratio, and the number of removals by reason. For example:
+//
+//	{"length": 42, "hits": 1000, "misses": 250, "stores": 300, "hit_ratio": 0.8,
+//	 "removals": {"expired": 200, "deleted": 8, "cleared": 0, "evicted": 50, "replaced": 0}}
+//
+// Like [expvar.Publish], PublishExpvar panics if a variable with the same name is already
+// published, so it should be called once per name, typically after creating a long-lived map.
+func PublishExpvar(name string, m interface{ Stats() Stats }) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return expvarStats(m.Stats())
+	}))
+}
+
+// expvarStats returns the representation of s published by PublishExpvar.
+func expvarStats(s Stats) map[string]any {
+	return map[string]any{
+		"length":    s.Length,
+		"hits":      s.Hits,
+		"misses":    s.Misses,
+		"stores":    s.Stores,
+		"hit_ratio": s.HitRatio(),
+		"removals": map[string]uint64{
+			ReasonExpired.String():  s.Removals.Expired,
+			ReasonDeleted.String():  s.Removals.Deleted,
+			ReasonCleared.String():  s.Removals.Cleared,
+			ReasonEvicted.String():  s.Removals.Evicted,
+			ReasonReplaced.String(): s.Removals.Replaced,
+		},
+	}
+}
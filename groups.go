@@ -0,0 +1,93 @@
+package ttl
+
+// StoreInGroup stores value with the default time to live, like [Map.Store], and puts key in group,
+// moving it out of any other group, so that it can be expired along with every other entry of the
+// group using [Map.ExpireGroup] (for example, everything cached for a tenant). A key belongs to at
+// most one group, and stays in it when it is stored again using the other store methods, until it
+// is removed. An empty group takes key out of its group. Groups are independent of the tags set
+// using [Map.StoreTagged]. StoreInGroup is safe for concurrent use.
+func (m *Map[K, V]) StoreInGroup(group string, key K, value V) {
+	m.lock()
+	defer m.unlock()
+
+	m.storeLocked(key, value)
+	m.groupLocked(key, m.m[key], group)
+}
+
+// ExpireGroup forces every entry in group to expire immediately, like [Map.Expire], returning the
+// number of entries expired. Only the keys in the group are visited, not the whole [Map]. Entries
+// that had already expired are not counted. ExpireGroup is safe for concurrent use.
+func (m *Map[K, V]) ExpireGroup(group string) (expired int) {
+	m.lock()
+	defer m.unlock()
+
+	now := m.now()
+
+	for key := range m.groups[group] {
+		it := m.m[key]
+		if it.expired(now) {
+			continue
+		}
+
+		it.dead = true
+		m.recheckLocked(key)
+		expired++
+	}
+
+	return
+}
+
+// groupLocked moves key, whose item is it, to group. The caller must hold the write lock.
+func (m *Map[K, V]) groupLocked(key K, it *mapItem[V], group string) {
+	m.ungroupLocked(key, it)
+
+	if group == "" {
+		return
+	}
+
+	if m.groups == nil {
+		m.groups = make(map[string]map[K]struct{})
+	}
+
+	keys, ok := m.groups[group]
+	if !ok {
+		keys = make(map[K]struct{})
+		m.groups[group] = keys
+	}
+
+	keys[key] = struct{}{}
+	it.group = group
+}
+
+// ungroupLocked removes key, whose item is it, from its group. The caller must hold the write lock.
+func (m *Map[K, V]) ungroupLocked(key K, it *mapItem[V]) {
+	if it.group == "" {
+		return
+	}
+
+	delete(m.groups[it.group], key)
+
+	if len(m.groups[it.group]) == 0 {
+		delete(m.groups, it.group)
+	}
+
+	it.group = ""
+}
+
+// labelLocked adds key, whose item is it, to tags and group, as returned by unlabelLocked. The
+// caller must hold the write lock.
+func (m *Map[K, V]) labelLocked(key K, it *mapItem[V], tags []string, group string) {
+	m.tagLocked(key, it, tags)
+	m.groupLocked(key, it, group)
+}
+
+// unlabelLocked removes key, whose item is it, from its tags and group, returning them. The caller
+// must hold the write lock.
+func (m *Map[K, V]) unlabelLocked(key K, it *mapItem[V]) (tags []string, group string) {
+	tags, group = it.tags, it.group
+
+	m.untagLocked(key, it)
+	m.ungroupLocked(key, it)
+
+	return
+}
@@ -0,0 +1,89 @@
+package ttl_test
+
+import (
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestStoreInGroup() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	m := ttl.New[string, int](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0))
+	defer m.Close()
+
+	has := func(key string) bool {
+		_, ok := m.LoadPassive(key)
+		return ok
+	}
+
+	m.StoreInGroup("tenant:1", "a", 1)
+	m.StoreInGroup("tenant:1", "b", 2)
+	m.StoreInGroup("tenant:2", "c", 3)
+	m.Store("d", 4)
+
+	s.Equal(2, m.ExpireGroup("tenant:1"))
+	_, ok := m.Load("a")
+	s.False(ok)
+	_, ok = m.Load("c")
+	s.True(ok)
+	s.Zero(m.ExpireGroup("tenant:1"))
+
+	// Storing a key again keeps its group, while StoreInGroup moves it
+	m.Store("c", 5)
+	m.StoreInGroup("tenant:1", "e", 6)
+	m.StoreInGroup("tenant:2", "e", 7)
+	s.Zero(m.ExpireGroup("tenant:1"))
+	s.Equal(2, m.ExpireGroup("tenant:2"))
+	s.Equal([]bool{false, false, true}, []bool{has("c"), has("e"), has("d")})
+
+	// Groups are independent of tags
+	m.StoreInGroup("g", "f", 8)
+	m.StoreTagged("f", 9, "g")
+	s.Equal(1, m.DeleteByTag("g"))
+	m.StoreInGroup("g", "f", 10)
+	m.StoreTagged("f", 11, "t")
+	s.Equal(1, m.ExpireGroup("g"))
+
+	// An empty group takes the key out of its group
+	m.StoreInGroup("g", "h", 12)
+	m.StoreInGroup("", "h", 13)
+	s.Zero(m.ExpireGroup("g"))
+
+	// Renamed keys keep their group, and keys replaced by a rename lose theirs
+	m.StoreInGroup("x", "i", 14)
+	m.StoreInGroup("y", "j", 15)
+	m.Rename("i", "j")
+	s.Zero(m.ExpireGroup("y"))
+	s.Equal(1, m.ExpireGroup("x"))
+	s.False(has("j"))
+
+	// Pinned entries are expired too
+	m.StoreInGroup("z", "k", 16)
+	m.Pin("k")
+	clock.Advance(time.Hour)
+	s.Equal(1, m.ExpireGroup("z"))
+	s.False(has("k"))
+}
+
+func (s *MapTestSuite) TestShardedStoreInGroup() {
+	m := ttl.NewShardedMap[int, int](ttl.WithShards(4), ttl.WithPruneInterval(0))
+	defer m.Close()
+
+	for i := 0; i < 100; i++ {
+		m.StoreInGroup("all", i, i)
+	}
+
+	// Keys renamed between shards keep their group
+	for i := 0; i < 100; i++ {
+		m.Rename(i, i+100)
+	}
+
+	s.Equal(100, m.ExpireGroup("all"))
+	s.Equal(100, m.Prune())
+	s.Zero(m.ExpireGroup("all"))
+}
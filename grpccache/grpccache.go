@@ -0,0 +1,206 @@
+// Package grpccache provides gRPC interceptors that cache the responses of unary calls in a
+// [ttl.Map], keyed by method and a hash of the request:
+//
+//	m := ttl.New[string, proto.Message](
+//		ttl.WithTTL(time.Minute),
+//		ttl.WithMaxCost(64<<20, grpccache.Cost))
+//	defer m.Close()
+//
+//	conn, err := grpc.NewClient(target,
+//		grpc.WithUnaryInterceptor(grpccache.UnaryClientInterceptor(m,
+//			grpccache.WithMethodTTL("/pkg.Service/Slow", time.Hour),
+//			grpccache.WithMethodTTL("/pkg.Service/Mutate", 0))))
+//
+// Responses are cached for the TTL of the Map unless overridden for their method, and only
+// successful responses are cached. By default, every unary method is cached, so methods with side
+// effects must be excluded using [WithMethodTTL], and the key does not include metadata, so
+// responses that depend on the caller must be distinguished using [WithKeyFunc].
+package grpccache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// Cost returns the approximate size in bytes of a cached response, including its key, for use as
+// the cost function of ttl.WithMaxCost.
+func Cost(key string, resp proto.Message) int64 {
+	return int64(len(key) + proto.Size(resp))
+}
+
+// KeyFunc returns the cache key of a call to method with req, as well as a bool indicating whether
+// the call may be cached.
+type KeyFunc func(ctx context.Context, method string, req proto.Message) (key string, ok bool)
+
+// Option configures an interceptor.
+type Option func(*options)
+
+type options struct {
+	keyFunc    KeyFunc
+	methodTTLs map[string]time.Duration
+}
+
+// WithKeyFunc sets the function that returns the cache key of a call. The default is [Key].
+func WithKeyFunc(f KeyFunc) Option {
+	return func(o *options) {
+		o.keyFunc = f
+	}
+}
+
+// WithMethodTTL sets the time to live of the responses of method, which is the full method name,
+// such as "/pkg.Service/Method", overriding the TTL of the Map. A zero or negative TTL means the
+// responses of method are not cached.
+func WithMethodTTL(method string, TTL time.Duration) Option {
+	return func(o *options) {
+		o.methodTTLs[method] = TTL
+	}
+}
+
+// Key returns the method followed by the SHA-256 hash of the deterministic encoding of req, as
+// well as a bool indicating whether req could be encoded.
+func Key(_ context.Context, method string, req proto.Message) (key string, ok bool) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", false
+	}
+
+	sum := sha256.Sum256(data)
+
+	return method + " " + hex.EncodeToString(sum[:]), true
+}
+
+// UnaryClientInterceptor returns a client interceptor that serves the responses of unary calls
+// from m, invoking the call and caching its response if m holds none. m remains owned by the
+// caller, who must close it once the interceptor is no longer used.
+func UnaryClientInterceptor(
+	m *ttl.Map[string, proto.Message],
+	opts ...Option,
+) grpc.UnaryClientInterceptor {
+	c := newCache(m, opts)
+
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		callOpts ...grpc.CallOption,
+	) error {
+		key, ok := c.key(ctx, method, req)
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+
+		out, isMessage := reply.(proto.Message)
+		if !isMessage {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+
+		if cached, ok := m.LoadPassive(key); ok {
+			proto.Reset(out)
+			proto.Merge(out, cached)
+
+			return nil
+		}
+
+		if err := invoker(ctx, method, req, reply, cc, callOpts...); err != nil {
+			return err
+		}
+
+		c.store(key, method, out)
+
+		return nil
+	}
+}
+
+// UnaryServerInterceptor returns a server interceptor that serves the responses of unary calls
+// from m, calling the handler and caching its response if m holds none. m remains owned by the
+// caller, who must close it once the interceptor is no longer used.
+func UnaryServerInterceptor(
+	m *ttl.Map[string, proto.Message],
+	opts ...Option,
+) grpc.UnaryServerInterceptor {
+	c := newCache(m, opts)
+
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		key, ok := c.key(ctx, info.FullMethod, req)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		// Each call gets its own copy of the response, which gRPC may use after the interceptor
+		// returns
+		if cached, ok := m.LoadPassive(key); ok {
+			return proto.Clone(cached), nil
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+
+		if out, ok := resp.(proto.Message); ok {
+			c.store(key, info.FullMethod, out)
+		}
+
+		return resp, nil
+	}
+}
+
+// cache holds the configuration shared by the interceptors.
+type cache struct {
+	m          *ttl.Map[string, proto.Message]
+	keyFunc    KeyFunc
+	methodTTLs map[string]time.Duration
+}
+
+func newCache(m *ttl.Map[string, proto.Message], opts []Option) *cache {
+	o := options{
+		keyFunc:    Key,
+		methodTTLs: make(map[string]time.Duration),
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &cache{
+		m:          m,
+		keyFunc:    o.keyFunc,
+		methodTTLs: o.methodTTLs,
+	}
+}
+
+// key returns the cache key of a call, as well as a bool indicating whether it may be cached.
+func (c *cache) key(ctx context.Context, method string, req any) (string, bool) {
+	if TTL, ok := c.methodTTLs[method]; ok && TTL <= 0 {
+		return "", false
+	}
+
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return "", false
+	}
+
+	return c.keyFunc(ctx, method, msg)
+}
+
+// store caches a copy of resp, so that later changes to resp by the caller are not seen.
+func (c *cache) store(key string, method string, resp proto.Message) {
+	if TTL, ok := c.methodTTLs[method]; ok {
+		c.m.StoreWithTTL(key, proto.Clone(resp), TTL)
+	} else {
+		c.m.Store(key, proto.Clone(resp))
+	}
+}
@@ -0,0 +1,170 @@
+package grpccache_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/grpccache"
+	"github.com/glenvan/ttl/v2/ttltest"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type InterceptorTestSuite struct {
+	suite.Suite
+
+	clock *ttltest.Clock
+	m     *ttl.Map[string, proto.Message]
+	calls int
+}
+
+func TestInterceptorTestSuite(t *testing.T) {
+	suite.Run(t, new(InterceptorTestSuite))
+}
+
+func (s *InterceptorTestSuite) SetupTest() {
+	s.clock = ttltest.NewClock(time.Unix(1700000000, 0))
+	s.m = ttl.New[string, proto.Message](
+		ttl.WithClock(s.clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0))
+	s.calls = 0
+}
+
+func (s *InterceptorTestSuite) TearDownTest() {
+	s.m.Close()
+}
+
+// handler responds with the request and the number of calls so far, or fails if the request is
+// "error".
+func (s *InterceptorTestSuite) handler(_ context.Context, req any) (any, error) {
+	s.calls++
+
+	in := req.(*wrapperspb.StringValue).GetValue()
+	if in == "error" {
+		return nil, errors.New("failed")
+	}
+
+	return wrapperspb.String(in + " " + strconv.Itoa(s.calls)), nil
+}
+
+func (s *InterceptorTestSuite) TestServer() {
+	intercept := grpccache.UnaryServerInterceptor(s.m,
+		grpccache.WithMethodTTL("/test/Short", 10*time.Second),
+		grpccache.WithMethodTTL("/test/Never", 0))
+
+	call := func(method string, in string) (string, error) {
+		resp, err := intercept(context.Background(), wrapperspb.String(in),
+			&grpc.UnaryServerInfo{FullMethod: method}, s.handler)
+		if err != nil {
+			return "", err
+		}
+
+		return resp.(*wrapperspb.StringValue).GetValue(), nil
+	}
+
+	out, err := call("/test/Get", "a")
+	s.Require().NoError(err)
+	s.Equal("a 1", out)
+
+	out, _ = call("/test/Get", "a")
+	s.Equal("a 1", out)
+
+	out, _ = call("/test/Get", "b")
+	s.Equal("b 2", out)
+
+	// The method is part of the key
+	out, _ = call("/test/Short", "a")
+	s.Equal("a 3", out)
+
+	out, _ = call("/test/Never", "a")
+	s.Equal("a 4", out)
+
+	out, _ = call("/test/Never", "a")
+	s.Equal("a 5", out)
+
+	// Errors are not cached
+	_, err = call("/test/Get", "error")
+	s.Error(err)
+
+	_, err = call("/test/Get", "error")
+	s.Error(err)
+	s.Equal(7, s.calls)
+
+	s.clock.Advance(10 * time.Second)
+
+	out, _ = call("/test/Short", "a")
+	s.Equal("a 8", out)
+
+	out, _ = call("/test/Get", "a")
+	s.Equal("a 1", out)
+
+	// Each call gets its own copy of the response
+	resp, _ := intercept(context.Background(), wrapperspb.String("a"),
+		&grpc.UnaryServerInfo{FullMethod: "/test/Get"}, s.handler)
+	resp.(*wrapperspb.StringValue).Value = "changed"
+
+	out, _ = call("/test/Get", "a")
+	s.Equal("a 1", out)
+}
+
+func (s *InterceptorTestSuite) TestClient() {
+	intercept := grpccache.UnaryClientInterceptor(s.m)
+
+	invoker := func(
+		ctx context.Context,
+		_ string,
+		req, reply any,
+		_ *grpc.ClientConn,
+		_ ...grpc.CallOption,
+	) error {
+		resp, err := s.handler(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		proto.Merge(reply.(proto.Message), resp.(proto.Message))
+
+		return nil
+	}
+
+	call := func(in string) string {
+		reply := wrapperspb.String("stale")
+		err := intercept(context.Background(), "/test/Get", wrapperspb.String(in), reply, nil, invoker)
+		s.Require().NoError(err)
+
+		return reply.GetValue()
+	}
+
+	s.Equal("a 1", call("a"))
+	s.Equal("a 1", call("a"))
+	s.Equal("b 2", call("b"))
+
+	s.clock.Advance(time.Minute)
+	s.Equal("a 3", call("a"))
+}
+
+func (s *InterceptorTestSuite) TestKeyFunc() {
+	intercept := grpccache.UnaryServerInterceptor(s.m,
+		grpccache.WithKeyFunc(func(_ context.Context, method string, _ proto.Message) (string, bool) {
+			return method, true
+		}))
+
+	for _, in := range []string{"a", "b"} {
+		resp, err := intercept(context.Background(), wrapperspb.String(in),
+			&grpc.UnaryServerInfo{FullMethod: "/test/Get"}, s.handler)
+		s.Require().NoError(err)
+		s.Equal("a 1", resp.(*wrapperspb.StringValue).GetValue())
+	}
+}
+
+func (s *InterceptorTestSuite) TestCost() {
+	resp := wrapperspb.String("abc")
+	s.Equal(int64(len("key")+proto.Size(resp)), grpccache.Cost("key", resp))
+}
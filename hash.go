@@ -0,0 +1,144 @@
+package ttl
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"reflect"
+)
+
+// defaultHasher returns a function that hashes keys of any comparable type with a random seed.
+// Common key types are hashed directly. Other types are hashed by walking them with reflection,
+// consistently with ==: pointers and channels are hashed by address, interfaces by their dynamic
+// value, and structs and arrays by their fields and elements. This is comparatively slow, so maps
+// with such keys may be given a faster hasher using [WithHasher].
+func defaultHasher[K comparable]() func(K) uint64 {
+	seed := maphash.MakeSeed()
+
+	return func(key K) uint64 {
+		return hashKey(seed, key)
+	}
+}
+
+func hashKey[K comparable](seed maphash.Seed, key K) uint64 {
+	switch k := any(key).(type) {
+	case string:
+		return maphash.String(seed, k)
+	case int:
+		return hashUint64(seed, uint64(k))
+	case int8:
+		return hashUint64(seed, uint64(k))
+	case int16:
+		return hashUint64(seed, uint64(k))
+	case int32:
+		return hashUint64(seed, uint64(k))
+	case int64:
+		return hashUint64(seed, uint64(k))
+	case uint:
+		return hashUint64(seed, uint64(k))
+	case uint8:
+		return hashUint64(seed, uint64(k))
+	case uint16:
+		return hashUint64(seed, uint64(k))
+	case uint32:
+		return hashUint64(seed, uint64(k))
+	case uint64:
+		return hashUint64(seed, k)
+	case uintptr:
+		return hashUint64(seed, uint64(k))
+	case float32:
+		return hashFloat(seed, float64(k))
+	case float64:
+		return hashFloat(seed, k)
+	case bool:
+		if k {
+			return hashUint64(seed, 1)
+		}
+		return hashUint64(seed, 0)
+	default:
+		var h maphash.Hash
+		h.SetSeed(seed)
+		hashValue(&h, reflect.ValueOf(&key).Elem())
+
+		return h.Sum64()
+	}
+}
+
+// hashValue writes v to h such that values that are equal according to == are written
+// identically.
+func hashValue(h *maphash.Hash, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			writeUint64(h, 1)
+		} else {
+			writeUint64(h, 0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint64(h, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint64(h, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeFloat(h, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		writeFloat(h, real(c))
+		writeFloat(h, imag(c))
+	case reflect.String:
+		writeUint64(h, uint64(v.Len()))
+		_, _ = h.WriteString(v.String())
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		// Equal pointers have the same address, whatever they point to
+		writeUint64(h, uint64(v.Pointer()))
+	case reflect.Interface:
+		if v.IsNil() {
+			writeUint64(h, 0)
+			return
+		}
+
+		hashValue(h, v.Elem())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			hashValue(h, v.Index(i))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			// Blank fields are ignored by ==
+			if t.Field(i).Name != "_" {
+				hashValue(h, v.Field(i))
+			}
+		}
+	}
+}
+
+func hashUint64(seed maphash.Seed, v uint64) uint64 {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+
+	return maphash.Bytes(seed, b[:])
+}
+
+func hashFloat(seed maphash.Seed, f float64) uint64 {
+	if f == 0 {
+		// +0 and -0 are equal, so must hash the same
+		f = 0
+	}
+
+	return hashUint64(seed, math.Float64bits(f))
+}
+
+func writeUint64(h *maphash.Hash, v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+
+	_, _ = h.Write(b[:])
+}
+
+func writeFloat(h *maphash.Hash, f float64) {
+	if f == 0 {
+		f = 0
+	}
+
+	writeUint64(h, math.Float64bits(f))
+}
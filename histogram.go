@@ -0,0 +1,120 @@
+package ttl
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// histogram is a lock-free, log-linear histogram of durations used to estimate quantiles. Each
+// power of two is divided into 2^histogramSubBucketBits linear buckets, so estimates are within
+// about 12.5% of the true value.
+type histogram struct {
+	counts [histogramBuckets]atomic.Uint64
+}
+
+const (
+	histogramSubBucketBits = 3
+	histogramSubBuckets    = 1 << histogramSubBucketBits
+	histogramBuckets       = (64 - histogramSubBucketBits + 1) * histogramSubBuckets
+)
+
+func histogramBucket(v uint64) int {
+	if v < histogramSubBuckets {
+		return int(v)
+	}
+
+	shift := bits.Len64(v) - histogramSubBucketBits - 1
+	return (shift+1)*histogramSubBuckets + int((v>>shift)&(histogramSubBuckets-1))
+}
+
+// histogramValue returns a value representative of the bucket: the midpoint of its range.
+func histogramValue(bucket int) uint64 {
+	if bucket < histogramSubBuckets {
+		return uint64(bucket)
+	}
+
+	shift := bucket/histogramSubBuckets - 1
+	lower := uint64(histogramSubBuckets+bucket%histogramSubBuckets) << shift
+
+	return lower + (uint64(1)<<shift)/2
+}
+
+func (h *histogram) observe(d time.Duration) {
+	h.counts[histogramBucket(uint64(max(d, 0)))].Add(1)
+}
+
+func (h *histogram) observeSince(start time.Time) {
+	h.observe(time.Since(start))
+}
+
+// reset discards all observations.
+func (h *histogram) reset() {
+	for i := range h.counts {
+		h.counts[i].Store(0)
+	}
+}
+
+// Quantiles summarizes a distribution of durations with estimates of its 50th, 95th and 99th
+// percentiles. Estimates are within about 12.5% of the true value.
+type Quantiles struct {
+	// Count is the number of observations.
+	Count uint64
+
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+}
+
+func (h *histogram) quantiles() Quantiles {
+	var counts [histogramBuckets]uint64
+	h.addTo(&counts)
+
+	return quantilesOf(&counts)
+}
+
+// addTo adds the counts of h to counts, so that histograms can be combined.
+func (h *histogram) addTo(counts *[histogramBuckets]uint64) {
+	for i := range h.counts {
+		counts[i] += h.counts[i].Load()
+	}
+}
+
+// quantilesOf estimates quantiles from histogram bucket counts.
+func quantilesOf(counts *[histogramBuckets]uint64) (q Quantiles) {
+	for _, count := range counts {
+		q.Count += count
+	}
+
+	if q.Count == 0 {
+		return
+	}
+
+	rank := func(p float64) uint64 {
+		return uint64(math.Ceil(p * float64(q.Count)))
+	}
+
+	targets := []struct {
+		rank uint64
+		dst  *time.Duration
+	}{
+		{rank(0.50), &q.P50},
+		{rank(0.95), &q.P95},
+		{rank(0.99), &q.P99},
+	}
+
+	var cumulative uint64
+	for bucket, count := range counts {
+		cumulative += count
+		for len(targets) > 0 && cumulative >= targets[0].rank {
+			*targets[0].dst = time.Duration(histogramValue(bucket))
+			targets = targets[1:]
+		}
+		if len(targets) == 0 {
+			break
+		}
+	}
+
+	return
+}
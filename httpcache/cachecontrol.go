@@ -0,0 +1,122 @@
+package httpcache
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TTL returns the time for which a response with header may be cached by a shared cache as of now,
+// derived from its Cache-Control, Expires, Date and Age headers, as well as a bool indicating
+// whether it may be cached at all. The s-maxage directive takes precedence over max-age, which
+// takes precedence over Expires, and the Age of the response is deducted from its lifetime.
+//
+// A response is not cacheable if Cache-Control includes no-store, no-cache or private (as this
+// package neither revalidates responses nor serves a single user), or if it is already stale. If
+// the response is cacheable but header sets no lifetime, the TTL returned is zero, meaning that the
+// default TTL should be used.
+func TTL(header http.Header, now time.Time) (TTL time.Duration, ok bool) {
+	directives := parseCacheControl(header.Values("Cache-Control"))
+
+	for _, name := range []string{"no-store", "no-cache", "private"} {
+		if _, found := directives[name]; found {
+			return 0, false
+		}
+	}
+
+	var lifetime time.Duration
+
+	if seconds, found := directives["s-maxage"]; found {
+		lifetime, ok = parseSeconds(seconds)
+	} else if seconds, found := directives["max-age"]; found {
+		lifetime, ok = parseSeconds(seconds)
+	} else if expires := header.Get("Expires"); expires != "" {
+		lifetime, ok = expiresLifetime(expires, header.Get("Date"), now)
+	} else {
+		return 0, true
+	}
+
+	if !ok {
+		return 0, false
+	}
+
+	if age, ageOK := parseSeconds(header.Get("Age")); ageOK {
+		lifetime -= age
+	}
+
+	if lifetime <= 0 {
+		return 0, false
+	}
+
+	return lifetime, true
+}
+
+// sharedWhenAuthorized reports whether a response with header may be stored and served by a shared
+// cache in response to requests with an Authorization header, as its Cache-Control includes
+// public, s-maxage or must-revalidate.
+func sharedWhenAuthorized(header http.Header) bool {
+	directives := parseCacheControl(header.Values("Cache-Control"))
+
+	for _, name := range []string{"public", "s-maxage", "must-revalidate"} {
+		if _, found := directives[name]; found {
+			return true
+		}
+	}
+
+	return false
+}
+
+// requestNoStore reports whether the Cache-Control of r includes no-store, so that its response
+// must not be stored.
+func requestNoStore(r *http.Request) bool {
+	_, found := parseCacheControl(r.Header.Values("Cache-Control"))["no-store"]
+	return found
+}
+
+// parseCacheControl returns the directives of the Cache-Control header values, mapping each name,
+// in lower case, to its value, with any quotes removed.
+func parseCacheControl(values []string) map[string]string {
+	directives := make(map[string]string)
+
+	for _, value := range values {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name == "" {
+				continue
+			}
+
+			directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+		}
+	}
+
+	return directives
+}
+
+// parseSeconds parses a non-negative number of seconds, as used by max-age and Age.
+func parseSeconds(s string) (time.Duration, bool) {
+	// Values too large to represent are treated as the largest, as required by RFC 9111
+	seconds, err := strconv.ParseInt(s, 10, 64)
+	if (err != nil && !errors.Is(err, strconv.ErrRange)) || seconds < 0 {
+		return 0, false
+	}
+
+	return time.Duration(min(seconds, int64(time.Duration(1<<63-1)/time.Second))) * time.Second, true
+}
+
+// expiresLifetime returns the freshness lifetime given by the Expires header, relative to the Date
+// header, or to now if the response has no valid Date. An invalid Expires, such as "0", means the
+// response is already stale.
+func expiresLifetime(expires string, date string, now time.Time) (time.Duration, bool) {
+	expireAt, err := http.ParseTime(expires)
+	if err != nil {
+		return 0, false
+	}
+
+	if d, err := http.ParseTime(date); err == nil {
+		now = d
+	}
+
+	return expireAt.Sub(now), true
+}
@@ -0,0 +1,81 @@
+package httpcache_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/glenvan/ttl/v2/httpcache"
+)
+
+func (s *CacheTestSuite) TestTTL() {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	header := func(kv ...string) http.Header {
+		h := make(http.Header)
+		for i := 0; i < len(kv); i += 2 {
+			h.Add(kv[i], kv[i+1])
+		}
+
+		return h
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		TTL    time.Duration
+		ok     bool
+	}{
+		{"none", header(), 0, true},
+		{"max-age", header("Cache-Control", "public, max-age=60"), time.Minute, true},
+		{"s-maxage", header("Cache-Control", `max-age=60, S-MAXAGE="120"`), 2 * time.Minute, true},
+		{"age", header("Cache-Control", "max-age=60", "Age", "15"), 45 * time.Second, true},
+		{"stale", header("Cache-Control", "max-age=60", "Age", "60"), 0, false},
+		{"no-store", header("Cache-Control", "max-age=60", "Cache-Control", "no-store"), 0, false},
+		{"no-cache", header("Cache-Control", "no-cache"), 0, false},
+		{"private", header("Cache-Control", "private, max-age=60"), 0, false},
+		{"invalid max-age", header("Cache-Control", "max-age=soon"), 0, false},
+		{"huge max-age", header("Cache-Control", "max-age=99999999999999999999"),
+			time.Duration(1<<63 - 1).Truncate(time.Second), true},
+		{"expires", header("Expires", now.Add(time.Hour).Format(http.TimeFormat)), time.Hour, true},
+		{"expires and date", header(
+			"Expires", now.Add(time.Hour).Format(http.TimeFormat),
+			"Date", now.Add(-time.Hour).Format(http.TimeFormat)), 2 * time.Hour, true},
+		{"expires and max-age", header(
+			"Expires", now.Add(time.Hour).Format(http.TimeFormat),
+			"Cache-Control", "max-age=60"), time.Minute, true},
+		{"invalid expires", header("Expires", "0"), 0, false},
+	}
+
+	for _, tt := range tests {
+		TTL, ok := httpcache.TTL(tt.header, now)
+		s.Equal(tt.ok, ok, tt.name)
+		s.Equal(tt.TTL, TTL, tt.name)
+	}
+}
+
+func (s *CacheTestSuite) TestHandlerCacheControl() {
+	h := httpcache.New(s.m).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.calls++
+
+		switch r.URL.Path {
+		case "/short":
+			w.Header().Set("Cache-Control", "max-age=10")
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store")
+		}
+	}))
+
+	for _, path := range []string{"/default", "/short", "/no-store"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	s.Equal(3, s.calls)
+	s.Equal(2, s.m.Length())
+
+	TTL, _ := s.m.TTL("GET /default")
+	s.Equal(time.Minute, TTL)
+
+	TTL, _ = s.m.TTL("GET /short")
+	s.Equal(10*time.Second, TTL)
+}
@@ -0,0 +1,272 @@
+// Package httpcache provides net/http middleware that caches responses in a [ttl.Map], keyed by
+// method and URL:
+//
+//	m := ttl.New[string, *httpcache.Response](
+//		ttl.WithTTL(time.Minute),
+//		ttl.WithMaxCost(64<<20, httpcache.Cost))
+//	defer m.Close()
+//
+//	cache := httpcache.New(m, httpcache.WithVary("Accept-Encoding"))
+//	http.Handle("/", cache.Handler(handler))
+//
+// Responses are cached for the time given by their Cache-Control or Expires headers, as returned
+// by [TTL], or otherwise for the TTL of the Map, which is not extended when they are served, and
+// [Cost] bounds the memory they use when the Map is configured with ttl.WithMaxCost.
+package httpcache
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+)
+
+// DefaultMaxBodySize is the default size, in bytes, of the largest response body that is cached.
+const DefaultMaxBodySize = 1 << 20
+
+// Response is a cached response.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Cost returns the approximate size in bytes of a cached response, including its key, for use as
+// the cost function of ttl.WithMaxCost.
+func Cost(key string, resp *Response) int64 {
+	n := len(key) + len(resp.Body)
+	for name, values := range resp.Header {
+		n += len(name)
+		for _, value := range values {
+			n += len(value)
+		}
+	}
+
+	return int64(n)
+}
+
+// Option configures a [Cache].
+type Option func(*options)
+
+type options struct {
+	keyFunc     func(r *http.Request) string
+	maxBodySize int64
+	vary        []string
+}
+
+// WithKeyFunc sets the function that returns the cache key of a request. The default is the method
+// followed by the URL. The values of the headers set using [WithVary] are added to the key.
+func WithKeyFunc(f func(r *http.Request) string) Option {
+	return func(o *options) {
+		o.keyFunc = f
+	}
+}
+
+// WithMaxBodySize sets the size, in bytes, of the largest response body that is cached. Larger
+// responses are served without being cached. The default is [DefaultMaxBodySize].
+func WithMaxBodySize(n int64) Option {
+	return func(o *options) {
+		o.maxBodySize = n
+	}
+}
+
+// WithVary sets request headers, such as "Accept-Encoding" or "Accept-Language", whose values
+// select between different responses to the same URL, so that they are cached separately.
+func WithVary(headers ...string) Option {
+	return func(o *options) {
+		o.vary = append(o.vary, headers...)
+	}
+}
+
+// Cache caches the responses of handlers in a [ttl.Map]. Only responses to GET and HEAD requests
+// are cached, and only if their status code is cacheable by default (such as 200 OK or 404 Not
+// Found), they do not set cookies and their headers allow it, as reported by [TTL]. Responses to
+// requests with Cache-Control: no-store are not cached.
+//
+// As a shared cache, Cache neither caches nor serves cached responses for requests with an
+// Authorization header, unless the response's Cache-Control includes public, s-maxage or
+// must-revalidate, as required by RFC 9111, section 3.5.
+//
+// Cache is safe for concurrent use.
+type Cache struct {
+	m           *ttl.Map[string, *Response]
+	keyFunc     func(r *http.Request) string
+	maxBodySize int64
+	vary        []string
+}
+
+// New returns a [Cache] that caches responses in m, configured by opts. m remains owned by the
+// caller, who must close it once the Cache is no longer needed.
+func New(m *ttl.Map[string, *Response], opts ...Option) *Cache {
+	o := options{
+		keyFunc: func(r *http.Request) string {
+			return r.Method + " " + r.URL.String()
+		},
+		maxBodySize: DefaultMaxBodySize,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	vary := make([]string, len(o.vary))
+	for i, header := range o.vary {
+		vary[i] = http.CanonicalHeaderKey(header)
+	}
+
+	return &Cache{
+		m:           m,
+		keyFunc:     o.keyFunc,
+		maxBodySize: o.maxBodySize,
+		vary:        vary,
+	}
+}
+
+// Handler returns a handler that serves cached responses of next, calling next and caching its
+// response if the request has none.
+func (c *Cache) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := c.key(r)
+		authorized := r.Header.Get("Authorization") != ""
+
+		// Serving a response does not extend its time to live, so it is never served stale
+		resp, ok := c.m.LoadPassive(key)
+		if ok && (!authorized || sharedWhenAuthorized(resp.Header)) {
+			serve(w, resp)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w, maxBodySize: c.maxBodySize}
+		next.ServeHTTP(rec, r)
+
+		resp, ok = rec.response()
+		if !ok || requestNoStore(r) || (authorized && !sharedWhenAuthorized(resp.Header)) {
+			return
+		}
+
+		lifetime, ok := TTL(resp.Header, time.Now())
+		switch {
+		case !ok:
+			return
+		case lifetime > 0:
+			c.m.StoreWithTTL(key, resp, lifetime)
+		default:
+			// Store would keep the lifetime of a response already cached for the key
+			c.m.StoreWithTTL(key, resp, c.m.DefaultTTL())
+		}
+	})
+}
+
+// key returns the cache key of r.
+func (c *Cache) key(r *http.Request) string {
+	key := c.keyFunc(r)
+	if len(c.vary) == 0 {
+		return key
+	}
+
+	var b strings.Builder
+	b.WriteString(key)
+
+	for _, header := range c.vary {
+		b.WriteString("\n")
+		b.WriteString(header)
+		b.WriteString(": ")
+		b.WriteString(strings.Join(r.Header.Values(header), ", "))
+	}
+
+	return b.String()
+}
+
+// serve writes resp to w.
+func serve(w http.ResponseWriter, resp *Response) {
+	header := w.Header()
+	for name, values := range resp.Header {
+		header[name] = append([]string(nil), values...)
+	}
+
+	w.WriteHeader(resp.StatusCode)
+	_, _ = w.Write(resp.Body)
+}
+
+// cacheableStatus holds the status codes of the responses that are cacheable by default, as
+// defined by RFC 9110, section 15.1, except 206 Partial Content, as range requests are not cached.
+var cacheableStatus = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// recorder passes a response through to the client, recording it to be cached.
+type recorder struct {
+	http.ResponseWriter
+
+	maxBodySize int64
+	statusCode  int
+	header      http.Header // a copy of the header, taken when it was written
+	body        bytes.Buffer
+	tooLarge    bool
+}
+
+func (rec *recorder) WriteHeader(statusCode int) {
+	if rec.statusCode == 0 {
+		rec.statusCode = statusCode
+		rec.header = rec.Header().Clone()
+	}
+
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rec *recorder) Write(p []byte) (int, error) {
+	if rec.statusCode == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+
+	if !rec.tooLarge {
+		if int64(rec.body.Len()+len(p)) > rec.maxBodySize {
+			rec.tooLarge = true
+			rec.body = bytes.Buffer{}
+		} else {
+			rec.body.Write(p)
+		}
+	}
+
+	return rec.ResponseWriter.Write(p)
+}
+
+// Unwrap returns the underlying [http.ResponseWriter], for [http.ResponseController].
+func (rec *recorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// response returns the recorded response, as well as a bool indicating whether it may be cached.
+func (rec *recorder) response() (*Response, bool) {
+	if rec.statusCode == 0 {
+		// The handler wrote nothing, which is an empty 200 OK
+		rec.WriteHeader(http.StatusOK)
+	}
+
+	if rec.tooLarge || !cacheableStatus[rec.statusCode] || rec.header.Get("Set-Cookie") != "" {
+		return nil, false
+	}
+
+	return &Response{
+		StatusCode: rec.statusCode,
+		Header:     rec.header,
+		Body:       rec.body.Bytes(),
+	}, true
+}
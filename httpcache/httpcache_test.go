@@ -0,0 +1,231 @@
+package httpcache_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/httpcache"
+	"github.com/glenvan/ttl/v2/ttltest"
+	"github.com/stretchr/testify/suite"
+)
+
+type CacheTestSuite struct {
+	suite.Suite
+
+	clock *ttltest.Clock
+	m     *ttl.Map[string, *httpcache.Response]
+	calls int
+}
+
+func TestCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(CacheTestSuite))
+}
+
+func (s *CacheTestSuite) SetupTest() {
+	s.clock = ttltest.NewClock(time.Unix(1700000000, 0))
+	s.m = ttl.New[string, *httpcache.Response](
+		ttl.WithClock(s.clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0))
+	s.calls = 0
+}
+
+func (s *CacheTestSuite) TearDownTest() {
+	s.m.Close()
+}
+
+// handler counts its calls and responds with the call count, the path and the Accept-Language
+// header of the request.
+func (s *CacheTestSuite) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.calls++
+
+		switch r.URL.Path {
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/cookie":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret"})
+		case "/large":
+			_, _ = w.Write([]byte(strings.Repeat("x", 100)))
+			return
+		case "/public":
+			w.Header().Set("Cache-Control", "public")
+		}
+
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = fmt.Fprintf(w, "%d %s %s", s.calls, r.URL.Path, r.Header.Get("Accept-Language"))
+	})
+}
+
+func (s *CacheTestSuite) get(h http.Handler, method string, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+
+	return w
+}
+
+func (s *CacheTestSuite) TestHandler() {
+	h := httpcache.New(s.m).Handler(s.handler())
+
+	w := s.get(h, http.MethodGet, "/a")
+	s.Equal("1 /a ", w.Body.String())
+
+	w = s.get(h, http.MethodGet, "/a")
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal("text/plain", w.Header().Get("Content-Type"))
+	s.Equal("1 /a ", w.Body.String())
+
+	// The query string is part of the key
+	s.Equal("2 /a ", s.get(h, http.MethodGet, "/a?b=c").Body.String())
+
+	// Other methods are not cached
+	s.Equal("3 /a ", s.get(h, http.MethodPost, "/a").Body.String())
+	s.Equal("4 /a ", s.get(h, http.MethodPost, "/a").Body.String())
+
+	// Serving a response does not extend its time to live
+	s.clock.Advance(30 * time.Second)
+	s.Equal("1 /a ", s.get(h, http.MethodGet, "/a").Body.String())
+
+	s.clock.Advance(30 * time.Second)
+	s.Equal("5 /a ", s.get(h, http.MethodGet, "/a").Body.String())
+}
+
+func (s *CacheTestSuite) TestNotCached() {
+	h := httpcache.New(s.m, httpcache.WithMaxBodySize(50)).Handler(s.handler())
+
+	for _, path := range []string{"/error", "/cookie", "/large"} {
+		s.get(h, http.MethodGet, path)
+		s.get(h, http.MethodGet, path)
+	}
+
+	s.Equal(6, s.calls)
+	s.Zero(s.m.Length())
+
+	// A response too large to cache is still served in full
+	s.Len(s.get(h, http.MethodGet, "/large").Body.String(), 100)
+}
+
+func (s *CacheTestSuite) TestAuthorization() {
+	h := httpcache.New(s.m).Handler(s.handler())
+
+	get := func(path string, authorization string) string {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		return w.Body.String()
+	}
+
+	// Responses to authorized requests are neither stored nor served from the cache
+	s.Equal("1 /a ", get("/a", "Bearer alice"))
+	s.Equal("2 /a ", get("/a", "Bearer bob"))
+	s.Zero(s.m.Length())
+
+	s.Equal("3 /a ", get("/a", ""))
+	s.Equal("4 /a ", get("/a", "Bearer bob"))
+	s.Equal("3 /a ", get("/a", ""))
+
+	// Unless the response allows it
+	s.Equal("5 /public ", get("/public", "Bearer alice"))
+	s.Equal("5 /public ", get("/public", "Bearer bob"))
+	s.Equal("5 /public ", get("/public", ""))
+}
+
+func (s *CacheTestSuite) TestReplacedLifetime() {
+	h := httpcache.New(s.m).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			w.Header().Set("Cache-Control", "public")
+		} else {
+			w.Header().Set("Cache-Control", "max-age=3600")
+		}
+
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	s.get(h, http.MethodGet, "/a")
+	s.Require().Len(s.m.Entries(), 1)
+	s.Equal(time.Hour, s.m.Entries()[0].Remaining)
+
+	// A response without a lifetime replaces it with the default TTL rather than keeping the hour
+	r := httptest.NewRequest(http.MethodGet, "/a", nil)
+	r.Header.Set("Authorization", "Bearer alice")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	s.Require().Len(s.m.Entries(), 1)
+	s.Equal(time.Minute, s.m.Entries()[0].Remaining)
+}
+
+func (s *CacheTestSuite) TestRequestNoStore() {
+	h := httpcache.New(s.m).Handler(s.handler())
+
+	get := func(cacheControl string) string {
+		r := httptest.NewRequest(http.MethodGet, "/a", nil)
+		r.Header.Set("Cache-Control", cacheControl)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		return w.Body.String()
+	}
+
+	s.Equal("1 /a ", get("no-store"))
+	s.Zero(s.m.Length())
+
+	s.Equal("2 /a ", get(""))
+	s.Equal("2 /a ", get("no-store"))
+}
+
+func (s *CacheTestSuite) TestVary() {
+	h := httpcache.New(s.m, httpcache.WithVary("accept-language")).Handler(s.handler())
+
+	get := func(language string) string {
+		r := httptest.NewRequest(http.MethodGet, "/a", nil)
+		r.Header.Set("Accept-Language", language)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		return w.Body.String()
+	}
+
+	s.Equal("1 /a en", get("en"))
+	s.Equal("2 /a fr", get("fr"))
+	s.Equal("1 /a en", get("en"))
+	s.Equal("2 /a fr", get("fr"))
+}
+
+func (s *CacheTestSuite) TestKeyFunc() {
+	h := httpcache.New(s.m, httpcache.WithKeyFunc(func(r *http.Request) string {
+		return r.URL.Path
+	})).Handler(s.handler())
+
+	s.Equal("1 /a ", s.get(h, http.MethodGet, "/a?b=c").Body.String())
+	s.Equal("1 /a ", s.get(h, http.MethodGet, "/a?b=d").Body.String())
+}
+
+func (s *CacheTestSuite) TestCost() {
+	m := ttl.New[string, *httpcache.Response](
+		ttl.WithClock(s.clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithMaxCost(100, httpcache.Cost),
+		ttl.WithPruneInterval(0))
+	defer m.Close()
+
+	h := httpcache.New(m).Handler(s.handler())
+
+	for i := 0; i < 10; i++ {
+		s.get(h, http.MethodGet, fmt.Sprintf("/%d", i))
+	}
+
+	s.Less(m.Length(), 10)
+	s.Positive(m.Length())
+}
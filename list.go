@@ -0,0 +1,284 @@
+package ttl
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// List is an ordered sequence of elements, each of which is removed once its own time to live has
+// elapsed since it was appended, for example to keep the events of the last few minutes as a
+// sliding window. Elements keep the order in which they were appended; removing expired elements
+// closes the gaps they leave, so the index of an element may decrease over time.
+//
+// A List is configured using the same [Option]s as a [Map]. [WithContext], [WithTTL],
+// [WithPruneInterval], [WithPruneJitter] and [WithClock] apply; other options are ignored. Expired
+// elements are never visible, whether or not they have been pruned. Loading an element does not
+// extend its time to live.
+//
+// List is safe for concurrent use.
+type List[T any] struct {
+	clock      Clock
+	defaultTTL time.Duration
+
+	mtx sync.Mutex
+	seq sequence[T]
+
+	bg background
+}
+
+// NewList returns a new, empty [List] configured by opts, like [New].
+func NewList[T any](opts ...Option) *List[T] {
+	o := defaultOptions()
+	o.apply(opts)
+	o.logMisconfiguration()
+
+	l := &List[T]{
+		clock:      o.clock,
+		defaultTTL: o.ttl,
+		seq:        newSequence[T](),
+		bg:         newBackground(),
+	}
+
+	l.bg.start(o, func(now time.Time) {
+		l.mtx.Lock()
+		defer l.mtx.Unlock()
+
+		l.seq.prune(now.UnixNano(), nil)
+	})
+
+	return l
+}
+
+// Append appends value to the end of the [List] with the default time to live. Append is safe for
+// concurrent use.
+func (l *List[T]) Append(value T) {
+	l.AppendWithTTL(value, l.defaultTTL)
+}
+
+// AppendWithTTL appends value to the end of the [List] with a custom time to live. A zero or
+// negative TTL means the element never expires. AppendWithTTL is safe for concurrent use.
+func (l *List[T]) AppendWithTTL(value T, TTL time.Duration) {
+	e := newSequenceElement(value, l.clock.Now(), TTL)
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.seq.push(e)
+}
+
+// Length returns the number of unexpired elements in the [List]. Length is safe for concurrent
+// use.
+func (l *List[T]) Length() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.seq.prune(l.clock.Now().UnixNano(), nil)
+
+	return len(l.seq.elements)
+}
+
+// At returns the unexpired element at index i, counting from the oldest, as well as a bool
+// indicating whether there is such an element. At is safe for concurrent use.
+func (l *List[T]) At(i int) (value T, ok bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.seq.prune(l.clock.Now().UnixNano(), nil)
+
+	if i < 0 || i >= len(l.seq.elements) {
+		return value, false
+	}
+
+	return l.seq.elements[i].value, true
+}
+
+// Values returns the unexpired elements of the [List], from the oldest to the newest. Values is
+// safe for concurrent use.
+func (l *List[T]) Values() []T {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.seq.prune(l.clock.Now().UnixNano(), nil)
+
+	values := make([]T, len(l.seq.elements))
+	for i, e := range l.seq.elements {
+		values[i] = e.value
+	}
+
+	return values
+}
+
+// Range calls f sequentially with the index and value of each unexpired element of the [List],
+// from the oldest to the newest. If f returns false, Range stops the iteration. Range iterates
+// over a copy of the elements taken when it is called, so f may modify the [List]. Range is safe
+// for concurrent use.
+func (l *List[T]) Range(f func(i int, value T) bool) {
+	for i, value := range l.Values() {
+		if !f(i, value) {
+			return
+		}
+	}
+}
+
+// RangeContext is like [List.Range], but stops and returns ctx.Err() if ctx is done before the
+// iteration completes.
+func (l *List[T]) RangeContext(ctx context.Context, f func(i int, value T) bool) error {
+	for i, value := range l.Values() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !f(i, value) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Prune removes the expired elements from the [List], returning the number removed. Prune is safe
+// for concurrent use.
+func (l *List[T]) Prune() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.seq.prune(l.clock.Now().UnixNano(), nil)
+}
+
+// Clear removes every element from the [List]. Clear is safe for concurrent use.
+func (l *List[T]) Clear() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.seq.clear()
+}
+
+// Close stops the [List] from pruning in the background. If Close is not called on a [List] after
+// it's no longer needed, its prune goroutine will leak (unless the context has been cancelled).
+// Close may be called multiple times.
+func (l *List[T]) Close() {
+	l.bg.close()
+}
+
+// CloseWait is like [List.Close], but also waits for the prune goroutine to exit.
+func (l *List[T]) CloseWait() {
+	l.bg.closeWait()
+}
+
+// sequence is the storage of a List or a Queue: a sequence of elements in the order in which they
+// were added, each with its own expiry. It is not safe for concurrent use.
+type sequence[T any] struct {
+	elements []sequenceElement[T]
+	earliest int64 // the earliest expiry of the elements in Unix nanoseconds, or math.MaxInt64
+}
+
+type sequenceElement[T any] struct {
+	value    T
+	expireAt int64 // in Unix nanoseconds, or zero if the element never expires
+}
+
+func newSequence[T any]() sequence[T] {
+	return sequence[T]{earliest: math.MaxInt64}
+}
+
+// newSequenceElement returns an element holding value that expires TTL after now, or never if TTL
+// is zero or negative.
+func newSequenceElement[T any](value T, now time.Time, TTL time.Duration) sequenceElement[T] {
+	e := sequenceElement[T]{value: value}
+	if TTL > 0 {
+		e.expireAt = now.Add(TTL).UnixNano()
+	}
+
+	return e
+}
+
+// expired reports whether e has expired as of now, in Unix nanoseconds.
+func (e sequenceElement[T]) expired(now int64) bool {
+	return expiredAt(e.expireAt, now)
+}
+
+// push appends e to the end of the sequence.
+func (s *sequence[T]) push(e sequenceElement[T]) {
+	if e.expireAt != 0 {
+		s.earliest = min(s.earliest, e.expireAt)
+	}
+
+	s.elements = append(s.elements, e)
+}
+
+// pop removes and returns the first element of the sequence, which must not be empty.
+func (s *sequence[T]) pop() sequenceElement[T] {
+	e := s.elements[0]
+
+	var zero sequenceElement[T]
+	s.elements[0] = zero
+	s.elements = s.elements[1:]
+
+	if len(s.elements) == 0 {
+		// Start again from the beginning of the array rather than growing past its end
+		s.elements = s.elements[:0:0]
+		s.earliest = math.MaxInt64
+	}
+
+	return e
+}
+
+// popBack removes and returns the last element of the sequence, which must not be empty.
+func (s *sequence[T]) popBack() sequenceElement[T] {
+	last := len(s.elements) - 1
+	e := s.elements[last]
+
+	var zero sequenceElement[T]
+	s.elements[last] = zero
+	s.elements = s.elements[:last]
+
+	if last == 0 {
+		s.earliest = math.MaxInt64
+	}
+
+	return e
+}
+
+// prune removes the elements that have expired as of now, in Unix nanoseconds, appending their
+// values to expired if it is not nil. It returns the number of elements removed and expired. It
+// does nothing unless an element has expired.
+func (s *sequence[T]) prune(now int64, expired *[]T) int {
+	if now < s.earliest {
+		return 0
+	}
+
+	kept := s.elements[:0]
+	earliest := int64(math.MaxInt64)
+
+	for _, e := range s.elements {
+		if e.expired(now) {
+			if expired != nil {
+				*expired = append(*expired, e.value)
+			}
+			continue
+		}
+
+		if e.expireAt != 0 {
+			earliest = min(earliest, e.expireAt)
+		}
+
+		kept = append(kept, e)
+	}
+
+	pruned := len(s.elements) - len(kept)
+
+	clear(s.elements[len(kept):])
+	s.elements = kept
+	s.earliest = earliest
+
+	return pruned
+}
+
+// clear removes every element from the sequence.
+func (s *sequence[T]) clear() {
+	clear(s.elements)
+	s.elements = s.elements[:0]
+	s.earliest = math.MaxInt64
+}
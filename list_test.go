@@ -0,0 +1,73 @@
+package ttl_test
+
+import (
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestList() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	list := ttl.NewList[string](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(time.Second))
+	defer list.Close()
+
+	list.Append("a")
+	list.AppendWithTTL("b", 10*time.Second)
+	list.AppendWithTTL("c", 0)
+	list.Append("d")
+
+	s.Equal(4, list.Length())
+	s.Equal([]string{"a", "b", "c", "d"}, list.Values())
+
+	v, ok := list.At(1)
+	s.True(ok)
+	s.Equal("b", v)
+
+	_, ok = list.At(4)
+	s.False(ok)
+
+	// Expired elements leave no gaps
+	clock.Advance(10 * time.Second)
+	v, ok = list.At(1)
+	s.True(ok)
+	s.Equal("c", v)
+
+	clock.Advance(time.Minute)
+	s.Equal([]string{"c"}, list.Values())
+
+	var indexes []int
+	list.Append("e")
+	list.Append("f")
+	list.Range(func(i int, value string) bool {
+		indexes = append(indexes, i)
+		return value != "e"
+	})
+	s.Equal([]int{0, 1}, indexes)
+
+	list.Clear()
+	s.Zero(list.Length())
+}
+
+func (s *MapTestSuite) TestListPrunes() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	list := ttl.NewList[int](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0))
+	defer list.CloseWait()
+
+	for i := 0; i < 10; i++ {
+		list.AppendWithTTL(i, time.Duration(i+1)*time.Second)
+	}
+
+	clock.Advance(5 * time.Second)
+	s.Equal(5, list.Prune())
+	s.Zero(list.Prune())
+	s.Equal([]int{5, 6, 7, 8, 9}, list.Values())
+}
@@ -0,0 +1,166 @@
+package ttl
+
+import (
+	"errors"
+)
+
+// LRU adapts a [Map] bounded by [WithMaxEntries] to the method set of the Cache of
+// [hashicorp/golang-lru], so that code written against it can use a [Map] by changing only the
+// constructor, gaining expiry:
+//
+//	cache, err := lru.New[string, int](128)
+//
+// becomes
+//
+//	cache, err := ttl.NewLRU[string, int](128, ttl.WithTTL(time.Hour))
+//
+// Eviction is approximately least recently used, as for [WithMaxEntries], and methods that list
+// entries return them in no particular order, rather than from oldest to newest.
+//
+// LRU is safe for concurrent use.
+//
+// [hashicorp/golang-lru]: https://github.com/hashicorp/golang-lru
+type LRU[K comparable, V any] struct {
+	m *Map[K, V]
+}
+
+// NewLRU returns a new, empty [LRU] holding up to size entries, configured by opts, like [New],
+// except that [WithMaxEntries] is set to size. Unless opts include [WithTTL], entries never
+// expire. An error is returned if size is not positive, as by golang-lru.
+func NewLRU[K comparable, V any](size int, opts ...Option) (*LRU[K, V], error) {
+	if size <= 0 {
+		return nil, errors.New("ttl: NewLRU must be given a positive size")
+	}
+
+	return &LRU[K, V]{
+		m: New[K, V](append(append([]Option{WithTTL(0)}, opts...), WithMaxEntries(size))...),
+	}, nil
+}
+
+// Add stores value with the default time to live, returning true if an entry was evicted to make
+// room for it. Add is safe for concurrent use.
+func (c *LRU[K, V]) Add(key K, value V) (evicted bool) {
+	return c.m.storeEvicting(key, value)
+}
+
+// Get returns the value of key, as well as a bool indicating whether it was found, marking it as
+// recently used, like [Map.Load]. Get is safe for concurrent use.
+func (c *LRU[K, V]) Get(key K) (value V, ok bool) {
+	return c.m.Load(key)
+}
+
+// Peek is like [LRU.Get], but does not mark key as recently used, like [Map.LoadPassive]. Peek is
+// safe for concurrent use.
+func (c *LRU[K, V]) Peek(key K) (value V, ok bool) {
+	return c.m.LoadPassive(key)
+}
+
+// ContainsOrAdd reports whether key is present, without marking it as recently used, and stores
+// value for it if not. The check and the store are atomic. evicted reports whether an entry was
+// evicted to make room for the value. ContainsOrAdd is safe for concurrent use.
+func (c *LRU[K, V]) ContainsOrAdd(key K, value V) (ok, evicted bool) {
+	_, ok, evicted = c.m.loadOrStoreEvicting(key, value)
+	return
+}
+
+// PeekOrAdd returns the value of key, as well as true, if it is present, without marking it as
+// recently used. Otherwise, it stores value for key. The check and the store are atomic. evicted
+// reports whether an entry was evicted to make room for the value. PeekOrAdd is safe for
+// concurrent use.
+func (c *LRU[K, V]) PeekOrAdd(key K, value V) (previous V, ok, evicted bool) {
+	previous, ok, evicted = c.m.loadOrStoreEvicting(key, value)
+	if !ok {
+		var zero V
+		previous = zero
+	}
+
+	return
+}
+
+// Contains reports whether key is present, without marking it as recently used. Contains is safe
+// for concurrent use.
+func (c *LRU[K, V]) Contains(key K) bool {
+	_, ok := c.m.LoadPassive(key)
+	return ok
+}
+
+// Remove removes key, reporting whether it was present. Remove is safe for concurrent use.
+func (c *LRU[K, V]) Remove(key K) (present bool) {
+	_, present = c.m.LoadAndDelete(key)
+	return
+}
+
+// RemoveOldest removes the least recently used entry, returning its key and value, as well as a
+// bool indicating whether there was one to remove. Unlike eviction, RemoveOldest visits every
+// entry to find the least recently used, as [Map.Oldest] does. RemoveOldest is safe for concurrent
+// use.
+func (c *LRU[K, V]) RemoveOldest() (key K, value V, ok bool) {
+	return c.m.removeOldest()
+}
+
+// GetOldest returns the least recently used entry without removing it or marking it as recently
+// used, as well as a bool indicating whether there was one, like [Map.Oldest]. GetOldest is safe
+// for concurrent use.
+func (c *LRU[K, V]) GetOldest() (key K, value V, ok bool) {
+	e, ok := c.m.Oldest()
+	return e.Key, e.Value, ok
+}
+
+// Keys returns the keys, in no particular order. Keys is safe for concurrent use.
+func (c *LRU[K, V]) Keys() []K {
+	keys := make([]K, 0, c.m.Length())
+	c.m.Range(func(key K, _ V) bool {
+		keys = append(keys, key)
+		return true
+	})
+
+	return keys
+}
+
+// Values returns the values, in no particular order. Values is safe for concurrent use.
+func (c *LRU[K, V]) Values() []V {
+	values := make([]V, 0, c.m.Length())
+	c.m.Range(func(_ K, value V) bool {
+		values = append(values, value)
+		return true
+	})
+
+	return values
+}
+
+// Len returns the number of entries, like [Map.Length]. Len is safe for concurrent use.
+func (c *LRU[K, V]) Len() int {
+	return c.m.Length()
+}
+
+// Resize changes the number of entries the [LRU] may hold to size, evicting entries as needed, and
+// returns the number evicted. Resize has no effect if size is not positive. Resize is safe for
+// concurrent use.
+func (c *LRU[K, V]) Resize(size int) (evicted int) {
+	if size <= 0 {
+		return 0
+	}
+
+	return c.m.resize(size)
+}
+
+// Purge removes every entry, like [Map.Clear]. Purge is safe for concurrent use.
+func (c *LRU[K, V]) Purge() {
+	c.m.Clear()
+}
+
+// Map returns the [Map] holding the entries, for access to the methods that golang-lru lacks.
+func (c *LRU[K, V]) Map() *Map[K, V] {
+	return c.m
+}
+
+// Close stops the [LRU] from pruning expired entries, like [Map.Close].
+func (c *LRU[K, V]) Close() {
+	c.m.Close()
+}
+
+// CloseWait is like [LRU.Close], but also waits for the prune goroutine to exit, like
+// [Map.CloseWait].
+func (c *LRU[K, V]) CloseWait() {
+	c.m.CloseWait()
+}
@@ -0,0 +1,140 @@
+package ttl_test
+
+import (
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestLRU() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	_, err := ttl.NewLRU[string, int](0)
+	s.Error(err)
+
+	c, err := ttl.NewLRU[string, int](2, ttl.WithClock(clock), ttl.WithPruneInterval(0))
+	s.Require().NoError(err)
+	defer c.Close()
+
+	s.False(c.Add("a", 1))
+	clock.Advance(time.Second)
+	s.False(c.Add("b", 2))
+	clock.Advance(time.Second)
+
+	// Getting "a" makes "b" the least recently used
+	v, ok := c.Get("a")
+	s.True(ok)
+	s.Equal(1, v)
+	clock.Advance(time.Second)
+
+	s.True(c.Add("c", 3))
+	s.Equal(2, c.Len())
+	s.False(c.Contains("b"))
+	s.ElementsMatch([]string{"a", "c"}, c.Keys())
+	s.ElementsMatch([]int{1, 3}, c.Values())
+
+	// Peek and Contains do not mark keys as used, so "a" is evicted next
+	clock.Advance(time.Second)
+	_, _ = c.Peek("a")
+	s.True(c.Contains("a"))
+	_, _ = c.Get("c")
+	clock.Advance(time.Second)
+
+	s.True(c.Add("d", 4))
+	s.False(c.Contains("a"))
+
+	// Replacing a value evicts nothing
+	s.False(c.Add("d", 5))
+
+	s.True(c.Remove("d"))
+	s.False(c.Remove("d"))
+	s.Equal(1, c.Len())
+
+	// Entries never expire unless a TTL is set
+	clock.Advance(24 * time.Hour)
+	s.True(c.Contains("c"))
+
+	c.Purge()
+	s.Zero(c.Len())
+}
+
+func (s *MapTestSuite) TestLRUWithTTL() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	c, err := ttl.NewLRU[string, int](10,
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0))
+	s.Require().NoError(err)
+	defer c.Close()
+
+	c.Add("a", 1)
+	clock.Advance(time.Minute)
+
+	_, ok := c.Get("a")
+	s.False(ok)
+	s.False(c.Remove("a"))
+	s.Zero(c.Map().Length())
+}
+
+func (s *MapTestSuite) TestLRUOldest() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	c, err := ttl.NewLRU[string, int](3, ttl.WithClock(clock), ttl.WithPruneInterval(0))
+	s.Require().NoError(err)
+	defer c.Close()
+
+	_, _, ok := c.GetOldest()
+	s.False(ok)
+	_, _, ok = c.RemoveOldest()
+	s.False(ok)
+
+	ok, evicted := c.ContainsOrAdd("a", 1)
+	s.False(ok)
+	s.False(evicted)
+	clock.Advance(time.Second)
+
+	// ContainsOrAdd does not replace or mark the key as used
+	ok, _ = c.ContainsOrAdd("a", 2)
+	s.True(ok)
+	v, _ := c.Peek("a")
+	s.Equal(1, v)
+
+	previous, ok, _ := c.PeekOrAdd("b", 2)
+	s.False(ok)
+	s.Zero(previous)
+	clock.Advance(time.Second)
+
+	previous, ok, _ = c.PeekOrAdd("b", 3)
+	s.True(ok)
+	s.Equal(2, previous)
+
+	c.Add("c", 3)
+	clock.Advance(time.Second)
+
+	ok, evicted = c.ContainsOrAdd("d", 4)
+	s.False(ok)
+	s.True(evicted)
+	s.Equal(3, c.Len())
+	clock.Advance(time.Second)
+
+	key, value, ok := c.GetOldest()
+	s.True(ok)
+	s.Equal("b", key)
+	s.Equal(2, value)
+
+	key, value, ok = c.RemoveOldest()
+	s.True(ok)
+	s.Equal("b", key)
+	s.Equal(2, value)
+	s.False(c.Contains("b"))
+
+	s.Equal(1, c.Resize(1))
+	s.Equal([]string{"d"}, c.Keys())
+	s.Zero(c.Resize(0))
+	s.Zero(c.Resize(2))
+
+	c.Add("e", 5)
+	s.Equal(2, c.Len())
+}
@@ -2,7 +2,9 @@ package ttl
 
 import (
 	"context"
-	"maps"
+	"log/slog"
+	"math"
+	"math/rand"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -12,10 +14,119 @@ type mapItem[V any] struct {
 	value      V
 	itemTTL    time.Duration
 	lastAccess atomic.Int64
+	policy     RefreshPolicy
+	dead       bool  // the item was expired explicitly with Map.Expire
+	pinned     bool  // the item does not expire while pinned
+	created    int64 // Unix nanoseconds
+	written    int64 // Unix nanoseconds of the last write of the value
+	defaulted  bool  // the item's TTL is the Map's default TTL, see Map.SetDefaultTTL
+	cost       int64 // the cost of the value, if WithMaxCost is used
+	accesses   atomic.Uint64
+	tags       []string // set by Map.StoreTagged
+	group      string   // set by Map.StoreInGroup
 }
 
-func (i *mapItem[V]) touch() {
-	i.lastAccess.Store(time.Now().UnixNano())
+func newMapItem[V any](now int64) *mapItem[V] {
+	it := &mapItem[V]{created: now, written: now}
+	it.lastAccess.Store(now)
+
+	return it
+}
+
+// touch sets the last access time of the item to now, expressed in Unix nanoseconds.
+func (i *mapItem[V]) touch(now int64) {
+	i.lastAccess.Store(now)
+}
+
+// clone returns a copy of the item. The caller must hold at least a read lock on the item's map.
+func (i *mapItem[V]) clone() *mapItem[V] {
+	c := &mapItem[V]{
+		value:     i.value,
+		itemTTL:   i.itemTTL,
+		policy:    i.policy,
+		dead:      i.dead,
+		pinned:    i.pinned,
+		created:   i.created,
+		written:   i.written,
+		defaulted: i.defaulted,
+	}
+	c.lastAccess.Store(i.lastAccess.Load())
+
+	return c
+}
+
+// fixed reports whether the item expires at a fixed time, such that access does not extend its
+// lifetime.
+func (i *mapItem[V]) fixed() bool {
+	return i.policy == RefreshNever
+}
+
+// refreshesOnLoad reports whether loading the item should update its last access time, given the
+// map-wide setting.
+func (i *mapItem[V]) refreshesOnLoad(mapDefault bool) bool {
+	switch i.policy {
+	case RefreshOnLoad:
+		return true
+	case RefreshNever:
+		return false
+	default:
+		return mapDefault
+	}
+}
+
+// RefreshPolicy determines whether accessing an individual entry extends its lifetime.
+type RefreshPolicy int
+
+const (
+	// RefreshDefault follows the refreshOnLoad setting of the [Map].
+	RefreshDefault RefreshPolicy = iota
+
+	// RefreshOnLoad refreshes the entry's last access time on every [Map.Load], even if the [Map]
+	// does not refresh on load.
+	RefreshOnLoad
+
+	// RefreshNever gives the entry a fixed lifetime: neither loads, [Map.Touch] nor [Map.Store] to
+	// the existing key extend it.
+	RefreshNever
+)
+
+// NoExpiry is the remaining time to live reported for an entry that never expires, because it was
+// stored with a zero or negative TTL.
+const NoExpiry = time.Duration(math.MaxInt64)
+
+// immortal reports whether the item never expires.
+func (i *mapItem[V]) immortal() bool {
+	return i.itemTTL <= 0
+}
+
+// remaining returns the item's time to live remaining as of now, expressed in Unix nanoseconds. It
+// returns NoExpiry if the item never expires.
+func (i *mapItem[V]) remaining(now int64) time.Duration {
+	if i.immortal() {
+		return NoExpiry
+	}
+
+	return i.itemTTL - time.Duration(now-i.lastAccess.Load())
+}
+
+// expiresAt returns the time at which the item will expire, in Unix nanoseconds. It returns
+// math.MaxInt64 if the item never expires.
+func (i *mapItem[V]) expiresAt() int64 {
+	if i.immortal() {
+		return math.MaxInt64
+	}
+
+	return i.lastAccess.Load() + int64(i.itemTTL)
+}
+
+func (i *mapItem[V]) expired(now int64) bool {
+	return i.expiredAccessed(now, i.lastAccess.Load())
+}
+
+// expiredAccessed reports whether the item has expired as of now, if it was last accessed at
+// lastAccess, both expressed in Unix nanoseconds.
+func (i *mapItem[V]) expiredAccessed(now int64, lastAccess int64) bool {
+	return i.dead || (!i.pinned && !i.immortal() && i.itemTTL <= time.Duration(now-lastAccess))
 }
 
 // Map is a "time-to-live" map such that after a given amount of time, items in the map are deleted.
@@ -27,6 +138,13 @@ func (i *mapItem[V]) touch() {
 //
 // [Map.LoadPassive] can be used in which case the lastAccess time will *not* be updated.
 //
+// An item stored with a zero or negative TTL never expires, so permanent and expiring items may be
+// mixed in one Map.
+//
+// Items are removed from the Map by a prune pass, which runs periodically. By default, an item
+// whose TTL has elapsed is treated as missing by loads and iteration even before it has been
+// pruned, so expired items are never returned. See [WithLazyExpiry].
+//
 // Adapted from: https://stackoverflow.com/a/25487392/452281
 type Map[K comparable, V any] struct {
 	m             map[K]*mapItem[V]
@@ -34,22 +152,73 @@ type Map[K comparable, V any] struct {
 	defaultTTL    time.Duration
 	refreshOnLoad bool
 	stop          chan bool
+	done          chan struct{}
+	finished      []<-chan struct{} // closed when the other background goroutines have exited
 	closed        atomic.Bool
+	paused        atomic.Bool
+	length        atomic.Int64 // len(m), updated whenever the write lock is released
+	stats         mapStats
+	missValue     func(K) V
+	lazyExpiry    bool
+	statsScope    func(K) string
+	coalesce      time.Duration
+	equal         func(V, V) bool
+	pruneBatch    int
+	strategy      PruneStrategy
+	expiry        expiryIndex[K] // only used by the PruneHeap and PruneTimingWheel strategies
+	pruneKeys     []K            // keys remaining in the current bounded prune cycle, owned by prune
+	iterBatch     int
+	onExpire      func(K, V)
+	onRemove      func(K, V, RemovalReason)
+	logger        *slog.Logger
+	clock         Clock
+	ttlJitter     float64
+	expired       *expiredStream[K, V] // only set if WithExpiredChannel is used
+	watchers      map[K][]chan Event[V]
+	stored        []K // watched keys stored to while the write lock is held
+	maxEntries    int
+	maxCost       int64
+	costOf        func(K, V) int64
+	totalCost     int64               // the total cost of the items, if maxCost is set
+	added         []K                 // keys added while the write lock is held, if maxEntries is set
+	sketch        *frequencySketch[K] // only used by AdmitTinyLFU
+	pending       []removal[K, V]     // removals to report once the write lock is released
+	store         Store[K, V]         // only set if WithWriteThrough is used
+	readThrough   Store[K, V]         // only set if WithReadThrough is used
+	storeCtx      context.Context
+	loadMtx       sync.Mutex
+	loading       map[K]*loadCall[V] // loads from the read-through store in progress, by key
+	writes        []write[K, V]      // changes to write to the store once the write lock is released
+	storeMtx      sync.Mutex         // held while writing to the store, so that writes stay in order
+	behind        *writeBehind[K, V] // only set if WithWriteBehind is used
+	readMostly    bool
+	view          atomic.Pointer[readView[K, V]] // only used if readMostly is set
+	prefixes      *prefixIndex[K]                // only set if WithPrefixIndex is used
+	samples       *sampleIndex[K]                // only set if WithSampleIndex is used
+	tags          map[string]map[K]struct{}      // the keys with each tag, see StoreTagged
+	groups        map[string]map[K]struct{}      // the keys in each group, see StoreInGroup
+
+	cancelMtx   sync.Mutex
+	cancelStops []func() bool // unregister functions for contexts added with AlsoCancelOn
 }
 
 // NewMap returns a new [Map] with items expiring according to the defaultTTL specified if
 // they have not been accessed within that duration. Access refresh can be overridden so that
 // items expire after the TTL whether they have been accessed or not.
 //
+// Additional behaviour may be configured with opts. NewMap is equivalent to calling [New] with
+// [WithTTL], [WithCapacity], [WithPruneInterval] and [WithRefreshOnLoad] followed by opts.
+//
 // [Map] objects returned by NewMap must be closed with [Map.Close] when they're no longer needed.
 func NewMap[K comparable, V any](
 	defaultTTL time.Duration,
 	length int,
 	pruneInterval time.Duration,
 	refreshOnLoad bool,
+	opts ...Option,
 ) (m *Map[K, V]) {
 	ctx := context.Background()
-	return NewMapContext[K, V](ctx, defaultTTL, length, pruneInterval, refreshOnLoad)
+	return NewMapContext[K, V](ctx, defaultTTL, length, pruneInterval, refreshOnLoad, opts...)
 }
 
 // NewMapContext returns a new [Map] with items expiring according to the defaultTTL specified if
@@ -62,6 +231,10 @@ func NewMap[K comparable, V any](
 // context.Background() is perfectly acceptable as the default context, however you should
 // [Map.Close] the Map yourself in that case.
 //
+// Additional behaviour may be configured with opts. NewMapContext is equivalent to calling [New]
+// with [WithContext], [WithTTL], [WithCapacity], [WithPruneInterval] and [WithRefreshOnLoad]
+// followed by opts.
+//
 // [Map] objects returned by NewMapContext may still be closed with [Map.Close] when they're no
 // longer needed or if the cancellation of the context is not guaranteed.
 func NewMapContext[K comparable, V any](
@@ -70,39 +243,252 @@ func NewMapContext[K comparable, V any](
 	length int,
 	pruneInterval time.Duration,
 	refreshOnLoad bool,
+	opts ...Option,
 ) (m *Map[K, V]) {
-	if length < 0 {
-		length = 0
+	positional := []Option{
+		WithContext(ctx),
+		WithTTL(defaultTTL),
+		WithCapacity(length),
+		WithPruneInterval(pruneInterval),
+		WithRefreshOnLoad(refreshOnLoad),
+	}
+
+	return New[K, V](append(positional, opts...)...)
+}
+
+// New returns a new [Map] configured by opts. Options that are not specified take their default
+// values: items expire after [DefaultTTL] unless they are accessed, and expired items are pruned
+// every [DefaultPruneInterval].
+//
+// If the prune interval is zero or negative, no background pruning takes place and no goroutine is
+// started. Expired items are still hidden from loads (see [WithLazyExpiry]), but remain in the
+// [Map] until they are deleted or stored over.
+//
+// [Map] objects returned by New must be closed with [Map.Close] when they're no longer needed,
+// unless a context was provided using [WithContext] and it is guaranteed to be cancelled.
+func New[K comparable, V any](opts ...Option) (m *Map[K, V]) {
+	o := defaultOptions()
+	o.apply(opts)
+	o.logMisconfiguration()
+
+	m = newMap[K, V](o)
+	m.start(o)
+
+	return
+}
+
+// start starts the background goroutines of a [Map] created by newMap, as configured by o.
+func (m *Map[K, V]) start(o options) {
+	if o.pruneInterval > 0 {
+		// The ticker is created before the goroutine starts, so that it counts from the time the
+		// Map is created
+		go m.prune(o.ctx, o.clock.NewTicker(o.jitteredPruneInterval()), o.pruneInterval)
+	} else {
+		close(m.done)
 	}
 
+	if o.snapshotPath != "" {
+		m.finished = append(m.finished, startAutoSave(o, m, m.stop))
+	}
+
+	if m.behind != nil {
+		m.finished = append(m.finished, m.behind.start(o, m.Close, m.stop))
+	}
+}
+
+// newMap returns a new [Map] configured by o, without starting background pruning. The caller is
+// responsible for starting the prune goroutine or closing m.done.
+func newMap[K comparable, V any](o options) (m *Map[K, V]) {
 	m = &Map[K, V]{
-		m:             make(map[K]*mapItem[V], length),
-		defaultTTL:    defaultTTL,
-		refreshOnLoad: refreshOnLoad,
+		m:             make(map[K]*mapItem[V], max(o.capacity, 0)),
+		defaultTTL:    o.ttl,
+		refreshOnLoad: o.refreshOnLoad,
 		stop:          make(chan bool),
+		done:          make(chan struct{}),
+		missValue:     typedOption[func(K) V]("WithMissValue", o.missValue),
+		lazyExpiry:    o.lazyExpiry,
+		statsScope:    typedOption[func(K) string]("WithStatsScope", o.statsScope),
+		coalesce:      o.coalesceWindow,
+		equal:         typedOption[func(V, V) bool]("WithWriteCoalescing", o.coalesceEqual),
+		pruneBatch:    o.pruneBatch,
+		strategy:      o.pruneStrategy,
+		readMostly:    o.readMostly,
+		iterBatch:     o.iterBatch,
+		onExpire:      typedOption[func(K, V)]("WithOnExpire", o.onExpire),
+		onRemove:      typedOption[func(K, V, RemovalReason)]("WithOnRemove", o.onRemove),
+		store:         typedOption[Store[K, V]]("WithWriteThrough", o.store),
+		readThrough:   typedOption[Store[K, V]]("WithReadThrough", o.readThrough),
+		storeCtx:      context.WithoutCancel(o.ctx),
+		logger:        o.logger,
+		clock:         o.clock,
+		ttlJitter:     o.ttlJitter,
+	}
+
+	m.stats.counting = o.counters
+
+	if o.quantileStats {
+		m.stats.quantiles = &quantileStats{}
+	}
+
+	if o.expiredBuffer > 0 {
+		m.expired = newExpiredStream[K, V](o.expiredBuffer)
+	}
+
+	if o.writeBehind {
+		m.behind = newWriteBehind(m.store, m.storeCtx, o.logger)
+	}
+
+	if o.maxEntries > 0 {
+		m.maxEntries = o.maxEntries
+	}
+
+	if o.maxCost > 0 {
+		m.maxCost = o.maxCost
+		m.costOf = typedOption[func(K, V) int64]("WithMaxCost", o.costOf)
+
+		if o.memoryCost {
+			m.costOf = memoryCost[K, V]()
+		}
+	}
+
+	if m.bounded() && o.admission == AdmitTinyLFU {
+		hash := typedOption[func(K) uint64]("WithHasher", o.hasher)
+		if hash == nil {
+			hash = defaultHasher[K]()
+		}
+
+		entries := o.maxEntries
+		if entries <= 0 {
+			entries = max(o.capacity, sketchMinEntries)
+		}
+
+		m.sketch = newFrequencySketch[K](entries, hash)
 	}
 
-	go func() {
-		ticker := time.NewTicker(pruneInterval)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				m.Close()
-				return
-			case <-m.stop:
-				return
-			case now := <-ticker.C:
-				currentTime := now.UnixNano()
-				m.mtx.Lock()
-				maps.DeleteFunc(m.m, func(key K, item *mapItem[V]) bool {
-					return currentTime-item.lastAccess.Load() >= int64(item.itemTTL)
-				})
-				m.mtx.Unlock()
+	if prefixKey := typedOption[func(K) string]("WithPrefixIndex", o.prefixKey); prefixKey != nil {
+		m.prefixes = newPrefixIndex(prefixKey)
+	}
+
+	if o.sampleIndex {
+		m.samples = newSampleIndex[K]()
+	}
+
+	if o.pruneInterval > 0 {
+		switch o.pruneStrategy {
+		case PruneHeap:
+			m.expiry = NewExpiryList[K]()
+		case PruneTimingWheel:
+			m.expiry = newTimingWheel[K](int64(o.pruneInterval), m.now())
+		}
+	}
+
+	return
+}
+
+// prune removes expired items from the map on each tick of ticker, which ticks every
+// pruneInterval, until the map is closed or ctx is cancelled.
+func (m *Map[K, V]) prune(ctx context.Context, ticker Ticker, pruneInterval time.Duration) {
+	defer close(m.done)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			m.Close()
+			return
+		case <-m.stop:
+			return
+		case <-ticker.C():
+			// The time of the tick may be stale if the previous pass was slow, so read the clock
+			if !m.paused.Load() {
+				start := time.Now()
+				m.logPrune(start, m.pruneTick(m.clock.Now(), pruneInterval))
 			}
 		}
-	}()
+	}
+}
+
+// logPrune logs a background prune pass that started at start and removed pruned items, if a
+// logger is set.
+func (m *Map[K, V]) logPrune(start time.Time, pruned int) {
+	if m.logger != nil {
+		m.logger.Debug("prune pass", "pruned", pruned, "length", m.Length(), "duration", time.Since(start))
+	}
+}
+
+// pruneTick runs a single background prune pass at now using the configured strategy. budget is
+// the prune interval, which bounds the time spent by strategies that repeat. It returns the number
+// of items removed.
+func (m *Map[K, V]) pruneTick(now time.Time, budget time.Duration) int {
+	switch {
+	case m.strategy == PruneSampled:
+		return m.pruneSampled(budget)
+	case m.expiry != nil:
+		return m.pruneIndexed(now.UnixNano())
+	case m.pruneBatch > 0:
+		return m.pruneBatchAt(now.UnixNano())
+	default:
+		return m.pruneAt(now.UnixNano())
+	}
+}
+
+// pruneBatchAt checks the next batch of at most pruneBatch keys of the current prune cycle,
+// removing those that have expired as of now, expressed in Unix nanoseconds. When a cycle is
+// finished, the keys of the map are captured under the read lock to start the next one, so keys
+// stored during a cycle are checked in the next. It returns the number of items removed.
+func (m *Map[K, V]) pruneBatchAt(now int64) (pruned int) {
+	if len(m.pruneKeys) == 0 {
+		m.mtx.RLock()
+		for key := range m.m {
+			m.pruneKeys = append(m.pruneKeys, key)
+		}
+		m.mtx.RUnlock()
+	}
+
+	n := min(m.pruneBatch, len(m.pruneKeys))
+	batch := m.pruneKeys[:n]
+
+	m.mtx.Lock()
+	for _, key := range batch {
+		if item, ok := m.m[key]; ok && item.expired(now) {
+			m.removeLocked(key, ReasonExpired)
+			pruned++
+		}
+	}
+	m.unlock()
+
+	// Release the keys that have been checked so that they can be garbage collected
+	clear(batch)
+	m.pruneKeys = m.pruneKeys[n:]
+	if len(m.pruneKeys) == 0 {
+		m.pruneKeys = nil
+	}
+
+	return
+}
+
+// Prune immediately removes all expired items from the [Map] and returns the number of items
+// removed. This is the same pass that background pruning runs every prune interval, exposed for
+// embedders that schedule pruning themselves (for example, with background pruning disabled using
+// [WithPruneInterval]). Prune works even if pruning is paused or the [Map] has been closed.
+//
+// Prune is safe for concurrent use.
+func (m *Map[K, V]) Prune() int {
+	return m.pruneAt(m.now())
+}
+
+// pruneAt removes all items that have expired as of now, expressed in Unix nanoseconds, and returns
+// the number of items removed.
+func (m *Map[K, V]) pruneAt(now int64) (pruned int) {
+	m.mtx.Lock()
+	defer m.unlock()
+
+	for key, item := range m.m {
+		if item.expired(now) {
+			m.removeLocked(key, ReasonExpired)
+			pruned++
+		}
+	}
 
 	return
 }
@@ -114,122 +500,1258 @@ func NewMapContext[K comparable, V any](
 func (m *Map[K, V]) Close() {
 	if m.closed.CompareAndSwap(false, true) {
 		close(m.stop)
+
+		if m.expired != nil {
+			m.expired.close()
+		}
+
+		m.cancelMtx.Lock()
+		for _, stop := range m.cancelStops {
+			stop()
+		}
+		m.cancelStops = nil
+		m.cancelMtx.Unlock()
+	}
+}
+
+// PausePruning suspends background pruning of the [Map] until [Map.ResumePruning] is called, so
+// that no expired items are removed (for example, during a bulk import or while debugging). A prune
+// pass that is already in progress runs to completion.
+//
+// Pausing does not stop expired items from being treated as missing by loads and iteration unless
+// lazy expiry has been disabled with [WithLazyExpiry]. Unlike [Map.Close], pausing can be undone.
+// PausePruning may be called multiple times and is safe for concurrent use.
+func (m *Map[K, V]) PausePruning() {
+	m.paused.Store(true)
+}
+
+// ResumePruning resumes background pruning of the [Map] after [Map.PausePruning]. Items that
+// expired while pruning was paused are removed by the next prune pass. ResumePruning has no effect
+// if pruning is not paused, and does not restart pruning of a [Map] that has been closed. It is
+// safe for concurrent use.
+func (m *Map[K, V]) ResumePruning() {
+	m.paused.Store(false)
+}
+
+// AlsoCancelOn adds ctx as an additional cancellation source for the [Map]. If ctx is cancelled,
+// pruning stops as if [Map.Close] had been called, just like the context the [Map] was created
+// with. This allows the lifetime of a [Map] to be tied to several contexts (for example, both
+// application shutdown and tenant teardown) without merging them.
+//
+// AlsoCancelOn does nothing if the [Map] is already closed. It is safe for concurrent use.
+func (m *Map[K, V]) AlsoCancelOn(ctx context.Context) {
+	m.cancelMtx.Lock()
+	defer m.cancelMtx.Unlock()
+
+	if m.closed.Load() {
+		return
+	}
+
+	m.cancelStops = append(m.cancelStops, context.AfterFunc(ctx, m.Close))
+}
+
+// CloseWait terminates TTL pruning of the Map like [Map.Close], then blocks until the pruning
+// goroutine has exited. When CloseWait returns, no prune pass is in progress and none will start,
+// and the callbacks for the removals made by the last prune pass (such as those set using
+// [WithOnExpire]) have returned, so resources referenced by the [Map]'s values may be released
+// safely and tests can check for leaked goroutines without sleeping. If [WithSnapshotFile] is used,
+// CloseWait also waits for the final save of the file, and if [WithWriteBehind] is used, it waits
+// for the queued writes to be made.
+//
+// CloseWait may be called multiple times, and concurrently with [Map.Close], but not from a
+// callback of the [Map], which would wait for itself.
+func (m *Map[K, V]) CloseWait() {
+	m.Close()
+	<-m.done
+
+	for _, finished := range m.finished {
+		<-finished
 	}
 }
 
-// Length returns the current length of the [Map]'s internal map. Length is safe for concurrent use.
+// Length returns the current length of the [Map]'s internal map. Length does not take the lock on
+// the [Map], so it is cheap enough to call frequently (for example, to export as a metric). Length
+// is safe for concurrent use.
 func (m *Map[K, V]) Length() int {
-	m.mtx.RLock()
-	defer m.mtx.RUnlock()
+	return int(m.length.Load())
+}
+
+// lock acquires the write lock of the map. Since the caller may modify the map, the read view used
+// by WithReadMostly is discarded.
+func (m *Map[K, V]) lock() {
+	m.mtx.Lock()
+	m.view.Store(nil)
+}
+
+// unlock releases the write lock of the map, first recording its length so that [Map.Length] does
+// not need the lock. Queued writes are then made to the store and removals queued while the lock
+// was held are reported to the callbacks, which may therefore call methods of the map. unlock
+// returns the number of entries evicted to keep the map within its capacity.
+func (m *Map[K, V]) unlock() (evicted int) {
+	r := m.release()
+	r.write()
+
+	return r.report()
+}
+
+// release releases the write lock of the map like unlock, but returns the writes and removals
+// queued while it was held rather than making and reporting them. A caller holding the locks of
+// several maps releases all of them before making the writes of each, then reporting the removals
+// of each, so that a callback may call methods of any of the maps.
+func (m *Map[K, V]) release() released[K, V] {
+	if m.costOf != nil {
+		m.costStoredLocked()
+	}
+
+	// The values to write are read before eviction, which does not remove them from the store
+	r := released[K, V]{m: m, writes: m.resolveWritesLocked()}
+
+	if m.bounded() {
+		r.evicted = m.evictLocked()
+	}
+
+	m.notifyStoredLocked()
+	m.length.Store(int64(len(m.m)))
+
+	r.pending = m.pending
+	m.pending = nil
+
+	// The store lock is taken before the write lock is released, so that writes stay in order
+	if len(r.writes) > 0 {
+		m.storeMtx.Lock()
+	}
+
+	m.mtx.Unlock()
+
+	return r
+}
+
+// released holds the work left once the write lock of a map has been released by release.
+type released[K comparable, V any] struct {
+	m       *Map[K, V]
+	writes  []write[K, V]
+	pending []removal[K, V]
+	evicted int
+}
+
+// write makes the queued writes to the store, then releases the store lock.
+func (r released[K, V]) write() {
+	if len(r.writes) == 0 {
+		return
+	}
+
+	defer r.m.storeMtx.Unlock()
+
+	r.m.writeThrough(r.writes)
+}
+
+// report reports the queued removals to the callbacks, returning the number of entries evicted.
+// It must be called after write.
+func (r released[K, V]) report() (evicted int) {
+	m := r.m
+
+	if r.evicted > 0 && m.logger != nil {
+		m.logger.Debug("evicted entries to stay within capacity", "evicted", r.evicted, "length", m.Length())
+	}
+
+	for _, p := range r.pending {
+		m.report(p)
+	}
+
+	return r.evicted
+}
+
+// report calls the callbacks for a removal. If a logger is set, a panic in a callback is logged
+// rather than propagated.
+func (m *Map[K, V]) report(r removal[K, V]) {
+	if m.logger != nil {
+		defer func() {
+			if p := recover(); p != nil {
+				m.logger.Error("removal callback panicked", "key", r.key, "reason", r.reason, "panic", p)
+			}
+		}()
+	}
+
+	if r.reason == ReasonExpired && m.onExpire != nil {
+		m.onExpire(r.key, r.value)
+	}
 
-	return len(m.m)
+	if r.reason == ReasonExpired && m.expired != nil {
+		m.expired.send(Entry[K, V]{Key: r.key, Value: r.value})
+	}
+
+	if m.onRemove != nil {
+		m.onRemove(r.key, r.value, r.reason)
+	}
+}
+
+// removal is an entry removed from the map, queued to be reported to callbacks.
+type removal[K comparable, V any] struct {
+	key    K
+	value  V
+	reason RemovalReason
+}
+
+// queueRemovalLocked queues the removal of key and value to be reported to the callbacks once the
+// write lock is released, if there is a callback for reason. The caller must hold the write lock.
+func (m *Map[K, V]) queueRemovalLocked(key K, value V, reason RemovalReason) {
+	if m.onRemove != nil || (reason == ReasonExpired && (m.onExpire != nil || m.expired != nil)) {
+		m.pending = append(m.pending, removal[K, V]{key, value, reason})
+	}
+}
+
+// replacedLocked records that the value of key, old, is being overwritten by a store. The caller
+// must hold the write lock.
+func (m *Map[K, V]) replacedLocked(key K, old V) {
+	m.stats.removed(ReasonReplaced, 1)
+	m.queueRemovalLocked(key, old, ReasonReplaced)
 }
 
 // Load will retrieve a value from the [Map], as well as a bool indicating whether the key was
-// found. If the item was not found the value returned is undefined. Load is safe for concurrent
-// use.
+// found. If the item was not found the value returned is undefined, unless the [Map] was created
+// using [WithMissValue]. Load is safe for concurrent use.
 func (m *Map[K, V]) Load(key K) (value V, ok bool) {
 	return m.loadImpl(key, true)
 }
 
-// LoadPassive will retrieve a value from the [Map] (without updating that value's time to live),
-// as well as a bool indicating whether the key was found. If the item was not found the value
-// returned is undefined. LoadPassive is safe for concurrent use.
+// LoadContext is like [Map.Load], except that a key not found in the [Map] is loaded from the store
+// set using [WithReadThrough] with ctx, and an error is returned if that fails instead of being
+// logged. If ctx is done while waiting for the store, LoadContext returns ctx.Err(). Without
+// [WithReadThrough], LoadContext is the same as [Map.Load] and always returns a nil error.
+// LoadContext is safe for concurrent use.
+func (m *Map[K, V]) LoadContext(ctx context.Context, key K) (value V, ok bool, err error) {
+	if q := m.stats.quantiles; q != nil {
+		defer q.loadLatency.observeSince(time.Now())
+	}
+
+	return m.loadContext(ctx, key, true)
+}
+
+// LoadPassive will retrieve a value from the [Map] (without updating that value's time to live), as
+// well as a bool indicating whether the key was found. If the item was not found the value returned
+// is undefined, unless the [Map] was created using [WithMissValue]. LoadPassive is safe for
+// concurrent use.
 func (m *Map[K, V]) LoadPassive(key K) (value V, ok bool) {
 	return m.loadImpl(key, false)
 }
 
-// Store will insert a value into the [Map] with the default tome to live. If the key/value pair
-// already exists, the last access time will be updated, but the TTL will not be changed. This
-// is important if the key/value pair was created with a non-default TTL using [Map.StoreWithTTL].
-// Store is safe for concurrent use.
-func (m *Map[K, V]) Store(key K, value V) {
-	m.mtx.Lock()
-	defer m.mtx.Unlock()
+// LoadStale will retrieve a value from the [Map] like [Map.Load], but will also return a value
+// whose TTL has elapsed if it has not been pruned yet. stale reports whether the value returned has
+// expired, and ok reports whether a value was found at all. Loading a stale value does not update
+// its last access time, so it is not revived. LoadStale is safe for concurrent use.
+func (m *Map[K, V]) LoadStale(key K) (value V, stale bool, ok bool) {
+	if q := m.stats.quantiles; q != nil {
+		defer q.loadLatency.observeSince(time.Now())
+	}
 
-	it, ok := m.m[key]
-	if !ok {
-		it = &mapItem[V]{
-			itemTTL: m.defaultTTL,
+	m.mtx.RLock()
+
+	var it *mapItem[V]
+	if it, ok = m.m[key]; ok {
+		value = it.value
+		stale = it.expired(m.now())
+
+		if !stale && it.refreshesOnLoad(m.refreshOnLoad) {
+			it.touch(m.now())
 		}
-		m.m[key] = it
 	}
 
-	it.value = value
-	it.touch()
-}
+	m.mtx.RUnlock()
 
-// StoreWithTTL will insert a value into the [Map] with a custom time to live. If the key/value pair
-// already exists, the last access time will be updated and the TTL will not be changed to the
-// parameter value. Store is safe for concurrent use.
-func (m *Map[K, V]) StoreWithTTL(key K, value V, TTL time.Duration) {
-	m.mtx.Lock()
-	defer m.mtx.Unlock()
+	m.recordLoad(key, ok)
 
-	it, ok := m.m[key]
-	if !ok {
-		it = &mapItem[V]{}
-		m.m[key] = it
+	if !ok && m.missValue != nil {
+		value = m.missValue(key)
 	}
 
-	it.value = value
-	it.itemTTL = TTL
-	it.touch()
+	return
 }
 
-func (m *Map[K, V]) loadImpl(key K, update bool) (value V, ok bool) {
+// Touch updates the last access time of key without reading its value, extending its lifetime as a
+// [Map.Load] would. Touch refreshes the key even if the [Map] does not refresh on load, but never
+// extends an entry with the [RefreshNever] policy, such as one stored with [Map.StoreWithExpireAt].
+// Touch returns false if the key was not found. Touch is safe for concurrent use.
+func (m *Map[K, V]) Touch(key K) bool {
 	m.mtx.RLock()
 	defer m.mtx.RUnlock()
 
-	var it *mapItem[V]
+	it, ok := m.liveItemLocked(key)
+	if !ok {
+		return false
+	}
 
-	if it, ok = m.m[key]; !ok {
-		return
+	if !it.fixed() {
+		it.touch(m.now())
 	}
 
-	value = it.value
+	return true
+}
 
-	if !update || !m.refreshOnLoad {
-		return
+// Extend lengthens the remaining time to live of key by extra without replacing its value or
+// updating its last access time. This also applies to entries stored with
+// [Map.StoreWithExpireAt], whose expiry time is moved later by extra. Extending an entry that
+// never expires has no effect. Extend returns false if the key was not found. Extend is safe for
+// concurrent use.
+func (m *Map[K, V]) Extend(key K, extra time.Duration) bool {
+	m.lock()
+	defer m.unlock()
+
+	it, ok := m.liveItemLocked(key)
+	if !ok {
+		return false
 	}
 
-	it.touch()
+	if !it.immortal() {
+		it.itemTTL = max(it.itemTTL+extra, 1)
+		it.defaulted = false
+		m.recheckLocked(key)
+	}
 
-	return
+	return true
 }
 
-// Delete will remove a key and its value from the [Map]. Delete is safe for concurrent use.
-func (m *Map[K, V]) Delete(key K) {
-	m.mtx.Lock()
-	defer m.mtx.Unlock()
+// SetTTL changes the TTL of key to TTL without replacing its value or updating its last access
+// time, so the new TTL is measured from the entry's last access. The entry's [RefreshPolicy] is
+// unchanged. A zero or negative TTL means the entry never expires. SetTTL returns false if the key
+// was not found. SetTTL is safe for concurrent use.
+func (m *Map[K, V]) SetTTL(key K, TTL time.Duration) bool {
+	m.lock()
+	defer m.unlock()
 
-	delete(m.m, key)
+	it, ok := m.liveItemLocked(key)
+	if !ok {
+		return false
+	}
+
+	it.itemTTL = TTL
+	it.defaulted = false
+	m.recheckLocked(key)
+
+	return true
 }
 
-// DeleteFunc deletes any key/value pairs from the [Map] for which del returns true. DeleteFunc is
-// safe for concurrent use.
-func (m *Map[K, V]) DeleteFunc(del func(key K, value V) bool) {
-	m.mtx.Lock()
-	defer m.mtx.Unlock()
+// SetDefaultTTL changes the default time to live of the [Map], used by subsequent calls to
+// [Map.Store] that create a new entry. A zero or negative TTL means such entries never expire.
+//
+// If updateExisting is true, the new TTL is also applied to existing entries that were stored with
+// the previous default TTL, measured from each entry's last access, exactly as if [Map.SetTTL] had
+// been called for each of them. Entries stored with an explicit TTL (for example, using
+// [Map.StoreWithTTL]) or whose TTL has since been changed are not affected.
+//
+// SetDefaultTTL is safe for concurrent use.
+func (m *Map[K, V]) SetDefaultTTL(TTL time.Duration, updateExisting bool) {
+	m.lock()
+	defer m.unlock()
 
-	for key, item := range m.m {
-		if del(key, item.value) {
-			delete(m.m, key)
+	m.defaultTTL = TTL
+
+	if !updateExisting {
+		return
+	}
+
+	for key, it := range m.m {
+		if it.defaulted {
+			it.itemTTL = m.jittered(TTL)
+			m.recheckLocked(key)
 		}
 	}
 }
 
-// Clear will remove all key/value pairs from the [Map]. Clear is safe for concurrent use.
-func (m *Map[K, V]) Clear() {
-	m.mtx.Lock()
-	defer m.mtx.Unlock()
+// DefaultTTL returns the default time to live of the [Map], used by [Map.Store] for new keys.
+// DefaultTTL is safe for concurrent use.
+func (m *Map[K, V]) DefaultTTL() time.Duration {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
 
-	clear(m.m)
+	return m.defaultTTL
 }
 
-// Range calls f sequentially for each key and value present in the [Map]. If f returns false, Range
-// stops the iteration.
-//
+// TTL returns the time to live remaining for key, as well as a bool indicating whether the key was
+// found. TTL does not update the key's last access time. If lazy expiry has been disabled with
+// [WithLazyExpiry] and the key's TTL has elapsed but it has not been pruned yet, the remaining time
+// returned is zero. If the key never expires, the remaining time returned is [NoExpiry]. TTL is
+// safe for concurrent use.
+func (m *Map[K, V]) TTL(key K) (remaining time.Duration, ok bool) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	it, ok := m.liveItemLocked(key)
+	if !ok {
+		return 0, false
+	}
+
+	return max(it.remaining(m.now()), 0), true
+}
+
+// ExpirationTime returns the time at which key will expire unless it is accessed again, as well as
+// a bool indicating whether the key was found. ExpirationTime does not update the key's last access
+// time. An entry is removed by the first prune pass after its expiration time. If the key never
+// expires, the zero time.Time is returned. ExpirationTime is safe for concurrent use.
+func (m *Map[K, V]) ExpirationTime(key K) (expireAt time.Time, ok bool) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	it, ok := m.liveItemLocked(key)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	if it.immortal() {
+		return time.Time{}, true
+	}
+
+	return time.Unix(0, it.expiresAt()), true
+}
+
+// NextExpiration returns the time at which the unexpired entry that expires soonest will expire,
+// unless it is accessed before then, as well as a bool indicating whether any entry will expire.
+// Entries that never expire or are pinned with [Map.Pin] are not considered. This lets callers
+// with their own scheduler sleep until the next expiration rather than polling. NextExpiration
+// visits every entry of the [Map], does not update any last access time and is safe for concurrent
+// use.
+func (m *Map[K, V]) NextExpiration() (expireAt time.Time, ok bool) {
+	now := m.now()
+
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	next := int64(math.MaxInt64)
+	for _, it := range m.m {
+		if !it.expired(now) && !it.pinned && !it.immortal() {
+			next = min(next, it.expiresAt())
+		}
+	}
+
+	if next == math.MaxInt64 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, next), true
+}
+
+// KeysExpiringBefore returns the keys whose expiration time is before t, including any whose TTL
+// has elapsed but which have not been pruned yet. The keys are returned in no particular order.
+// KeysExpiringBefore does not update any last access time and is safe for concurrent use.
+func (m *Map[K, V]) KeysExpiringBefore(t time.Time) []K {
+	deadline := t.UnixNano()
+
+	return m.keysFunc(func(it *mapItem[V]) bool {
+		return it.expiresAt() < deadline
+	})
+}
+
+// KeysExpiringAfter returns the keys whose expiration time is after t. The keys are returned in no
+// particular order. KeysExpiringAfter does not update any last access time and is safe for
+// concurrent use.
+func (m *Map[K, V]) KeysExpiringAfter(t time.Time) []K {
+	deadline := t.UnixNano()
+
+	return m.keysFunc(func(it *mapItem[V]) bool {
+		return it.expiresAt() > deadline
+	})
+}
+
+func (m *Map[K, V]) keysFunc(match func(it *mapItem[V]) bool) (keys []K) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	for key, it := range m.m {
+		if match(it) {
+			keys = append(keys, key)
+		}
+	}
+
+	return
+}
+
+// Store will insert a value into the [Map] with the default tome to live. If the key/value pair
+// already exists, the last access time will be updated, but the TTL will not be changed. This
+// is important if the key/value pair was created with a non-default TTL using [Map.StoreWithTTL].
+// Store is safe for concurrent use.
+func (m *Map[K, V]) Store(key K, value V) {
+	if q := m.stats.quantiles; q != nil {
+		defer q.storeLatency.observeSince(time.Now())
+	}
+
+	if m.coalesced(key, value, nil) {
+		return
+	}
+
+	m.lock()
+	defer m.unlock()
+
+	m.storeLocked(key, value)
+}
+
+// StoreWithTTL will insert a value into the [Map] with a custom time to live. A zero or negative
+// TTL means the entry never expires and remains in the [Map] until it is deleted. If the key/value
+// pair already exists, the last access time will be updated and the TTL will not be changed to the
+// parameter value. Store is safe for concurrent use.
+func (m *Map[K, V]) StoreWithTTL(key K, value V, TTL time.Duration) {
+	if q := m.stats.quantiles; q != nil {
+		defer q.storeLatency.observeSince(time.Now())
+	}
+
+	sameTTL := func(it *mapItem[V]) bool {
+		return m.sameJitteredTTL(it.itemTTL, TTL) && it.policy == RefreshDefault
+	}
+
+	if m.coalesced(key, value, sameTTL) {
+		return
+	}
+
+	m.lock()
+	defer m.unlock()
+
+	m.storeWithTTLLocked(key, value, TTL)
+}
+
+// storeWithTTLLocked is like [Map.StoreWithTTL]. The caller must hold the write lock.
+func (m *Map[K, V]) storeWithTTLLocked(key K, value V, TTL time.Duration) {
+	it, _ := m.storeItemLocked(key)
+
+	it.value = value
+	it.itemTTL = m.jittered(TTL)
+	it.policy = RefreshDefault
+	it.defaulted = false
+	it.touch(m.now())
+}
+
+// swap stores value as for [Map.Store], returning the previous value of key, as well as a bool
+// indicating whether it was found.
+func (m *Map[K, V]) swap(key K, value V) (previous V, loaded bool) {
+	m.lock()
+	defer m.unlock()
+
+	if it, ok := m.liveItemLocked(key); ok {
+		previous, loaded = it.value, true
+	}
+
+	m.storeLocked(key, value)
+
+	return
+}
+
+// compareAndSwap stores value as for [Map.Store] if key is present with a value equal to old,
+// comparing them as any(old) == any(current), and reports whether it did.
+func (m *Map[K, V]) compareAndSwap(key K, old, value V) (swapped bool) {
+	m.lock()
+	defer m.unlock()
+
+	it, ok := m.liveItemLocked(key)
+	if !ok || any(it.value) != any(old) {
+		return false
+	}
+
+	m.storeLocked(key, value)
+
+	return true
+}
+
+// compareAndDelete removes key if it is present with a value equal to old, comparing them as
+// any(old) == any(current), and reports whether it did.
+func (m *Map[K, V]) compareAndDelete(key K, old V) (deleted bool) {
+	m.lock()
+	defer m.unlock()
+
+	it, ok := m.liveItemLocked(key)
+	if !ok || any(it.value) != any(old) {
+		return false
+	}
+
+	m.removeLocked(key, ReasonDeleted)
+
+	return true
+}
+
+// storeEvicting is like [Map.Store], but reports whether entries were evicted to make room for the
+// value.
+func (m *Map[K, V]) storeEvicting(key K, value V) (evicted bool) {
+	if m.coalesced(key, value, nil) {
+		return false
+	}
+
+	m.lock()
+	m.storeLocked(key, value)
+
+	return m.unlock() > 0
+}
+
+// loadOrStoreEvicting returns the value of key, as well as true, if the key is present, without
+// updating its last access time. Otherwise, it stores value as for [Map.Store] and returns it, as
+// well as false. evicted reports whether entries were evicted to make room for the value.
+func (m *Map[K, V]) loadOrStoreEvicting(key K, value V) (actual V, loaded, evicted bool) {
+	m.lock()
+
+	if it, ok := m.liveItemLocked(key); ok {
+		actual, loaded = it.value, true
+	} else {
+		m.storeLocked(key, value)
+		actual = value
+	}
+
+	evicted = m.unlock() > 0
+
+	return
+}
+
+// storeLocked stores value as for [Map.Store]. The caller must hold the write lock.
+func (m *Map[K, V]) storeLocked(key K, value V) {
+	it, ok := m.storeItemLocked(key)
+	if !ok {
+		it.itemTTL = m.jittered(m.defaultTTL)
+		it.defaulted = true
+	}
+
+	it.value = value
+	if !it.fixed() {
+		it.touch(m.now())
+	}
+}
+
+// LoadOrStore returns the value of key, as well as true, if the key is present, like [Map.Load].
+// Otherwise, it stores value with the default time to live and returns it, as well as false. The
+// check and the store are atomic, so of concurrent calls for a missing key, only one stores its
+// value. LoadOrStore is safe for concurrent use.
+func (m *Map[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	return m.loadOrStore(key, func() V {
+		return value
+	})
+}
+
+// loadOrStore returns the value of key, as well as true, if the key is present. Otherwise, it
+// stores the value returned by create with the default time to live and returns it, as well as
+// false. The check and the store are atomic, so create is called at most once for a missing key,
+// with the write lock held.
+func (m *Map[K, V]) loadOrStore(key K, create func() V) (value V, loaded bool) {
+	if value, ok := m.loadItem(key, true); ok {
+		return value, true
+	}
+
+	m.lock()
+	defer m.unlock()
+
+	// The key may have been stored while the lock was not held
+	if it, ok := m.liveItemLocked(key); ok {
+		if it.refreshesOnLoad(m.refreshOnLoad) {
+			it.touch(m.now())
+		}
+		return it.value, true
+	}
+
+	it, _ := m.storeItemLocked(key)
+	it.itemTTL = m.jittered(m.defaultTTL)
+	it.defaulted = true
+	it.value = create()
+	it.touch(m.now())
+
+	return it.value, false
+}
+
+// coalesced implements write coalescing: if it is enabled and key holds a live value equal to value
+// that was written within the coalescing window and for which unchanged (if not nil) returns true,
+// the entry is touched under the read lock rather than rewritten, and coalesced returns true.
+func (m *Map[K, V]) coalesced(key K, value V, unchanged func(it *mapItem[V]) bool) bool {
+	if m.equal == nil {
+		return false
+	}
+
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	now := m.now()
+
+	it, ok := m.m[key]
+	if !ok || it.expired(now) || now-it.written >= int64(m.coalesce) {
+		return false
+	}
+
+	if (unchanged != nil && !unchanged(it)) || !m.equal(it.value, value) {
+		return false
+	}
+
+	if !it.fixed() {
+		it.touch(now)
+	}
+
+	m.recordStore()
+
+	return true
+}
+
+// StoreWithPolicy will insert a value into the [Map] with a custom time to live and a
+// [RefreshPolicy] that overrides the [Map]'s refreshOnLoad setting for this entry. This allows
+// entries that are kept alive by access (such as sessions) to share a [Map] with entries that have
+// a fixed lifetime (such as one-time tokens). If the key/value pair already exists, its TTL and
+// policy are replaced. StoreWithPolicy is safe for concurrent use.
+func (m *Map[K, V]) StoreWithPolicy(key K, value V, TTL time.Duration, policy RefreshPolicy) {
+	if q := m.stats.quantiles; q != nil {
+		defer q.storeLatency.observeSince(time.Now())
+	}
+
+	m.lock()
+	defer m.unlock()
+
+	it, _ := m.storeItemLocked(key)
+
+	it.value = value
+	it.itemTTL = m.jittered(TTL)
+	it.policy = policy
+	it.defaulted = false
+	it.touch(m.now())
+}
+
+// StoreWithExpireAt will insert a value into the [Map] that expires at the absolute time expireAt.
+// The expiry time is fixed: it is not extended by [Map.Load] (even if the [Map] refreshes on load)
+// or by a subsequent [Map.Store] to the same key, which only replaces the value. If the key/value
+// pair already exists, its TTL is replaced. StoreWithExpireAt is safe for concurrent use.
+func (m *Map[K, V]) StoreWithExpireAt(key K, value V, expireAt time.Time) {
+	if q := m.stats.quantiles; q != nil {
+		defer q.storeLatency.observeSince(time.Now())
+	}
+
+	m.lock()
+	defer m.unlock()
+
+	it, _ := m.storeItemLocked(key)
+
+	now := m.clock.Now()
+	it.value = value
+	it.itemTTL = expireAt.Sub(now)
+	it.policy = RefreshNever
+	it.defaulted = false
+	it.lastAccess.Store(now.UnixNano())
+
+	if it.itemTTL <= 0 {
+		// Already past its deadline, rather than immortal
+		it.dead = true
+	}
+}
+
+// StoreDerived will insert a value into the [Map] under newKey that expires at the same time as the
+// existing entry fromKey, so that data derived from a cached entry never outlives it. The derived
+// entry has the [RefreshNever] policy, so accessing it does not extend its lifetime. If fromKey is
+// not found, nothing is stored and StoreDerived returns false. StoreDerived is safe for concurrent
+// use.
+func (m *Map[K, V]) StoreDerived(newKey K, value V, fromKey K) bool {
+	if q := m.stats.quantiles; q != nil {
+		defer q.storeLatency.observeSince(time.Now())
+	}
+
+	m.lock()
+	defer m.unlock()
+
+	from, ok := m.liveItemLocked(fromKey)
+	if !ok {
+		return false
+	}
+
+	m.storeDerivedLocked(newKey, value, from)
+
+	return true
+}
+
+// storeDerivedLocked stores value under newKey, expiring at the same time as the item from, which
+// may belong to another map. The caller must hold the write lock, and at least the read lock of the
+// map holding from.
+func (m *Map[K, V]) storeDerivedLocked(newKey K, value V, from *mapItem[V]) {
+	now := m.now()
+	remaining := from.remaining(now)
+	if from.immortal() {
+		remaining = 0
+	}
+
+	it, _ := m.storeItemLocked(newKey)
+	it.value = value
+	it.itemTTL = remaining
+	it.policy = RefreshNever
+	it.defaulted = false
+	it.lastAccess.Store(now)
+}
+
+// MergeFunc resolves a conflict when a key being merged into a [Map] already exists. It receives
+// the key, the value currently in the [Map] and the incoming value, and returns the value to keep.
+type MergeFunc[K comparable, V any] func(key K, current V, incoming V) V
+
+// Merge copies all unexpired key/value pairs from other into the [Map], preserving each pair's
+// TTL and remaining time to live.
+//
+// If a key exists in both maps, resolve is called to choose the resulting value. If resolve is nil,
+// the pair with the longer remaining time to live is kept. In either case, the resulting entry
+// keeps the longer of the two remaining lifetimes.
+//
+// Merge is safe for concurrent use. Merging a [Map] into itself has no effect.
+func (m *Map[K, V]) Merge(other *Map[K, V], resolve MergeFunc[K, V]) {
+	if other == nil || other == m {
+		return
+	}
+
+	now := m.now()
+	entries := other.liveClones(now)
+
+	m.lock()
+	defer m.unlock()
+
+	for _, e := range entries {
+		m.mergeItemLocked(e.key, e.it, now, resolve)
+	}
+}
+
+// keyedItem is a copy of an item along with its key.
+type keyedItem[K comparable, V any] struct {
+	key K
+	it  *mapItem[V]
+}
+
+// liveClones returns copies of all the items that have not expired as of now, expressed in Unix
+// nanoseconds, taken under the read lock.
+func (m *Map[K, V]) liveClones(now int64) []keyedItem[K, V] {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	entries := make([]keyedItem[K, V], 0, len(m.m))
+	for key, it := range m.m {
+		if it.expired(now) {
+			continue
+		}
+
+		entries = append(entries, keyedItem[K, V]{key, it.clone()})
+	}
+
+	return entries
+}
+
+// MergeMap copies all key/value pairs from src into the [Map] using the default TTL.
+//
+// If a key already exists in the [Map], resolve is called to choose the resulting value. If
+// resolve is nil, the pair with the longer remaining time to live is kept. In either case, the
+// resulting entry keeps the longer of the two remaining lifetimes.
+//
+// MergeMap is safe for concurrent use.
+func (m *Map[K, V]) MergeMap(src map[K]V, resolve MergeFunc[K, V]) {
+	now := m.now()
+
+	m.lock()
+	defer m.unlock()
+
+	for key, value := range src {
+		incoming := newMapItem[V](now)
+		incoming.value = value
+		incoming.itemTTL = m.jittered(m.defaultTTL)
+		incoming.defaulted = true
+
+		m.mergeItemLocked(key, incoming, now, resolve)
+	}
+}
+
+// mergeItemLocked merges incoming into the map under key. The caller must hold the write lock.
+func (m *Map[K, V]) mergeItemLocked(key K, incoming *mapItem[V], now int64, resolve MergeFunc[K, V]) {
+	m.storedLocked(key)
+
+	current, ok := m.m[key]
+	if !ok {
+		m.m[key] = incoming
+		m.recheckLocked(key)
+		m.addedLocked(key)
+		return
+	}
+
+	if current.expired(now) {
+		m.removeLocked(key, ReasonExpired)
+		m.m[key] = incoming
+		m.recheckLocked(key)
+		m.addedLocked(key)
+		return
+	}
+
+	m.replacedLocked(key, current.value)
+
+	value := incoming.value
+	if resolve != nil {
+		value = resolve(key, current.value, incoming.value)
+	} else if current.remaining(now) >= incoming.remaining(now) {
+		value = current.value
+	}
+
+	if incoming.remaining(now) > current.remaining(now) {
+		current.itemTTL = incoming.itemTTL
+		current.lastAccess.Store(incoming.lastAccess.Load())
+		current.policy = incoming.policy
+		current.defaulted = incoming.defaulted
+		m.recheckLocked(key)
+	}
+
+	current.value = value
+}
+
+func (m *Map[K, V]) loadImpl(key K, update bool) (value V, ok bool) {
+	if q := m.stats.quantiles; q != nil {
+		defer q.loadLatency.observeSince(time.Now())
+	}
+
+	value, ok, err := m.loadContext(m.storeCtx, key, update)
+	if err != nil && m.logger != nil {
+		m.logger.Error("reading through from store", "key", key, "error", err)
+	}
+
+	return value, ok
+}
+
+// loadContext is like loadImpl, returning the error of a failed read from the read-through store.
+func (m *Map[K, V]) loadContext(ctx context.Context, key K, update bool) (value V, ok bool, err error) {
+	value, ok = m.loadItem(key, update)
+	m.recordLoad(key, ok)
+
+	if !ok && m.readThrough != nil {
+		value, ok, err = m.loadThrough(ctx, key)
+	}
+
+	if !ok && m.missValue != nil {
+		value = m.missValue(key)
+	}
+
+	return
+}
+
+func (m *Map[K, V]) loadItem(key K, update bool) (value V, ok bool) {
+	if m.sketch != nil {
+		m.sketch.increment(key)
+	}
+
+	if m.readMostly {
+		return m.loadView(key, update)
+	}
+
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	var it *mapItem[V]
+
+	if it, ok = m.liveItemLocked(key); !ok {
+		return
+	}
+
+	value = it.value
+	it.accesses.Add(1)
+
+	if !update || !it.refreshesOnLoad(m.refreshOnLoad) {
+		return
+	}
+
+	it.touch(m.now())
+
+	return
+}
+
+// Rename atomically moves the value stored under oldKey to newKey, preserving its TTL and last
+// access time. If newKey already exists, its value is replaced. Rename returns false if oldKey was
+// not found, in which case the [Map] is not modified. Rename is safe for concurrent use.
+func (m *Map[K, V]) Rename(oldKey K, newKey K) bool {
+	m.lock()
+	defer m.unlock()
+
+	it, ok := m.liveItemLocked(oldKey)
+	if !ok {
+		return false
+	}
+
+	if oldKey == newKey {
+		return true
+	}
+
+	if old, ok := m.m[newKey]; ok {
+		m.replacedLocked(newKey, old.value)
+		m.totalCost -= old.cost
+		m.unlabelLocked(newKey, old)
+	}
+
+	delete(m.m, oldKey)
+	m.m[newKey] = it
+	m.renamedLocked(oldKey, newKey, it)
+	m.notifyLocked(oldKey, Event[V]{Kind: EventRemoved, Value: it.value, Reason: ReasonDeleted})
+	m.deletedLocked(oldKey)
+	m.storedLocked(newKey)
+
+	if m.expiry != nil {
+		m.expiry.Remove(oldKey)
+		m.recheckLocked(newKey)
+	}
+
+	return true
+}
+
+// liveItemLocked returns the item for key, along with a bool indicating whether it was found. If
+// lazy expiry is enabled, an item whose TTL has elapsed is reported as not found even if it has not
+// been pruned yet. The caller must hold the read or write lock.
+func (m *Map[K, V]) liveItemLocked(key K) (it *mapItem[V], ok bool) {
+	it, ok = m.m[key]
+	if ok && m.lazyExpiry && it.expired(m.now()) {
+		return nil, false
+	}
+
+	return
+}
+
+// jittered returns TTL randomized according to [WithTTLJitter]. A TTL that never expires is
+// returned unchanged.
+func (m *Map[K, V]) jittered(TTL time.Duration) time.Duration {
+	if m.ttlJitter == 0 || TTL <= 0 {
+		return TTL
+	}
+
+	return max(time.Duration(float64(TTL)*(1+m.ttlJitter*(2*rand.Float64()-1))), 1)
+}
+
+// sameJitteredTTL reports whether an item's TTL, itemTTL, may have been produced by jittering TTL.
+func (m *Map[K, V]) sameJitteredTTL(itemTTL time.Duration, TTL time.Duration) bool {
+	if m.ttlJitter == 0 || TTL <= 0 {
+		return itemTTL == TTL
+	}
+
+	return math.Abs(float64(itemTTL-TTL)) <= m.ttlJitter*float64(TTL)
+}
+
+// storeItemLocked returns the item for key so that it can be written, creating it if it doesn't
+// exist, along with a bool indicating whether the item already existed. An item whose TTL has
+// elapsed is removed as expired and replaced with a new one. The caller must hold the write lock.
+func (m *Map[K, V]) storeItemLocked(key K) (it *mapItem[V], existed bool) {
+	m.recordStore()
+
+	if m.sketch != nil {
+		m.sketch.increment(key)
+	}
+
+	it, existed = m.m[key]
+	if existed && it.expired(m.now()) {
+		m.removeLocked(key, ReasonExpired)
+		existed = false
+	}
+
+	m.storedLocked(key)
+
+	if existed {
+		m.replacedLocked(key, it.value)
+		it.written = m.now()
+		m.recheckLocked(key)
+		return
+	}
+
+	it = newMapItem[V](m.now())
+	m.m[key] = it
+	m.recheckLocked(key)
+	m.addedLocked(key)
+
+	return
+}
+
+// renamedLocked moves oldKey to newKey in the prefix and sample indexes, if any, and in the keys of
+// the tags and group of it, the item moved. The caller must hold the write lock.
+func (m *Map[K, V]) renamedLocked(oldKey K, newKey K, it *mapItem[V]) {
+	if m.prefixes != nil {
+		m.prefixes.remove(oldKey)
+		m.prefixes.insert(newKey)
+	}
+
+	if m.samples != nil {
+		m.samples.remove(oldKey)
+		m.samples.insert(newKey)
+	}
+
+	tags, group := m.unlabelLocked(oldKey, it)
+	m.labelLocked(newKey, it, tags, group)
+}
+
+// Expire forces key to expire immediately, without waiting for its TTL to elapse. Unlike
+// [Map.Delete], the entry is removed by the next prune pass and is treated as an expiry rather than
+// a deletion. Until then, storing to the key replaces the expired entry with a new one. Expire
+// returns false if the key was not found. Expire is safe for concurrent use.
+func (m *Map[K, V]) Expire(key K) bool {
+	m.lock()
+	defer m.unlock()
+
+	it, ok := m.m[key]
+	if !ok {
+		return false
+	}
+
+	it.dead = true
+	m.recheckLocked(key)
+
+	return true
+}
+
+// Pin protects key from expiring, regardless of its TTL, until it is unpinned with [Map.Unpin]. A
+// pinned entry can still be deleted, or expired explicitly with [Map.Expire]. Pin returns false if
+// the key was not found. Pin is safe for concurrent use.
+func (m *Map[K, V]) Pin(key K) bool {
+	m.lock()
+	defer m.unlock()
+
+	it, ok := m.liveItemLocked(key)
+	if !ok {
+		return false
+	}
+
+	it.pinned = true
+
+	return true
+}
+
+// Unpin allows key to expire again after it was pinned with [Map.Pin]. The entry's last access time
+// is updated, so it expires after its TTL measured from when it was unpinned. Unpin returns false
+// if the key was not found or was not pinned. Unpin is safe for concurrent use.
+func (m *Map[K, V]) Unpin(key K) bool {
+	m.lock()
+	defer m.unlock()
+
+	it, ok := m.liveItemLocked(key)
+	if !ok || !it.pinned {
+		return false
+	}
+
+	it.pinned = false
+	it.touch(m.now())
+	m.recheckLocked(key)
+
+	return true
+}
+
+// Delete will remove a key and its value from the [Map]. Delete is safe for concurrent use.
+func (m *Map[K, V]) Delete(key K) {
+	m.lock()
+	defer m.unlock()
+
+	if _, ok := m.m[key]; ok {
+		m.removeLocked(key, ReasonDeleted)
+	}
+}
+
+// LoadAndDelete removes key, returning its value, as well as a bool indicating whether it was
+// found. Unlike [Map.Load] followed by [Map.Delete], only one of concurrent calls for a key
+// returns its value. LoadAndDelete is safe for concurrent use.
+func (m *Map[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	m.lock()
+	defer m.unlock()
+
+	if it, ok := m.liveItemLocked(key); ok {
+		value, loaded = it.value, true
+	}
+
+	if _, ok := m.m[key]; ok {
+		m.removeLocked(key, ReasonDeleted)
+	}
+
+	return
+}
+
+// DeleteFunc deletes any key/value pairs from the [Map] for which del returns true. DeleteFunc is
+// safe for concurrent use.
+func (m *Map[K, V]) DeleteFunc(del func(key K, value V) bool) {
+	_ = m.DeleteFuncContext(context.Background(), del)
+}
+
+// DeleteFuncContext is like [Map.DeleteFunc], but checks ctx before visiting each key/value pair
+// and stops early if ctx is done, returning ctx.Err(). Pairs deleted before ctx was done remain
+// deleted. DeleteFuncContext is safe for concurrent use.
+func (m *Map[K, V]) DeleteFuncContext(ctx context.Context, del func(key K, value V) bool) error {
+	return m.iterate(ctx, func(key K, item *mapItem[V], _ int64) bool {
+		if del(key, item.value) {
+			m.removeLocked(key, ReasonDeleted)
+		}
+
+		return true
+	})
+}
+
+// Clear will remove all key/value pairs from the [Map]. Clear is safe for concurrent use.
+func (m *Map[K, V]) Clear() {
+	m.lock()
+	defer m.unlock()
+
+	m.stats.removed(ReasonCleared, len(m.m))
+	m.totalCost = 0
+
+	if m.onRemove != nil {
+		for key, it := range m.m {
+			m.queueRemovalLocked(key, it.value, ReasonCleared)
+		}
+	}
+
+	for key := range m.watchers {
+		if it, ok := m.m[key]; ok {
+			m.notifyLocked(key, Event[V]{Kind: EventRemoved, Value: it.value, Reason: ReasonCleared})
+		}
+	}
+
+	if m.store != nil {
+		for key := range m.m {
+			m.deletedLocked(key)
+		}
+	}
+
+	clear(m.m)
+
+	if m.expiry != nil {
+		m.expiry.Clear()
+	}
+
+	if m.prefixes != nil {
+		m.prefixes.clear()
+	}
+
+	if m.samples != nil {
+		m.samples.clear()
+	}
+
+	m.tags = nil
+	m.groups = nil
+}
+
+// removeLocked removes key from the map, recording the reason for its removal. The key must be
+// present and the caller must hold the write lock.
+func (m *Map[K, V]) removeLocked(key K, reason RemovalReason) {
+	it := m.m[key]
+	delete(m.m, key)
+	m.totalCost -= it.cost
+	m.stats.removed(reason, 1)
+	m.view.Store(nil)
+
+	if m.expiry != nil {
+		m.expiry.Remove(key)
+	}
+
+	if m.prefixes != nil {
+		m.prefixes.remove(key)
+	}
+
+	if m.samples != nil {
+		m.samples.remove(key)
+	}
+
+	m.unlabelLocked(key, it)
+	m.queueRemovalLocked(key, it.value, reason)
+	m.notifyLocked(key, Event[V]{Kind: EventRemoved, Value: it.value, Reason: reason})
+
+	if reason == ReasonDeleted {
+		m.deletedLocked(key)
+	}
+
+	if reason != ReasonExpired && reason != ReasonEvicted {
+		return
+	}
+
+	if q := m.stats.quantiles; q != nil {
+		q.evictionAge.observe(time.Duration(m.now() - it.created))
+	}
+
+	if m.statsScope != nil {
+		m.stats.scope(m.statsScope(key)).evictions.Add(1)
+	}
+}
+
+// Range calls f sequentially for each key and value present in the [Map]. If f returns false, Range
+// stops the iteration.
+//
 // Range is safe for concurrent use and supports modifying the value (assuming it's a reference
 // type like a slice, map, or a pointer) within the range function. However, this requires a write
 // lock on the Map – so you are not able to perform [Map.Delete] or [Map.Store] operations on the
@@ -241,12 +1763,92 @@ func (m *Map[K, V]) Clear() {
 //
 // If you just need to delete items with a certain key or value, use [Map.DeleteFunc] instead.
 func (m *Map[K, V]) Range(f func(key K, value V) bool) {
-	m.mtx.Lock()
-	defer m.mtx.Unlock()
+	_ = m.RangeContext(context.Background(), f)
+}
 
-	for key, item := range m.m {
-		if !f(key, item.value) {
-			break
+// RangeContext is like [Map.Range], but checks ctx before visiting each key/value pair and stops
+// early if ctx is done, returning ctx.Err(). This allows long-running iterations over large maps to
+// be cancelled, releasing the lock on the [Map]. RangeContext returns nil if the iteration
+// completed or f returned false.
+//
+// RangeContext is safe for concurrent use, with the same restrictions as [Map.Range].
+func (m *Map[K, V]) RangeContext(ctx context.Context, f func(key K, value V) bool) error {
+	return m.iterate(ctx, func(key K, item *mapItem[V], now int64) bool {
+		if m.lazyExpiry && item.expired(now) {
+			return true
+		}
+
+		return f(key, item.value)
+	})
+}
+
+// iterate calls visit with the write lock held for each key and item in the map, along with the
+// current time in Unix nanoseconds, until visit returns false. ctx is checked before each call,
+// and its error returned if it is done.
+//
+// If an iteration batch size was set with WithIterationBatchSize, the keys present when the
+// iteration starts are captured under the read lock, and the write lock is only held for a batch of
+// keys at a time. Keys that are removed before their batch is reached are skipped.
+func (m *Map[K, V]) iterate(ctx context.Context, visit func(key K, item *mapItem[V], now int64) bool) error {
+	if m.iterBatch <= 0 {
+		m.lock()
+		defer m.unlock()
+
+		now := m.now()
+
+		for key, item := range m.m {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if !visit(key, item, now) {
+				break
+			}
+		}
+
+		return nil
+	}
+
+	m.mtx.RLock()
+	keys := make([]K, 0, len(m.m))
+	for key := range m.m {
+		keys = append(keys, key)
+	}
+	m.mtx.RUnlock()
+
+	for len(keys) > 0 {
+		n := min(m.iterBatch, len(keys))
+		if stop, err := m.iterateKeys(ctx, keys[:n], visit); stop {
+			return err
 		}
+
+		keys = keys[n:]
 	}
+
+	return nil
+}
+
+// iterateKeys calls visit for each of keys that is still in the map, with the write lock held. It
+// returns true if the iteration should stop, along with ctx.Err() if ctx is done.
+func (m *Map[K, V]) iterateKeys(
+	ctx context.Context,
+	keys []K,
+	visit func(key K, item *mapItem[V], now int64) bool,
+) (stop bool, err error) {
+	m.lock()
+	defer m.unlock()
+
+	now := m.now()
+
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return true, err
+		}
+
+		if item, ok := m.m[key]; ok && !visit(key, item, now) {
+			return true, nil
+		}
+	}
+
+	return false, nil
 }
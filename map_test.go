@@ -1,9 +1,11 @@
 package ttl_test
 
 import (
+	"cmp"
 	"context"
 	"slices"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -11,6 +13,7 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
 )
 
 type MapTestSuite struct {
@@ -754,3 +757,1038 @@ func (s *MapTestSuite) TestConcurrentStoreDeleteFuncMatch() {
 		s.Equal(iteration/2, tm.Length())
 	}
 }
+
+func (s *MapTestSuite) TestMerge() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	other := ttl.NewMap[string, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer other.Close()
+
+	tm.Store("shared", 1)
+	tm.Store("mine", 2)
+	other.StoreWithTTL("shared", 10, 2*s.maxTTL)
+	other.Store("theirs", 20)
+
+	tm.Merge(other, nil)
+
+	s.Equal(3, tm.Length())
+
+	// The incoming pair has the longer remaining TTL, so it wins by default
+	v, ok := tm.LoadPassive("shared")
+	if s.True(ok) {
+		s.Equal(10, v)
+	}
+
+	v, ok = tm.LoadPassive("theirs")
+	if s.True(ok) {
+		s.Equal(20, v)
+	}
+
+	time.Sleep(s.sleepTime)
+
+	v, ok = tm.LoadPassive("shared")
+	if s.True(ok, "merged pair should keep the longer TTL") {
+		s.Equal(10, v)
+	}
+	s.Equal(1, tm.Length())
+}
+
+func (s *MapTestSuite) TestMergeResolve() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	other := ttl.NewMap[string, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer other.Close()
+
+	tm.Store("shared", 1)
+	other.Store("shared", 10)
+
+	tm.Merge(other, func(key string, current int, incoming int) int {
+		return current + incoming
+	})
+
+	v, ok := tm.LoadPassive("shared")
+	if s.True(ok) {
+		s.Equal(11, v)
+	}
+
+	tm.Merge(tm, nil) // no effect
+	s.Equal(1, tm.Length())
+}
+
+func (s *MapTestSuite) TestMergeMap() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	tm.StoreWithTTL("long", 1, time.Minute)
+	tm.Store("short", 2)
+
+	tm.MergeMap(map[string]int{"long": 10, "new": 30}, nil)
+
+	s.Equal(3, tm.Length())
+
+	// The existing pair has the longer remaining TTL, so it wins by default
+	v, ok := tm.LoadPassive("long")
+	if s.True(ok) {
+		s.Equal(1, v)
+	}
+
+	tm.MergeMap(map[string]int{"short": 20}, func(key string, current int, incoming int) int {
+		return incoming
+	})
+
+	v, ok = tm.LoadPassive("short")
+	if s.True(ok) {
+		s.Equal(20, v)
+	}
+}
+
+func (s *MapTestSuite) TestPausePruning() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](
+		s.maxTTL,
+		s.startSize,
+		s.pruneInterval,
+		refreshOnLoad,
+		ttl.WithLazyExpiry(false))
+	defer tm.Close()
+
+	tm.PausePruning()
+	tm.PausePruning()
+
+	tm.Store("a", 1)
+	tm.Store("b", 2)
+
+	time.Sleep(s.sleepTime)
+
+	s.Equal(2, tm.Length(), "nothing should be pruned while paused")
+	_, ok := tm.LoadPassive("a")
+	s.True(ok)
+
+	tm.ResumePruning()
+
+	time.Sleep(2 * s.pruneInterval)
+
+	s.Zero(tm.Length())
+}
+
+func (s *MapTestSuite) TestPrune() {
+	refreshOnLoad := true
+	tm := ttl.New[string, int](
+		ttl.WithTTL(s.maxTTL),
+		ttl.WithRefreshOnLoad(refreshOnLoad),
+		ttl.WithPruneInterval(0))
+	defer tm.Close()
+
+	tm.Store("a", 1)
+	tm.Store("b", 2)
+	tm.StoreWithTTL("c", 3, time.Minute)
+
+	s.Zero(tm.Prune())
+
+	time.Sleep(s.sleepTime)
+
+	s.Equal(3, tm.Length(), "nothing is pruned without background pruning")
+	s.Equal(2, tm.Prune())
+	s.Equal(1, tm.Length())
+	s.Zero(tm.Prune())
+	s.Equal(uint64(2), tm.Stats().Removals.Expired)
+}
+
+func (s *MapTestSuite) TestCloseWait() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, any](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+
+	tm.Store("myString", "a b c")
+
+	tm.CloseWait()
+	tm.CloseWait()
+	tm.Close()
+
+	time.Sleep(s.sleepTime)
+
+	s.Equal(1, tm.Length())
+}
+
+func (s *MapTestSuite) TestCloseWaitWaitsForCallbacks() {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var returned atomic.Bool
+
+	tm := ttl.New[string, int](
+		ttl.WithTTL(time.Millisecond),
+		ttl.WithPruneInterval(time.Millisecond),
+		ttl.WithOnExpire(func(string, int) {
+			close(started)
+			<-release
+			returned.Store(true)
+		}))
+
+	tm.Store("a", 1)
+	<-started
+
+	closed := make(chan struct{})
+	go func() {
+		tm.CloseWait()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+		s.Fail("CloseWait returned while a callback was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-closed
+	s.True(returned.Load())
+}
+
+func (s *MapTestSuite) TestCancelContextAndCloseWait() {
+	refreshOnLoad := true
+	cancellableCtx, cancelFunc := context.WithCancel(context.Background())
+	tm := ttl.NewMapContext[string, any](
+		cancellableCtx,
+		s.maxTTL,
+		s.startSize,
+		s.pruneInterval,
+		refreshOnLoad)
+
+	cancelFunc()
+	tm.CloseWait()
+}
+
+func (s *MapTestSuite) TestStoreWithExpireAt() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](time.Minute, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	tm.StoreWithExpireAt("deadline", 1, time.Now().Add(s.maxTTL))
+
+	time.Sleep(s.pruneInterval)
+	tm.Store("deadline", 2) // replaces the value, but not the deadline
+
+	v, ok := tm.LoadPassive("deadline")
+	if s.True(ok) {
+		s.Equal(2, v)
+	}
+
+	doneCh := make(chan struct{})
+
+	go func() {
+		for start := time.Now(); time.Since(start) < s.sleepTime; {
+			time.Sleep(50 * time.Millisecond)
+
+			tm.Load("deadline") // does not extend the deadline
+		}
+		close(doneCh)
+	}()
+
+	<-doneCh
+
+	s.Zero(tm.Length())
+}
+
+func (s *MapTestSuite) TestTTL() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	tm.Store("default", 1)
+	tm.StoreWithTTL("custom", 2, time.Minute)
+
+	remaining, ok := tm.TTL("default")
+	if s.True(ok) {
+		s.LessOrEqual(remaining, s.maxTTL)
+		s.Greater(remaining, s.maxTTL-s.pruneInterval)
+	}
+
+	remaining, ok = tm.TTL("custom")
+	if s.True(ok) {
+		s.LessOrEqual(remaining, time.Minute)
+		s.Greater(remaining, time.Minute-s.pruneInterval)
+	}
+
+	time.Sleep(s.pruneInterval)
+
+	// TTL must not refresh the last access time
+	remaining, ok = tm.TTL("default")
+	if s.True(ok) {
+		s.LessOrEqual(remaining, s.maxTTL-s.pruneInterval)
+	}
+
+	_, ok = tm.TTL("missing")
+	s.False(ok)
+}
+
+func (s *MapTestSuite) TestRename() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	tm.StoreWithTTL("old", 1, time.Minute)
+	tm.Store("taken", 2)
+
+	s.True(tm.Rename("old", "new"))
+
+	_, ok := tm.LoadPassive("old")
+	s.False(ok)
+
+	v, ok := tm.LoadPassive("new")
+	if s.True(ok) {
+		s.Equal(1, v)
+	}
+
+	remaining, ok := tm.TTL("new")
+	if s.True(ok) {
+		s.Greater(remaining, s.maxTTL, "renamed pair should keep its TTL")
+	}
+
+	s.True(tm.Rename("new", "taken"))
+	s.Equal(1, tm.Length())
+
+	v, ok = tm.LoadPassive("taken")
+	if s.True(ok) {
+		s.Equal(1, v)
+	}
+
+	s.True(tm.Rename("taken", "taken"))
+	s.False(tm.Rename("missing", "other"))
+	s.Equal(1, tm.Length())
+}
+
+func (s *MapTestSuite) TestExpirationTime() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	deadline := time.Now().Add(time.Hour)
+	tm.StoreWithExpireAt("deadline", 1, deadline)
+
+	before := time.Now()
+	tm.Store("default", 2)
+
+	expireAt, ok := tm.ExpirationTime("deadline")
+	if s.True(ok) {
+		s.WithinDuration(deadline, expireAt, time.Millisecond)
+	}
+
+	expireAt, ok = tm.ExpirationTime("default")
+	if s.True(ok) {
+		s.WithinRange(expireAt, before.Add(s.maxTTL), time.Now().Add(s.maxTTL))
+	}
+
+	_, ok = tm.ExpirationTime("missing")
+	s.False(ok)
+}
+
+func (s *MapTestSuite) TestTouch() {
+	refreshOnLoad := false
+	tm := ttl.NewMap[string, any](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	tm.Store("touched", 1)
+	tm.Store("untouched", 2)
+
+	doneCh := make(chan struct{})
+
+	go func() {
+		for start := time.Now(); time.Since(start) < s.sleepTime; {
+			time.Sleep(50 * time.Millisecond)
+			tm.Touch("touched")
+		}
+		close(doneCh)
+	}()
+
+	<-doneCh
+
+	s.Equal(1, tm.Length())
+	_, ok := tm.LoadPassive("touched")
+	s.True(ok)
+
+	s.False(tm.Touch("untouched"))
+}
+
+func (s *MapTestSuite) TestExtend() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	tm.Store("lease", 1)
+	tm.Store("other", 2)
+
+	before, _ := tm.ExpirationTime("lease")
+	s.True(tm.Extend("lease", s.maxTTL))
+
+	after, ok := tm.ExpirationTime("lease")
+	if s.True(ok) {
+		s.Equal(s.maxTTL, after.Sub(before))
+	}
+
+	time.Sleep(s.sleepTime)
+
+	s.Equal(1, tm.Length())
+	v, ok := tm.LoadPassive("lease")
+	if s.True(ok) {
+		s.Equal(1, v)
+	}
+
+	s.False(tm.Extend("other", time.Minute))
+}
+
+func (s *MapTestSuite) TestKeysExpiring() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](time.Minute, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	now := time.Now()
+	tm.StoreWithExpireAt("soon", 1, now.Add(time.Second))
+	tm.StoreWithExpireAt("sooner", 2, now.Add(500*time.Millisecond))
+	tm.StoreWithExpireAt("later", 3, now.Add(time.Hour))
+
+	before := tm.KeysExpiringBefore(now.Add(10 * time.Second))
+	slices.Sort(before)
+	s.Equal([]string{"soon", "sooner"}, before)
+
+	s.Equal([]string{"later"}, tm.KeysExpiringAfter(now.Add(10*time.Second)))
+	s.Empty(tm.KeysExpiringBefore(now))
+}
+
+func (s *MapTestSuite) TestNextExpiration() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	tm := ttl.NewShardedMap[string, int](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithShards(4),
+		ttl.WithPruneInterval(0))
+	defer tm.Close()
+
+	_, ok := tm.NextExpiration()
+	s.False(ok)
+
+	tm.StoreWithTTL("immortal", 0, 0)
+	tm.StoreWithTTL("pinned", 1, time.Second)
+	tm.Pin("pinned")
+	_, ok = tm.NextExpiration()
+	s.False(ok)
+
+	tm.Store("later", 2)
+	tm.StoreWithTTL("soon", 3, 10*time.Second)
+	tm.StoreWithTTL("expired", 4, time.Millisecond)
+	clock.Advance(time.Millisecond)
+
+	next, ok := tm.NextExpiration()
+	s.True(ok)
+	s.Equal(clock.Now().Add(10*time.Second-time.Millisecond), next)
+
+	tm.Delete("soon")
+	next, _ = tm.NextExpiration()
+	s.Equal(clock.Now().Add(time.Minute-time.Millisecond), next)
+}
+
+func (s *MapTestSuite) TestStoreWithPolicy() {
+	refreshOnLoad := false
+	tm := ttl.NewMap[string, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	tm.StoreWithPolicy("session", 1, s.maxTTL, ttl.RefreshOnLoad)
+	tm.StoreWithPolicy("nonce", 2, s.maxTTL, ttl.RefreshNever)
+	tm.StoreWithPolicy("default", 3, s.maxTTL, ttl.RefreshDefault)
+
+	doneCh := make(chan struct{})
+
+	go func() {
+		for start := time.Now(); time.Since(start) < s.sleepTime; {
+			time.Sleep(50 * time.Millisecond)
+
+			tm.Load("session")
+			tm.Load("nonce")
+			tm.Touch("nonce")
+			tm.Load("default")
+		}
+		close(doneCh)
+	}()
+
+	<-doneCh
+
+	s.Equal(1, tm.Length())
+	_, ok := tm.LoadPassive("session")
+	s.True(ok)
+}
+
+func (s *MapTestSuite) TestSetTTL() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](time.Minute, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	tm.Store("hot", 1)
+	tm.Store("other", 2)
+
+	s.True(tm.SetTTL("hot", s.maxTTL))
+
+	remaining, ok := tm.TTL("hot")
+	if s.True(ok) {
+		s.LessOrEqual(remaining, s.maxTTL)
+	}
+
+	time.Sleep(s.sleepTime)
+
+	s.Equal(1, tm.Length())
+	_, ok = tm.LoadPassive("other")
+	s.True(ok)
+
+	s.False(tm.SetTTL("hot", time.Minute))
+}
+
+func (s *MapTestSuite) TestSetDefaultTTL() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](time.Minute, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	tm.Store("existing", 1)
+	tm.Store("unchanged", 2)
+	tm.StoreWithTTL("explicit", 3, time.Minute)
+
+	s.Equal(time.Minute, tm.DefaultTTL())
+	tm.SetDefaultTTL(s.maxTTL, false)
+	s.Equal(s.maxTTL, tm.DefaultTTL())
+	tm.Store("new", 4)
+
+	remaining, _ := tm.TTL("existing")
+	s.Greater(remaining, s.maxTTL, "existing entries keep their TTL")
+
+	remaining, _ = tm.TTL("new")
+	s.LessOrEqual(remaining, s.maxTTL)
+
+	tm.SetDefaultTTL(s.maxTTL, true)
+	s.True(tm.SetTTL("unchanged", time.Minute))
+	tm.SetDefaultTTL(s.maxTTL, true)
+
+	time.Sleep(s.sleepTime)
+
+	_, ok := tm.LoadPassive("explicit")
+	s.True(ok, "entries with an explicit TTL are not updated")
+	_, ok = tm.LoadPassive("unchanged")
+	s.True(ok, "entries whose TTL was changed are not updated")
+	s.Equal(2, tm.Length())
+}
+
+func (s *MapTestSuite) TestExpire() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](time.Minute, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	tm.Store("expired", 1)
+	tm.Store("revived", 2)
+	tm.Store("kept", 3)
+
+	s.True(tm.Expire("expired"))
+	s.True(tm.Expire("revived"))
+	s.False(tm.Expire("missing"))
+
+	tm.Touch("expired") // does not revive an expired entry
+	tm.Store("revived", 20)
+
+	time.Sleep(2 * s.pruneInterval)
+
+	s.Equal(2, tm.Length())
+	v, ok := tm.LoadPassive("revived")
+	if s.True(ok) {
+		s.Equal(20, v)
+	}
+
+	stats := tm.Stats()
+	s.Equal(uint64(2), stats.Removals.Expired)
+	s.Zero(stats.Removals.Deleted)
+	s.Zero(stats.Removals.Replaced)
+}
+
+func (s *MapTestSuite) TestRangeContext() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[int, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	for i := 0; i < 10; i++ {
+		tm.Store(i, i)
+	}
+
+	visited := 0
+	err := tm.RangeContext(context.Background(), func(key int, val int) bool {
+		visited++
+		return true
+	})
+	s.NoError(err)
+	s.Equal(10, visited)
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+
+	visited = 0
+	err = tm.RangeContext(ctx, func(key int, val int) bool {
+		visited++
+		if visited == 3 {
+			cancelFunc()
+		}
+		return true
+	})
+	s.ErrorIs(err, context.Canceled)
+	s.Equal(3, visited)
+}
+
+func (s *MapTestSuite) TestDeleteFuncContext() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[int, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	for i := 0; i < 10; i++ {
+		tm.Store(i, i)
+	}
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+
+	deleted := 0
+	err := tm.DeleteFuncContext(ctx, func(key int, val int) bool {
+		deleted++
+		if deleted == 4 {
+			cancelFunc()
+		}
+		return true
+	})
+	s.ErrorIs(err, context.Canceled)
+	s.Equal(6, tm.Length())
+
+	s.NoError(tm.DeleteFuncContext(context.Background(), func(key int, val int) bool {
+		return true
+	}))
+	s.Zero(tm.Length())
+}
+
+func (s *MapTestSuite) TestLoadStale() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](s.pruneInterval, s.startSize, time.Minute, refreshOnLoad)
+	defer tm.Close()
+
+	tm.Store("key", 1)
+
+	v, stale, ok := tm.LoadStale("key")
+	s.True(ok)
+	s.False(stale)
+	s.Equal(1, v)
+
+	time.Sleep(2 * s.pruneInterval)
+
+	v, stale, ok = tm.LoadStale("key")
+	s.True(ok)
+	s.True(stale)
+	s.Equal(1, v)
+
+	// A stale read must not revive the entry
+	_, ok = tm.Load("key")
+	s.False(ok)
+
+	_, _, ok = tm.LoadStale("missing")
+	s.False(ok)
+}
+
+func (s *MapTestSuite) TestStoreDerived() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, string](time.Minute, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	tm.StoreWithTTL("source", "data", s.maxTTL)
+
+	s.True(tm.StoreDerived("view", "DATA", "source"))
+	s.False(tm.StoreDerived("orphan", "x", "missing"))
+
+	sourceExpiry, _ := tm.ExpirationTime("source")
+	viewExpiry, ok := tm.ExpirationTime("view")
+	if s.True(ok) {
+		s.WithinDuration(sourceExpiry, viewExpiry, time.Millisecond)
+	}
+
+	doneCh := make(chan struct{})
+
+	go func() {
+		for start := time.Now(); time.Since(start) < s.sleepTime; {
+			time.Sleep(50 * time.Millisecond)
+			tm.Load("view") // does not extend the derived entry
+		}
+		close(doneCh)
+	}()
+
+	<-doneCh
+
+	s.Zero(tm.Length())
+}
+
+func (s *MapTestSuite) TestAlsoCancelOn() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, any](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+
+	appCtx, appCancel := context.WithCancel(context.Background())
+	defer appCancel()
+	tenantCtx, tenantCancel := context.WithCancel(context.Background())
+
+	tm.AlsoCancelOn(appCtx)
+	tm.AlsoCancelOn(tenantCtx)
+
+	tm.Store("myString", "a b c")
+
+	tenantCancel()
+
+	time.Sleep(s.sleepTime)
+
+	s.Equal(1, tm.Length(), "pruning should have stopped when tenantCtx was cancelled")
+
+	tm.AlsoCancelOn(context.Background()) // no effect once closed
+	tm.CloseWait()
+}
+
+func (s *MapTestSuite) TestZeroPruneInterval() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, any](s.pruneInterval, s.startSize, 0, refreshOnLoad)
+	defer tm.Close()
+
+	tm.Store("myString", "a b c")
+
+	time.Sleep(2 * s.pruneInterval)
+
+	_, ok := tm.Load("myString")
+	s.False(ok)
+	s.Equal(1, tm.Length(), "expired items are not pruned")
+
+	tm.Store("myString", "d e f")
+	s.Equal(1, tm.Length())
+
+	tm.CloseWait()
+}
+
+func (s *MapTestSuite) TestNegativePruneInterval() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, any](s.maxTTL, s.startSize, -time.Second, refreshOnLoad)
+	tm.CloseWait()
+}
+
+func (s *MapTestSuite) TestZeroTTLNeverExpires() {
+	refreshOnLoad := false
+	tm := ttl.NewMap[string, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	tm.StoreWithTTL("permanent", 1, 0)
+	tm.StoreWithTTL("negative", 2, -time.Second)
+	tm.Store("expiring", 3)
+
+	remaining, ok := tm.TTL("permanent")
+	if s.True(ok) {
+		s.Equal(ttl.NoExpiry, remaining)
+	}
+
+	expireAt, ok := tm.ExpirationTime("permanent")
+	if s.True(ok) {
+		s.True(expireAt.IsZero())
+	}
+
+	s.True(tm.Extend("permanent", time.Second))
+	s.True(tm.StoreDerived("derived", 4, "permanent"))
+
+	time.Sleep(s.sleepTime)
+
+	s.Equal(3, tm.Length())
+	_, ok = tm.Load("expiring")
+	s.False(ok)
+
+	remaining, ok = tm.TTL("derived")
+	if s.True(ok) {
+		s.Equal(ttl.NoExpiry, remaining)
+	}
+
+	tm.StoreWithExpireAt("past", 5, time.Now().Add(-time.Second))
+	_, ok = tm.Load("past")
+	s.False(ok, "a deadline in the past must not make an entry permanent")
+}
+
+func (s *MapTestSuite) TestZeroDefaultTTL() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](0, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	tm.Store("permanent", 1)
+
+	time.Sleep(2 * s.pruneInterval)
+
+	s.Equal(1, tm.Length())
+}
+
+func (s *MapTestSuite) TestPin() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	tm.Store("keepalive", 1)
+	tm.Store("other", 2)
+
+	s.True(tm.Pin("keepalive"))
+	s.False(tm.Pin("missing"))
+
+	time.Sleep(s.sleepTime)
+
+	s.Equal(1, tm.Length())
+	_, ok := tm.LoadPassive("keepalive")
+	s.True(ok)
+
+	s.True(tm.Unpin("keepalive"))
+	s.False(tm.Unpin("keepalive"))
+
+	// Unpinning restarts the TTL
+	_, ok = tm.LoadPassive("keepalive")
+	s.True(ok)
+
+	time.Sleep(s.sleepTime)
+
+	s.Zero(tm.Length())
+}
+
+func (s *MapTestSuite) TestLengthDuringRange() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[int, int](time.Minute, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	for i := 0; i < 10; i++ {
+		tm.Store(i, i)
+	}
+
+	// Length does not need the lock held by Range, so it can be called from within it
+	tm.Range(func(int, int) bool {
+		s.Equal(10, tm.Length())
+		return false
+	})
+
+	tm.Delete(0)
+	tm.Rename(1, 100)
+	tm.Rename(2, 100)
+	s.Equal(8, tm.Length())
+}
+
+func (s *MapTestSuite) TestWatch() {
+	tm := ttl.New[string, int](
+		ttl.WithTTL(s.maxTTL),
+		ttl.WithPruneInterval(s.pruneInterval))
+	defer tm.Close()
+
+	events, cancel := tm.Watch("watched")
+
+	tm.Store("watched", 1)
+	tm.Store("other", 2)
+	tm.StoreWithTTL("watched", 3, time.Minute)
+	tm.Delete("watched")
+	tm.Store("watched", 4)
+
+	time.Sleep(s.sleepTime)
+
+	s.Equal(ttl.Event[int]{Kind: ttl.EventStored, Value: 1}, <-events)
+	s.Equal(ttl.Event[int]{Kind: ttl.EventStored, Value: 3}, <-events)
+	s.Equal(ttl.Event[int]{Kind: ttl.EventRemoved, Value: 3, Reason: ttl.ReasonDeleted}, <-events)
+	s.Equal(ttl.Event[int]{Kind: ttl.EventStored, Value: 4}, <-events)
+	s.Equal(ttl.Event[int]{Kind: ttl.EventRemoved, Value: 4, Reason: ttl.ReasonExpired}, <-events)
+
+	cancel()
+	cancel()
+
+	_, ok := <-events
+	s.False(ok)
+
+	// Events are no longer sent once cancelled
+	tm.Store("watched", 5)
+}
+
+func (s *MapTestSuite) TestMetadata() {
+	tm := ttl.New[string, int](
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0))
+	defer tm.Close()
+
+	before := time.Now()
+	tm.Store("a", 1)
+	tm.StoreWithTTL("immortal", 2, 0)
+
+	time.Sleep(time.Millisecond)
+	tm.Store("a", 3)
+	tm.Load("a")
+	tm.LoadPassive("a")
+	tm.Pin("a")
+
+	info, ok := tm.Metadata("a")
+	if s.True(ok) {
+		s.False(info.Created.Before(before))
+		s.True(info.Written.After(info.Created))
+		s.False(info.LastAccess.Before(info.Written))
+		s.Equal(uint64(2), info.Accesses)
+		s.Equal(time.Minute, info.TTL)
+		s.Equal(info.LastAccess.Add(time.Minute), info.ExpiresAt)
+		s.Equal(ttl.RefreshDefault, info.Policy)
+		s.True(info.Pinned)
+	}
+
+	// Metadata does not count as an access
+	info, _ = tm.Metadata("a")
+	s.Equal(uint64(2), info.Accesses)
+
+	info, ok = tm.Metadata("immortal")
+	if s.True(ok) {
+		s.True(info.ExpiresAt.IsZero())
+	}
+
+	_, ok = tm.Metadata("missing")
+	s.False(ok)
+}
+
+func (s *MapTestSuite) TestEntries() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	tm := ttl.New[string, int](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0))
+	defer tm.Close()
+
+	created := clock.Now()
+	tm.Store("a", 1)
+	tm.StoreWithTTL("immortal", 2, 0)
+	tm.StoreWithTTL("expired", 3, time.Second)
+
+	clock.Advance(10 * time.Second)
+	tm.Store("a", 4)
+	tm.LoadPassive("immortal")
+
+	entries := tm.Entries()
+	slices.SortFunc(entries, func(a, b ttl.Entry[string, int]) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+
+	s.Equal([]ttl.Entry[string, int]{
+		{Key: "a", Value: 4, Remaining: time.Minute, LastAccess: clock.Now(), Created: created},
+		{Key: "immortal", Value: 2, Remaining: ttl.NoExpiry, LastAccess: created, Created: created},
+	}, entries)
+
+	tm.Clear()
+	s.Empty(tm.Entries())
+}
+
+func (s *MapTestSuite) TestOldestNewest() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	tm := ttl.New[string, int](ttl.WithClock(clock), ttl.WithPruneInterval(0))
+	defer tm.Close()
+
+	_, ok := tm.Oldest()
+	s.False(ok)
+	_, ok = tm.Newest()
+	s.False(ok)
+
+	tm.Store("a", 1)
+	clock.Advance(time.Second)
+	tm.Store("b", 2)
+	clock.Advance(time.Second)
+	tm.StoreWithTTL("c", 3, time.Millisecond)
+	clock.Advance(time.Second)
+
+	oldest, ok := tm.Oldest()
+	s.True(ok)
+	s.Equal("a", oldest.Key)
+	s.Equal(1, oldest.Value)
+
+	newest, ok := tm.Newest()
+	s.True(ok)
+	s.Equal("b", newest.Key)
+
+	// Loading refreshes the last access time
+	tm.Load("a")
+	oldest, _ = tm.Oldest()
+	s.Equal("b", oldest.Key)
+	newest, _ = tm.Newest()
+	s.Equal("a", newest.Key)
+
+	sharded := ttl.NewShardedMap[int, int](
+		ttl.WithClock(clock),
+		ttl.WithShards(4),
+		ttl.WithPruneInterval(0))
+	defer sharded.Close()
+
+	for i := 0; i < 10; i++ {
+		sharded.Store(i, i)
+		clock.Advance(time.Second)
+	}
+
+	oldest2, _ := sharded.Oldest()
+	s.Equal(0, oldest2.Key)
+	newest2, _ := sharded.Newest()
+	s.Equal(9, newest2.Key)
+}
+
+func (s *MapTestSuite) TestRangeByExpiry() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	tm := ttl.NewShardedMap[string, int](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithShards(4),
+		ttl.WithPruneInterval(0))
+	defer tm.Close()
+
+	tm.StoreWithTTL("immortal", 0, 0)
+	tm.StoreWithTTL("c", 3, 3*time.Second)
+	tm.StoreWithTTL("a", 1, time.Second)
+	tm.StoreWithTTL("b", 2, 2*time.Second)
+	tm.StoreWithTTL("expired", 4, time.Millisecond)
+
+	clock.Advance(time.Millisecond)
+
+	var keys []string
+	var remaining []time.Duration
+	tm.RangeByExpiry(func(key string, value int, left time.Duration) bool {
+		keys = append(keys, key)
+		remaining = append(remaining, left)
+
+		// Entries can be refreshed while iterating
+		tm.Store(key, value)
+		return true
+	})
+
+	s.Equal([]string{"a", "b", "c", "immortal"}, keys)
+	s.Equal([]time.Duration{
+		time.Second - time.Millisecond,
+		2*time.Second - time.Millisecond,
+		3*time.Second - time.Millisecond,
+		ttl.NoExpiry,
+	}, remaining)
+
+	keys = nil
+	tm.RangeByExpiry(func(key string, _ int, _ time.Duration) bool {
+		keys = append(keys, key)
+		return len(keys) < 2
+	})
+	s.Equal([]string{"a", "b"}, keys)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.ErrorIs(tm.RangeByExpiryContext(ctx, func(string, int, time.Duration) bool {
+		s.Fail("called after the context was done")
+		return true
+	}), context.Canceled)
+}
+
+func (s *MapTestSuite) TestSystemClockMonotonic() {
+	now := ttl.SystemClock.Now()
+
+	// The time carries a monotonic clock reading, which Round(0) strips, so expiry is measured
+	// without being affected by steps of the wall clock
+	s.Contains(now.String(), "m=")
+	s.NotContains(now.Round(0).String(), "m=")
+
+	later := ttl.SystemClock.Now()
+	s.False(later.Before(now))
+}
@@ -0,0 +1,36 @@
+package ttl
+
+import (
+	"context"
+	"time"
+)
+
+// Memoize returns a function that calls fn and caches its result for each argument for TTL, in a
+// [Map] configured by opts. Concurrent calls with an argument whose result is not cached share a
+// single call of fn. Errors returned by fn are returned to every caller waiting for that call, but
+// are not cached, so the next call with the same argument calls fn again.
+//
+// Unless opts include [WithRefreshOnLoad], a result is cached for TTL from the time fn returned
+// it, however often it is used. [WithReadThrough] is used to call fn, so it must not be among
+// opts. The [Map] prunes expired results in the background until the context set using
+// [WithContext] is done, so a function memoized for less than the life of the process should be
+// given a context that is cancelled when it is no longer needed.
+func Memoize[K comparable, V any](
+	fn func(key K) (V, error),
+	TTL time.Duration,
+	opts ...Option,
+) func(key K) (V, error) {
+	loader := LoaderFunc[K, V](func(_ context.Context, key K) (V, time.Duration, bool, error) {
+		value, err := fn(key)
+		return value, TTL, err == nil, err
+	})
+
+	m := New[K, V](append(append([]Option{WithRefreshOnLoad(false)}, opts...),
+		WithTTL(TTL),
+		WithReadThrough[K, V](loader))...)
+
+	return func(key K) (V, error) {
+		value, _, err := m.LoadContext(context.Background(), key)
+		return value, err
+	}
+}
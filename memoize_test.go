@@ -0,0 +1,48 @@
+package ttl_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestMemoize() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	failed := errors.New("failed")
+	calls := make(map[int]int)
+
+	itoa := ttl.Memoize(func(i int) (string, error) {
+		calls[i]++
+		if i < 0 {
+			return "", failed
+		}
+		return strconv.Itoa(i), nil
+	}, time.Minute, ttl.WithClock(clock), ttl.WithContext(ctx))
+
+	for i := 0; i < 3; i++ {
+		v, err := itoa(1)
+		s.NoError(err)
+		s.Equal("1", v)
+
+		clock.Advance(20 * time.Second)
+	}
+	s.Equal(1, calls[1])
+
+	// Results are cached for the TTL from when they were computed, even if used
+	_, _ = itoa(1)
+	s.Equal(2, calls[1])
+
+	// Errors are not cached
+	for i := 0; i < 2; i++ {
+		_, err := itoa(-1)
+		s.ErrorIs(err, failed)
+	}
+	s.Equal(2, calls[-1])
+}
@@ -0,0 +1,210 @@
+package ttl
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+)
+
+// EntryInfo describes the state of a single entry of a [Map], as returned by [Map.Metadata].
+type EntryInfo struct {
+	// Created is the time the key was first stored. Replacing the value of a key does not change
+	// it.
+	Created time.Time
+
+	// Written is the time the value was last stored.
+	Written time.Time
+
+	// LastAccess is the time from which the entry's TTL is counted: the time it was last stored or
+	// refreshed by a load.
+	LastAccess time.Time
+
+	// Accesses is the number of loads that have found the key since it was created, including
+	// loads that did not refresh it.
+	Accesses uint64
+
+	// TTL is the entry's time to live. A zero or negative TTL means the entry never expires.
+	TTL time.Duration
+
+	// ExpiresAt is the time at which the entry will expire unless it is accessed again, or the
+	// zero time.Time if it never expires.
+	ExpiresAt time.Time
+
+	// Policy is the entry's [RefreshPolicy].
+	Policy RefreshPolicy
+
+	// Pinned reports whether the entry is pinned with [Map.Pin].
+	Pinned bool
+}
+
+// Metadata returns information about the entry for key, such as when it was created and how often
+// it has been loaded, as well as a bool indicating whether the key was found. This is intended for
+// debugging why a key is or isn't in the [Map]. Metadata does not update the key's last access time
+// or access count, and is safe for concurrent use.
+func (m *Map[K, V]) Metadata(key K) (info EntryInfo, ok bool) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	it, ok := m.liveItemLocked(key)
+	if !ok {
+		return
+	}
+
+	info = EntryInfo{
+		Created:    time.Unix(0, it.created),
+		Written:    time.Unix(0, it.written),
+		LastAccess: time.Unix(0, it.lastAccess.Load()),
+		Accesses:   it.accesses.Load(),
+		TTL:        it.itemTTL,
+		Policy:     it.policy,
+		Pinned:     it.pinned,
+	}
+
+	if !it.immortal() {
+		info.ExpiresAt = time.Unix(0, it.expiresAt())
+	}
+
+	return info, true
+}
+
+// Entries returns the unexpired entries of the [Map] along with the state of their TTLs, in no
+// particular order, for example for an administrative dashboard or a debugging dump. Values are
+// copied as-is, so reference types will share memory with the [Map]. Entries does not update the
+// last access time of any entry and is safe for concurrent use.
+func (m *Map[K, V]) Entries() []Entry[K, V] {
+	now := m.now()
+
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	entries := make([]Entry[K, V], 0, len(m.m))
+	for key, it := range m.m {
+		if it.expired(now) {
+			continue
+		}
+
+		entries = append(entries, newEntry(key, it, now))
+	}
+
+	return entries
+}
+
+// Oldest returns the unexpired entry that was accessed least recently, that is, whose
+// [Entry].LastAccess is the earliest, as well as a bool indicating whether the [Map] has any
+// unexpired entries. Oldest visits every entry of the [Map]. It does not update the last access
+// time of any entry and is safe for concurrent use.
+func (m *Map[K, V]) Oldest() (entry Entry[K, V], ok bool) {
+	return m.extreme(func(a, b int64) bool { return a < b })
+}
+
+// Newest returns the unexpired entry that was accessed most recently, that is, whose
+// [Entry].LastAccess is the latest, as well as a bool indicating whether the [Map] has any
+// unexpired entries. Newest visits every entry of the [Map]. It does not update the last access
+// time of any entry and is safe for concurrent use.
+func (m *Map[K, V]) Newest() (entry Entry[K, V], ok bool) {
+	return m.extreme(func(a, b int64) bool { return a > b })
+}
+
+// extreme returns the unexpired entry whose last access time comes first according to before.
+func (m *Map[K, V]) extreme(before func(a, b int64) bool) (entry Entry[K, V], ok bool) {
+	now := m.now()
+
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	key, it, ok := m.extremeLocked(before, now)
+	if !ok {
+		return
+	}
+
+	return newEntry(key, it, now), true
+}
+
+// removeOldest removes the unexpired entry that was accessed least recently, returning its key
+// and value, as well as a bool indicating whether the map had any unexpired entries.
+func (m *Map[K, V]) removeOldest() (key K, value V, ok bool) {
+	m.lock()
+	defer m.unlock()
+
+	key, it, ok := m.extremeLocked(func(a, b int64) bool { return a < b }, m.now())
+	if !ok {
+		return
+	}
+
+	m.removeLocked(key, ReasonDeleted)
+
+	return key, it.value, true
+}
+
+// extremeLocked returns the key and item of the unexpired entry whose last access time comes
+// first according to before. The caller must hold the read or write lock.
+func (m *Map[K, V]) extremeLocked(before func(a, b int64) bool, now int64) (found K, foundItem *mapItem[V], ok bool) {
+	var access int64
+
+	for key, it := range m.m {
+		if it.expired(now) {
+			continue
+		}
+
+		if a := it.lastAccess.Load(); !ok || before(a, access) {
+			found, foundItem, access, ok = key, it, a, true
+		}
+	}
+
+	return
+}
+
+// RangeByExpiry calls f sequentially for each unexpired key and value, with its remaining time to
+// live, from the entry that expires soonest to the one that expires latest; entries that never
+// expire come last, with a remaining time of [NoExpiry]. This suits refreshing entries before they
+// expire. If f returns false, RangeByExpiry stops the iteration. Like [Map.Entries], RangeByExpiry
+// iterates over a copy of the entries taken when it is called, so f may modify the [Map], and it
+// does not update the last access time of any key. RangeByExpiry is safe for concurrent use.
+func (m *Map[K, V]) RangeByExpiry(f func(key K, value V, remaining time.Duration) bool) {
+	_ = m.RangeByExpiryContext(context.Background(), f)
+}
+
+// RangeByExpiryContext is like [Map.RangeByExpiry], but stops and returns ctx.Err() if ctx is done
+// before the iteration completes.
+func (m *Map[K, V]) RangeByExpiryContext(
+	ctx context.Context,
+	f func(key K, value V, remaining time.Duration) bool,
+) error {
+	return rangeByExpiry(ctx, m.Entries(), f)
+}
+
+// rangeByExpiry sorts entries by their remaining time to live and calls f with each of them, as
+// RangeByExpiryContext does.
+func rangeByExpiry[K comparable, V any](
+	ctx context.Context,
+	entries []Entry[K, V],
+	f func(key K, value V, remaining time.Duration) bool,
+) error {
+	slices.SortFunc(entries, func(a, b Entry[K, V]) int {
+		return cmp.Compare(a.Remaining, b.Remaining)
+	})
+
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !f(e.Key, e.Value, e.Remaining) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// newEntry returns the Entry of key, whose item is it, as of now, expressed in Unix nanoseconds.
+func newEntry[K comparable, V any](key K, it *mapItem[V], now int64) Entry[K, V] {
+	return Entry[K, V]{
+		Key:        key,
+		Value:      it.value,
+		Remaining:  max(it.remaining(now), 0),
+		LastAccess: time.Unix(0, it.lastAccess.Load()),
+		Created:    time.Unix(0, it.created),
+	}
+}
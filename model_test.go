@@ -0,0 +1,52 @@
+package ttl_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func newModelTarget() *ttl.Map[int, int] {
+	return ttl.NewMap[int, int](time.Hour, 0, time.Minute, true)
+}
+
+func (s *MapTestSuite) TestModelSequential() {
+	for seed := int64(0); seed < 20; seed++ {
+		tm := newModelTarget()
+		ttltest.Check(s.T(), tm, ttltest.RandomOps(seed, 500, 8))
+		tm.Close()
+	}
+}
+
+func (s *MapTestSuite) TestModelConcurrent() {
+	tm := newModelTarget()
+	defer tm.Close()
+
+	ttltest.CheckConcurrent(s.T(), tm, 8, 2000, 1)
+}
+
+func (s *MapTestSuite) TestModelReadMostly() {
+	tm := ttl.New[int, int](
+		ttl.WithTTL(time.Hour),
+		ttl.WithPruneInterval(time.Minute),
+		ttl.WithReadMostly(true))
+	defer tm.Close()
+
+	ttltest.Check(s.T(), tm, ttltest.RandomOps(0, 500, 8))
+	tm.Clear()
+	ttltest.CheckConcurrent(s.T(), tm, 8, 2000, 1)
+}
+
+func FuzzModel(f *testing.F) {
+	f.Add([]byte{2, 1, 0, 7, 0, 1, 0, 0, 4, 1, 2, 0, 0, 2, 0, 0})
+	f.Add([]byte{2, 3, 0, 1, 3, 3, 0, 0, 6, 0, 0, 0})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tm := newModelTarget()
+		defer tm.Close()
+
+		ttltest.Check(t, tm, ttltest.DecodeOps(data, 8))
+	})
+}
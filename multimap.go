@@ -0,0 +1,288 @@
+package ttl
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MultiMap is a "time-to-live" map from each key to a set of values, each of which is removed once
+// its own time to live has elapsed since it was added, for example to track the active sessions of
+// each user. A key is removed along with its last value.
+//
+// A MultiMap is configured using the same [Option]s as a [Map]. [WithContext], [WithTTL],
+// [WithCapacity], [WithPruneInterval], [WithPruneJitter] and [WithClock] apply; other options are
+// ignored. Expired values are never visible, whether or not they have been pruned. Loading a value
+// does not extend its time to live; add it again to do so.
+//
+// MultiMap is safe for concurrent use.
+type MultiMap[K comparable, V comparable] struct {
+	clock      Clock
+	defaultTTL time.Duration
+
+	mtx sync.RWMutex
+	m   map[K]map[V]int64 // the expiry of each value in Unix nanoseconds, or zero if it never expires
+
+	bg background
+}
+
+// NewMultiMap returns a new, empty [MultiMap] configured by opts, like [New].
+func NewMultiMap[K comparable, V comparable](opts ...Option) *MultiMap[K, V] {
+	o := defaultOptions()
+	o.apply(opts)
+	o.logMisconfiguration()
+
+	mm := &MultiMap[K, V]{
+		clock:      o.clock,
+		defaultTTL: o.ttl,
+		m:          make(map[K]map[V]int64, max(o.capacity, 0)),
+		bg:         newBackground(),
+	}
+
+	mm.bg.start(o, func(now time.Time) {
+		mm.prune(now)
+	})
+
+	return mm
+}
+
+// Add adds value to the values of key with the default time to live. If the value is already held
+// by the key, its time to live starts again. Add is safe for concurrent use.
+func (mm *MultiMap[K, V]) Add(key K, value V) {
+	mm.AddWithTTL(key, value, mm.defaultTTL)
+}
+
+// AddWithTTL adds value to the values of key with a custom time to live, replacing its time to
+// live if the value is already held by the key. A zero or negative TTL means the value never
+// expires. AddWithTTL is safe for concurrent use.
+func (mm *MultiMap[K, V]) AddWithTTL(key K, value V, TTL time.Duration) {
+	var expireAt int64
+	if TTL > 0 {
+		expireAt = mm.clock.Now().Add(TTL).UnixNano()
+	}
+
+	mm.mtx.Lock()
+	defer mm.mtx.Unlock()
+
+	values, ok := mm.m[key]
+	if !ok {
+		values = make(map[V]int64, 1)
+		mm.m[key] = values
+	}
+
+	values[value] = expireAt
+}
+
+// Get returns the unexpired values of key, in no particular order, or nil if it has none. Get is
+// safe for concurrent use.
+func (mm *MultiMap[K, V]) Get(key K) (values []V) {
+	now := mm.clock.Now().UnixNano()
+
+	mm.mtx.RLock()
+	defer mm.mtx.RUnlock()
+
+	for value, expireAt := range mm.m[key] {
+		if !expiredAt(expireAt, now) {
+			values = append(values, value)
+		}
+	}
+
+	return
+}
+
+// Contains reports whether key holds value and it has not expired. Contains is safe for concurrent
+// use.
+func (mm *MultiMap[K, V]) Contains(key K, value V) bool {
+	now := mm.clock.Now().UnixNano()
+
+	mm.mtx.RLock()
+	defer mm.mtx.RUnlock()
+
+	expireAt, ok := mm.m[key][value]
+
+	return ok && !expiredAt(expireAt, now)
+}
+
+// Count returns the number of unexpired values of key. Count is safe for concurrent use.
+func (mm *MultiMap[K, V]) Count(key K) (n int) {
+	now := mm.clock.Now().UnixNano()
+
+	mm.mtx.RLock()
+	defer mm.mtx.RUnlock()
+
+	for _, expireAt := range mm.m[key] {
+		if !expiredAt(expireAt, now) {
+			n++
+		}
+	}
+
+	return
+}
+
+// TTL returns the time to live remaining for value of key, as well as a bool indicating whether the
+// key holds the value. If the value never expires, the remaining time returned is [NoExpiry]. TTL
+// is safe for concurrent use.
+func (mm *MultiMap[K, V]) TTL(key K, value V) (remaining time.Duration, ok bool) {
+	now := mm.clock.Now().UnixNano()
+
+	mm.mtx.RLock()
+	defer mm.mtx.RUnlock()
+
+	expireAt, ok := mm.m[key][value]
+
+	switch {
+	case !ok || expiredAt(expireAt, now):
+		return 0, false
+	case expireAt == 0:
+		return NoExpiry, true
+	default:
+		return time.Duration(expireAt - now), true
+	}
+}
+
+// Remove removes value from the values of key, removing the key if it was the last. Remove is safe
+// for concurrent use.
+func (mm *MultiMap[K, V]) Remove(key K, value V) {
+	mm.mtx.Lock()
+	defer mm.mtx.Unlock()
+
+	if values, ok := mm.m[key]; ok {
+		delete(values, value)
+
+		if len(values) == 0 {
+			delete(mm.m, key)
+		}
+	}
+}
+
+// Delete removes key and all of its values. Delete is safe for concurrent use.
+func (mm *MultiMap[K, V]) Delete(key K) {
+	mm.mtx.Lock()
+	defer mm.mtx.Unlock()
+
+	delete(mm.m, key)
+}
+
+// Clear removes every key. Clear is safe for concurrent use.
+func (mm *MultiMap[K, V]) Clear() {
+	mm.mtx.Lock()
+	defer mm.mtx.Unlock()
+
+	clear(mm.m)
+}
+
+// Length returns the number of keys in the [MultiMap], including any whose values have all expired
+// but which have not been pruned yet. Length is safe for concurrent use.
+func (mm *MultiMap[K, V]) Length() int {
+	mm.mtx.RLock()
+	defer mm.mtx.RUnlock()
+
+	return len(mm.m)
+}
+
+// Keys returns the keys that hold at least one unexpired value, in no particular order. Keys is
+// safe for concurrent use.
+func (mm *MultiMap[K, V]) Keys() (keys []K) {
+	mm.Range(func(key K, _ []V) bool {
+		keys = append(keys, key)
+		return true
+	})
+
+	return
+}
+
+// Range calls f sequentially for each key that holds at least one unexpired value, with those
+// values. If f returns false, Range stops the iteration. Range iterates over a copy of the
+// [MultiMap] taken when it is called, so f may modify the [MultiMap]. Range is safe for concurrent
+// use.
+func (mm *MultiMap[K, V]) Range(f func(key K, values []V) bool) {
+	_ = mm.RangeContext(context.Background(), f)
+}
+
+// RangeContext is like [MultiMap.Range], but stops and returns ctx.Err() if ctx is done before the
+// iteration completes.
+func (mm *MultiMap[K, V]) RangeContext(ctx context.Context, f func(key K, values []V) bool) error {
+	type entry struct {
+		key    K
+		values []V
+	}
+
+	now := mm.clock.Now().UnixNano()
+
+	mm.mtx.RLock()
+
+	entries := make([]entry, 0, len(mm.m))
+	for key, values := range mm.m {
+		e := entry{key: key}
+		for value, expireAt := range values {
+			if !expiredAt(expireAt, now) {
+				e.values = append(e.values, value)
+			}
+		}
+
+		if len(e.values) > 0 {
+			entries = append(entries, e)
+		}
+	}
+
+	mm.mtx.RUnlock()
+
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !f(e.key, e.values) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Prune removes the expired values, and the keys left without values, returning the number of
+// values removed. Prune is safe for concurrent use.
+func (mm *MultiMap[K, V]) Prune() int {
+	return mm.prune(mm.clock.Now())
+}
+
+// Close stops the [MultiMap] from pruning in the background. If Close is not called on a
+// [MultiMap] after it's no longer needed, its prune goroutine will leak (unless the context has
+// been cancelled). Close may be called multiple times.
+func (mm *MultiMap[K, V]) Close() {
+	mm.bg.close()
+}
+
+// CloseWait is like [MultiMap.Close], but also waits for the prune goroutine to exit.
+func (mm *MultiMap[K, V]) CloseWait() {
+	mm.bg.closeWait()
+}
+
+// prune removes the values that have expired as of now, and the keys left without values.
+func (mm *MultiMap[K, V]) prune(now time.Time) (pruned int) {
+	nowNano := now.UnixNano()
+
+	mm.mtx.Lock()
+	defer mm.mtx.Unlock()
+
+	for key, values := range mm.m {
+		for value, expireAt := range values {
+			if expiredAt(expireAt, nowNano) {
+				delete(values, value)
+				pruned++
+			}
+		}
+
+		if len(values) == 0 {
+			delete(mm.m, key)
+		}
+	}
+
+	return
+}
+
+// expiredAt reports whether something that expires at expireAt has expired as of now, both in Unix
+// nanoseconds. An expireAt of zero never expires.
+func expiredAt(expireAt int64, now int64) bool {
+	return expireAt != 0 && expireAt <= now
+}
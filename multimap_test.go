@@ -0,0 +1,64 @@
+package ttl_test
+
+import (
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestMultiMap() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	sessions := ttl.NewMultiMap[string, string](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0))
+	defer sessions.Close()
+
+	sessions.Add("alice", "s1")
+	sessions.AddWithTTL("alice", "s2", 10*time.Second)
+	sessions.Add("bob", "s3")
+
+	s.ElementsMatch([]string{"s1", "s2"}, sessions.Get("alice"))
+	s.True(sessions.Contains("alice", "s2"))
+	s.False(sessions.Contains("bob", "s1"))
+	s.Equal(2, sessions.Count("alice"))
+	s.Equal(2, sessions.Length())
+
+	clock.Advance(10 * time.Second)
+	s.Equal([]string{"s1"}, sessions.Get("alice"))
+	_, ok := sessions.TTL("alice", "s2")
+	s.False(ok)
+
+	// Adding a value again starts its time to live again
+	sessions.Add("bob", "s3")
+	remaining, ok := sessions.TTL("bob", "s3")
+	s.True(ok)
+	s.Equal(time.Minute, remaining)
+
+	clock.Advance(50 * time.Second)
+	s.Nil(sessions.Get("alice"))
+	s.Equal([]string{"bob"}, sessions.Keys())
+
+	// A key is removed with its last value
+	s.Equal(2, sessions.Prune())
+	s.Equal(1, sessions.Length())
+
+	sessions.Remove("bob", "s3")
+	s.Zero(sessions.Length())
+
+	sessions.Add("carol", "s4")
+	sessions.Add("carol", "s5")
+	n := 0
+	sessions.Range(func(key string, values []string) bool {
+		n++
+		s.Equal("carol", key)
+		s.Len(values, 2)
+		return true
+	})
+	s.Equal(1, n)
+
+	sessions.Delete("carol")
+	s.Zero(sessions.Count("carol"))
+}
@@ -0,0 +1,100 @@
+package ttl
+
+import (
+	"context"
+	"time"
+)
+
+// Namespace is a view of the entries of a [Map] with string keys whose keys start with a prefix,
+// such as "sessions:". Its methods take and return keys without the prefix, so that several
+// modules can share one [Map], and its pruning, without their keys colliding. A Namespace holds
+// no entries of its own and needs no closing.
+//
+// Operations on the whole Namespace, such as [Namespace.Clear], use the prefix index of the [Map]
+// if it was created using [WithPrefixIndex], and otherwise visit every key of the [Map].
+//
+// Namespace is safe for concurrent use.
+type Namespace[K ~string, V any] struct {
+	m      *Map[K, V]
+	prefix string
+}
+
+// NewNamespace returns a [Namespace] of m holding the keys that start with prefix.
+func NewNamespace[K ~string, V any](m *Map[K, V], prefix string) *Namespace[K, V] {
+	return &Namespace[K, V]{m: m, prefix: prefix}
+}
+
+// Namespace returns a [Namespace] nested in n, holding the keys of n that start with prefix.
+func (n *Namespace[K, V]) Namespace(prefix string) *Namespace[K, V] {
+	return NewNamespace(n.m, n.prefix+prefix)
+}
+
+// Prefix returns the prefix of the keys of n in the [Map].
+func (n *Namespace[K, V]) Prefix() string {
+	return n.prefix
+}
+
+// Store is like [Map.Store], for key in n.
+func (n *Namespace[K, V]) Store(key K, value V) {
+	n.m.Store(n.key(key), value)
+}
+
+// StoreWithTTL is like [Map.StoreWithTTL], for key in n.
+func (n *Namespace[K, V]) StoreWithTTL(key K, value V, TTL time.Duration) {
+	n.m.StoreWithTTL(n.key(key), value, TTL)
+}
+
+// Load is like [Map.Load], for key in n.
+func (n *Namespace[K, V]) Load(key K) (value V, ok bool) {
+	return n.m.Load(n.key(key))
+}
+
+// LoadPassive is like [Map.LoadPassive], for key in n.
+func (n *Namespace[K, V]) LoadPassive(key K) (value V, ok bool) {
+	return n.m.LoadPassive(n.key(key))
+}
+
+// TTL is like [Map.TTL], for key in n.
+func (n *Namespace[K, V]) TTL(key K) (remaining time.Duration, ok bool) {
+	return n.m.TTL(n.key(key))
+}
+
+// Delete is like [Map.Delete], for key in n.
+func (n *Namespace[K, V]) Delete(key K) {
+	n.m.Delete(n.key(key))
+}
+
+// Clear removes every entry of n from the [Map], returning the number removed, like
+// [DeletePrefix].
+func (n *Namespace[K, V]) Clear() int {
+	return DeletePrefix(n.m, n.prefix)
+}
+
+// Length returns the number of unexpired entries of n. Length is safe for concurrent use.
+func (n *Namespace[K, V]) Length() (length int) {
+	RangePrefix(n.m, n.prefix, func(K, V) bool {
+		length++
+		return true
+	})
+
+	return
+}
+
+// Range is like [Map.Range], visiting the entries of n with the prefix removed from their keys, as
+// [RangePrefix] does.
+func (n *Namespace[K, V]) Range(f func(key K, value V) bool) {
+	_ = n.RangeContext(context.Background(), f)
+}
+
+// RangeContext is like [Namespace.Range], but stops and returns ctx.Err() if ctx is done before the
+// iteration completes, like [Map.RangeContext].
+func (n *Namespace[K, V]) RangeContext(ctx context.Context, f func(key K, value V) bool) error {
+	return RangePrefixContext(ctx, n.m, n.prefix, func(key K, value V) bool {
+		return f(key[len(n.prefix):], value)
+	})
+}
+
+// key returns the key in the Map of key in n.
+func (n *Namespace[K, V]) key(key K) K {
+	return K(n.prefix) + key
+}
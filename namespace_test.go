@@ -0,0 +1,66 @@
+package ttl_test
+
+import (
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestNamespace() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	m := ttl.New[string, int](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0),
+		ttl.WithPrefixIndex[string]())
+	defer m.Close()
+
+	sessions := ttl.NewNamespace(m, "sessions:")
+	users := ttl.NewNamespace(m, "users:")
+	profiles := users.Namespace("profiles:")
+	s.Equal("users:profiles:", profiles.Prefix())
+
+	sessions.Store("1", 1)
+	users.Store("1", 2)
+	profiles.StoreWithTTL("1", 3, time.Second)
+
+	v, ok := sessions.Load("1")
+	s.True(ok)
+	s.Equal(1, v)
+
+	v, ok = users.LoadPassive("1")
+	s.True(ok)
+	s.Equal(2, v)
+
+	v, _ = m.Load("users:profiles:1")
+	s.Equal(3, v)
+
+	remaining, ok := profiles.TTL("1")
+	s.True(ok)
+	s.Equal(time.Second, remaining)
+
+	s.Equal(1, sessions.Length())
+	s.Equal(2, users.Length())
+
+	seen := make(map[string]int)
+	users.Range(func(key string, value int) bool {
+		seen[key] = value
+		return true
+	})
+	s.Equal(map[string]int{"1": 2, "profiles:1": 3}, seen)
+
+	clock.Advance(time.Second)
+	s.Zero(profiles.Length())
+
+	sessions.Delete("1")
+	_, ok = sessions.Load("1")
+	s.False(ok)
+
+	users.Store("2", 4)
+	sessions.Store("2", 5)
+	s.Equal(2, users.Clear())
+	s.Equal(1, m.Length())
+	s.Equal(1, sessions.Length())
+}
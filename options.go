@@ -0,0 +1,593 @@
+package ttl
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+)
+
+const (
+	// DefaultTTL is the default time to live of a [Map] created by [New] without [WithTTL].
+	DefaultTTL = time.Minute
+
+	// DefaultPruneInterval is the default prune interval of a [Map] created by [New] without
+	// [WithPruneInterval].
+	DefaultPruneInterval = time.Second
+
+	// DefaultCounterBuckets is the default number of buckets of a [Counter] created without
+	// [WithCounterBuckets].
+	DefaultCounterBuckets = 10
+)
+
+// Option configures optional behaviour of a [Map] when it is constructed.
+type Option func(*options)
+
+type options struct {
+	ctx           context.Context
+	ttl           time.Duration
+	capacity      int
+	pruneInterval time.Duration
+	refreshOnLoad bool
+	missValue     any // func(K) V
+	lazyExpiry    bool
+	quantileStats bool
+	counters      bool
+	logger        *slog.Logger
+	clock         Clock
+	pruneJitter   float64
+	ttlJitter     float64
+	statsScope    any // func(K) string
+	pruneBatch    int
+	pruneStrategy PruneStrategy
+	shards        int
+	hasher        any // func(K) uint64
+	readMostly    bool
+	iterBatch     int
+	onExpire      any // func(K, V)
+	onRemove      any // func(K, V, RemovalReason)
+	onDrop        any // func(T)
+	buckets       int
+	expiredBuffer int
+	maxEntries    int
+	maxCost       int64
+	costOf        any // func(K, V) int64
+	memoryCost    bool
+	admission     AdmissionPolicy
+	store         any // Store[K, V]
+	writeBehind   bool
+	flushInterval time.Duration
+	readThrough   any // Store[K, V]
+
+	snapshotPath     string
+	snapshotInterval time.Duration
+
+	coalesceWindow time.Duration
+	coalesceEqual  any // func(V, V) bool
+
+	prefixKey   any // func(K) string
+	sampleIndex bool
+}
+
+func defaultOptions() options {
+	return options{
+		ctx:           context.Background(),
+		ttl:           DefaultTTL,
+		pruneInterval: DefaultPruneInterval,
+		refreshOnLoad: true,
+		lazyExpiry:    true,
+		clock:         systemClock{},
+		buckets:       DefaultCounterBuckets,
+	}
+}
+
+// WithContext sets the context of the [Map]. If the context is cancelled, the pruning process will
+// automatically stop as if [Map.Close] had been called.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithTTL sets the default time to live of items in the [Map]. A zero or negative TTL means items
+// stored with [Map.Store] never expire.
+func WithTTL(TTL time.Duration) Option {
+	return func(o *options) {
+		o.ttl = TTL
+	}
+}
+
+// WithCapacity sets the number of items the [Map] initially allocates space for. It does not limit
+// the number of items the [Map] may hold.
+func WithCapacity(capacity int) Option {
+	return func(o *options) {
+		o.capacity = capacity
+	}
+}
+
+// WithMaxEntries limits the number of items the [Map] may hold to n. When a store would exceed the
+// limit, an existing item is evicted to make room: an expired item if one is found, otherwise the
+// least recently accessed of a small random sample of items. Evicted items are reported with
+// [ReasonEvicted]. Pinned items are never evicted, so a [Map] whose items are all pinned may exceed
+// the limit. A zero or negative n (the default) means the [Map] is unbounded.
+//
+// For a [ShardedMap], the limit is divided between the shards.
+func WithMaxEntries(n int) Option {
+	return func(o *options) {
+		o.maxEntries = n
+	}
+}
+
+// WithMaxCost limits the total cost of the items the [Map] may hold to budget, where the cost of
+// each item is given by cost, such as the size in bytes of the value. Items are evicted as for
+// [WithMaxEntries] until the total cost is within the budget, so a single item whose cost exceeds
+// the budget is evicted as soon as it is stored. cost is called with the lock on the [Map] held,
+// each time a value is stored, so it must be fast and must not call methods of the [Map].
+// WithMaxCost may be combined with [WithMaxEntries] and [WithAdmissionPolicy].
+//
+// For a [ShardedMap], the budget is divided between the shards.
+//
+// cost must use the same key and value types as the [Map], otherwise the constructor panics.
+func WithMaxCost[K comparable, V any](budget int64, cost func(key K, value V) int64) Option {
+	return func(o *options) {
+		o.maxCost = budget
+		o.costOf = cost
+		o.memoryCost = false
+	}
+}
+
+// WithMaxMemory limits the approximate memory used by the items of the [Map] to bytes, evicting
+// items as for [WithMaxCost]. The memory used by each item is estimated using [EstimateSize] when
+// it is stored, so this protects against unbounded growth without a cost function, but the
+// estimate is approximate and comparatively slow for large values. Where the size of values is
+// known more cheaply, such as for byte slices, [WithMaxCost] should be preferred.
+//
+// WithMaxMemory replaces any cost function set by [WithMaxCost]. For a [ShardedMap], the limit is
+// divided between the shards.
+func WithMaxMemory(bytes int64) Option {
+	return func(o *options) {
+		o.maxCost = bytes
+		o.costOf = nil
+		o.memoryCost = true
+	}
+}
+
+// WithAdmissionPolicy sets the policy that decides whether a new key is stored when the [Map] is
+// full, as limited by [WithMaxEntries] or [WithMaxCost]. The default is [AdmitAll]. It has no
+// effect on a [Map] without a limit.
+func WithAdmissionPolicy(policy AdmissionPolicy) Option {
+	return func(o *options) {
+		o.admission = policy
+	}
+}
+
+// WithPruneInterval sets how often the [Map] removes expired items. A zero or negative interval
+// disables background pruning.
+func WithPruneInterval(pruneInterval time.Duration) Option {
+	return func(o *options) {
+		o.pruneInterval = pruneInterval
+	}
+}
+
+// WithPruneJitter randomizes the prune interval of each [Map] by up to fraction of the interval in
+// either direction, so that many maps created at the same time (for example, at process start) do
+// not prune in lockstep. The interval is chosen once, when the [Map] is created, so maps with
+// different intervals drift apart over time. A fraction of 0.1 with a prune interval of one second
+// gives each [Map] an interval between 0.9 and 1.1 seconds. fraction is limited to between 0 (no
+// jitter, the default) and 0.5.
+func WithPruneJitter(fraction float64) Option {
+	return func(o *options) {
+		o.pruneJitter = min(max(fraction, 0), 0.5)
+	}
+}
+
+// WithTTLJitter randomizes the TTL of each entry by up to fraction of the TTL in either direction
+// when it is stored, so that entries stored at the same time do not all expire at the same instant
+// (causing a burst of reloads). A fraction of 0.1 gives an entry stored with a TTL of one minute a
+// TTL between 54 and 66 seconds. The jitter applies to the default TTL and to TTLs given to
+// [Map.StoreWithTTL] and [Map.StoreWithPolicy], but not to absolute expiration times or TTLs set
+// explicitly with [Map.SetTTL], and entries that never expire are unaffected. fraction is limited
+// to between 0 (no jitter, the default) and 0.5.
+func WithTTLJitter(fraction float64) Option {
+	return func(o *options) {
+		o.ttlJitter = min(max(fraction, 0), 0.5)
+	}
+}
+
+// WithPruneBatchSize limits each background prune pass to checking at most n items, bounding how
+// long the [Map] is locked by pruning. A full pass over a large [Map] blocks every load and store
+// until it completes; with a batch size, the pass is spread over as many prune intervals as needed
+// and continues where it left off each interval. Expired items may therefore remain in the [Map]
+// for several prune intervals (although they are still hidden from loads, see [WithLazyExpiry]).
+//
+// A zero or negative n (the default) checks every item on each pass. [Map.Prune] always performs a
+// full pass.
+func WithPruneBatchSize(n int) Option {
+	return func(o *options) {
+		o.pruneBatch = n
+	}
+}
+
+// WithPruneStrategy sets how background prune passes find expired items. The default is
+// [PruneFullScan]. [WithPruneBatchSize] applies to every strategy except [PruneSampled].
+// [Map.Prune] always performs a full pass, regardless of the strategy.
+func WithPruneStrategy(strategy PruneStrategy) Option {
+	return func(o *options) {
+		o.pruneStrategy = strategy
+	}
+}
+
+// WithReadMostly optimizes the [Map] for workloads that are dominated by reads. When enabled,
+// [Map.Load] and [Map.LoadPassive] are served from an immutable copy of the [Map] without taking
+// any lock, so concurrent loads do not contend with each other at all. The copy is discarded by any
+// modification of the [Map] (including the removal of expired items by a prune pass) and rebuilt by
+// the next load, which costs time and memory proportional to the size of the [Map]. It should
+// therefore only be used for maps that are modified rarely compared to how often they are read.
+//
+// A load that starts while the [Map] is being modified may return the value from before the
+// modification.
+func WithReadMostly(enabled bool) Option {
+	return func(o *options) {
+		o.readMostly = enabled
+	}
+}
+
+// WithIterationBatchSize limits how long [Map.Range], [Map.RangeContext], [Map.DeleteFunc] and
+// [Map.DeleteFuncContext] hold the write lock of the [Map]. By default, the lock is held for the
+// whole iteration, so that even a quick callback blocks all other operations for as long as it
+// takes to visit every entry of a large [Map]. With a batch size of n, the keys present when the
+// iteration starts are captured under the read lock, and the write lock is released after every n
+// keys so that other operations can proceed.
+//
+// As a result, the iteration does not observe a single consistent state of the [Map]: entries
+// stored during the iteration are not visited, entries removed before they are reached are
+// skipped, and an entry's value may have been replaced by the time it is visited. The callback is
+// still called with the write lock held and has the same restrictions. A zero or negative n (the
+// default) holds the lock for the whole iteration.
+func WithIterationBatchSize(n int) Option {
+	return func(o *options) {
+		o.iterBatch = n
+	}
+}
+
+// WithShards sets the number of shards of a [ShardedMap]. A zero or negative n (the default) uses
+// four shards per processor. It has no effect on a [Map].
+func WithShards(n int) Option {
+	return func(o *options) {
+		o.shards = n
+	}
+}
+
+// WithHasher sets the function used by a [ShardedMap] to assign keys to shards: a key is held by
+// shard hash(key) modulo the number of shards. Equal keys must have equal hashes. This allows
+// control over placement for skewed key distributions, or faster hashing of custom key types than
+// the default, which hashes key types other than strings and numbers using reflection. For a
+// [Map], it is only used by the frequency sketch of [AdmitTinyLFU].
+//
+// hash must use the same key type as the [ShardedMap], otherwise the constructor panics.
+func WithHasher[K comparable](hash func(key K) uint64) Option {
+	return func(o *options) {
+		o.hasher = hash
+	}
+}
+
+// WithRefreshOnLoad controls whether [Map.Load] updates an item's last access time, extending its
+// lifetime. It is enabled by default.
+func WithRefreshOnLoad(refreshOnLoad bool) Option {
+	return func(o *options) {
+		o.refreshOnLoad = refreshOnLoad
+	}
+}
+
+// WithMissValue configures the [Map] to return f(key) from [Map.Load] and [Map.LoadPassive] when
+// key is not found, instead of the zero value. The synthesized value is not stored in the [Map]
+// and the bool returned by the load still reports that the key was not found.
+//
+// f must use the same key and value types as the [Map], otherwise the constructor panics. f is
+// called without holding any lock on the [Map].
+func WithMissValue[K comparable, V any](f func(key K) V) Option {
+	return func(o *options) {
+		o.missValue = f
+	}
+}
+
+// WithOnExpire sets a callback that is called with the key and value of each entry that is removed
+// from the [Map] because it expired, so that resources held by the value can be released. An entry
+// is removed by the prune pass after its TTL elapses (or after [Map.Expire]), or when a store
+// replaces it, so the callback may run up to a prune interval after the entry expired. Entries that
+// are deleted, cleared or replaced before they expire are not reported.
+//
+// f is called after the lock on the [Map] has been released, so it may call methods of the [Map],
+// but it may be called concurrently from different goroutines. A slow callback delays the operation
+// that removed the entry, including the prune pass.
+//
+// f must use the same key and value types as the [Map], otherwise the constructor panics.
+func WithOnExpire[K comparable, V any](f func(key K, value V)) Option {
+	return func(o *options) {
+		o.onExpire = f
+	}
+}
+
+// WithOnDrop sets a callback that is called with each element of a [Queue] that expires before it
+// is dequeued and is dropped, so that stale work can be logged or retried, or with each object of a
+// [Pool] that is destroyed, so that it can be closed. f is called after the lock on the [Queue] or
+// [Pool] has been released, so it may call its methods.
+//
+// f must use the same element type as the [Queue] or [Pool], otherwise the constructor panics.
+func WithOnDrop[T any](f func(value T)) Option {
+	return func(o *options) {
+		o.onDrop = f
+	}
+}
+
+// WithCounterBuckets sets the number of buckets the window of each key of a [Counter] is divided
+// into. More buckets make counts more precise at the cost of memory: a contribution is expired
+// between TTL/n before and exactly at the end of its TTL. If n is less than 1, one bucket is used.
+func WithCounterBuckets(n int) Option {
+	return func(o *options) {
+		o.buckets = n
+	}
+}
+
+// WithOnRemove sets a callback that is called with the key and value of each entry that is removed
+// from the [Map], along with the reason it was removed, so that a single handler can, for example,
+// record different metrics for expiry and deletion. An entry that is replaced by a store to its key
+// is reported with its old value and [ReasonReplaced]. It may be combined with [WithOnExpire], in
+// which case both callbacks are called for an expired entry.
+//
+// f is called after the lock on the [Map] has been released, as with [WithOnExpire].
+//
+// f must use the same key and value types as the [Map], otherwise the constructor panics.
+func WithOnRemove[K comparable, V any](f func(key K, value V, reason RemovalReason)) Option {
+	return func(o *options) {
+		o.onRemove = f
+	}
+}
+
+// WithWriteThrough sets a [Store] to which every value stored in the [Map] is written, with the
+// time to live it has remaining, and from which every key deleted from the [Map] by [Map.Delete],
+// [Map.DeleteFunc], [Map.Clear] or [Map.Rename] is deleted. Entries that expire or are evicted
+// from the [Map] are left in the store, which expires them itself.
+//
+// Writes are made after the lock on the [Map] has been released, but before the method that made
+// them returns, in the order in which the [Map] was changed. A write that fails is logged to the
+// logger set using [WithLogger], if any; it is not retried. Loading from the [Map] never reads from
+// the store.
+//
+// store must use the same key and value types as the [Map], otherwise the constructor panics.
+func WithWriteThrough[K comparable, V any](store Store[K, V]) Option {
+	return func(o *options) {
+		o.store = store
+		o.writeBehind = false
+	}
+}
+
+// WithWriteBehind is like [WithWriteThrough], except that writes are queued and made to store in
+// the background, so that changing the [Map] does not wait for store. Queued writes are made every
+// flushInterval, or as soon as they are queued if flushInterval is zero or negative. Only the last
+// write queued for each key is made, so a key that changes often is written once per flush.
+//
+// The queue holds up to [WriteBehindQueue] writes. When it is full, changes to the [Map] wait for
+// room in the queue, and the queued writes are made without waiting for the next flush. Writes
+// still queued when the [Map] is closed are made before [Map.CloseWait] returns, and later writes
+// are made as with [WithWriteThrough].
+//
+// store must use the same key and value types as the [Map], otherwise the constructor panics.
+func WithWriteBehind[K comparable, V any](store Store[K, V], flushInterval time.Duration) Option {
+	return func(o *options) {
+		o.store = store
+		o.writeBehind = true
+		o.flushInterval = flushInterval
+	}
+}
+
+// WithReadThrough sets a [Store] from which a key that is not found in the [Map] is loaded by
+// [Map.Load] and [Map.LoadPassive]. If the store holds the key, its value is stored in the [Map]
+// with the time to live it has remaining in the store, then returned; it is not written back to a
+// store set using [WithWriteThrough]. Loads that fall through to the store are still counted as
+// misses by [Map.Stats]. A failed read is logged to the logger set using [WithLogger], if any, and
+// treated as a miss; use [Map.LoadContext] to have it returned instead. Concurrent loads of a key
+// that is not in the [Map] share a single read from the store.
+//
+// To load misses from a source other than a [Store], such as a database, wrap the function that
+// reads it in a [LoaderFunc].
+//
+// Combined with [WithWriteThrough] using the same store, the [Map] acts as a process-local cache
+// in front of a store that may be shared between processes.
+//
+// store must use the same key and value types as the [Map], otherwise the constructor panics.
+func WithReadThrough[K comparable, V any](store Store[K, V]) Option {
+	return func(o *options) {
+		o.readThrough = store
+	}
+}
+
+// WithExpiredChannel enables the channel returned by [Map.Expired], with a buffer of size entries.
+// Entries that expire while the buffer is full are dropped, so size should allow for the largest
+// number of entries expected to expire before the receiver catches up. If size is zero or negative,
+// the channel is disabled (the default).
+func WithExpiredChannel(size int) Option {
+	return func(o *options) {
+		o.expiredBuffer = size
+	}
+}
+
+// WithLazyExpiry controls whether the [Map] checks an item's TTL when it is accessed. When enabled
+// (the default), loads, iteration and other accessors treat an item whose TTL has elapsed as
+// missing, even if it has not been pruned yet. When disabled, an expired item remains visible until
+// the next prune pass removes it, which may be up to a full prune interval after it expired.
+func WithLazyExpiry(enabled bool) Option {
+	return func(o *options) {
+		o.lazyExpiry = enabled
+	}
+}
+
+// WithClock sets the [Clock] used by the [Map] to expire items and schedule background pruning, in
+// place of the system clock. This is intended for tests, which can use a fake clock to expire items
+// deterministically instead of sleeping. The latency distributions collected by
+// [WithQuantileStats] are always measured using the system clock.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithSnapshotFile saves the contents of the [Map] to the file at path every interval and once
+// more when the [Map] is closed, using [Map.SaveFile], so that a cache survives a restart or crash.
+// A zero or negative interval only saves when the [Map] is closed. Errors saving the file are
+// logged to the logger set using [WithLogger], if any.
+//
+// [New] does not read the file, so the [Map] should be created using [NewFromSnapshotFile] to
+// restore the contents saved by a previous process. [Map.CloseWait] waits for the final save.
+func WithSnapshotFile(path string, interval time.Duration) Option {
+	return func(o *options) {
+		o.snapshotPath = path
+		o.snapshotInterval = interval
+	}
+}
+
+// WithLogger sets a logger to which the [Map] reports its activity and problems that would
+// otherwise go unnoticed:
+//
+//   - each background prune pass and each eviction to stay within capacity, at debug level;
+//   - options that are likely to be mistakes, such as an admission policy without a capacity
+//     limit, at warning level when the [Map] is created;
+//   - a panic in a callback set using [WithOnExpire] or [WithOnRemove], at error level. When a
+//     logger is set, such a panic is recovered rather than crashing the process.
+//
+// Messages have no attributes identifying the [Map], so a logger with such attributes should be
+// given if an application has several maps, for example logger.With("cache", "sessions"). By
+// default, nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithStats enables counting of hits, misses and stores, reported by [Map.Stats]. This adds an
+// atomic increment to every load and store, which may contend between processors under heavy
+// concurrent use, so it is disabled by default. Removals are always counted.
+func WithStats(enabled bool) Option {
+	return func(o *options) {
+		o.counters = enabled
+	}
+}
+
+// WithQuantileStats enables collection of the distributions of entry age at eviction and of load
+// and store latency, reported by [Map.Stats]. This adds a small cost to every load and store, so it
+// is disabled by default.
+func WithQuantileStats(enabled bool) Option {
+	return func(o *options) {
+		o.quantileStats = enabled
+	}
+}
+
+// WithStatsScope enables a per-scope breakdown of entries, hits, misses and evictions in
+// [Map.Stats], where scope returns the name of the scope a key belongs to (for example, the tenant
+// encoded in the key). scope is called on every load and eviction, so it should be cheap.
+//
+// scope must use the same key type as the [Map], otherwise the constructor panics.
+func WithStatsScope[K comparable](scope func(key K) string) Option {
+	return func(o *options) {
+		o.statsScope = scope
+	}
+}
+
+// WithWriteCoalescing protects the [Map] from hot keys that are rewritten with the same value many
+// times per second. When [Map.Store] or [Map.StoreWithTTL] writes a value that equal reports is the
+// same as the current value (with the same TTL), within window of the last write of that key, the
+// entry's last access time is updated instead of performing a full write. A coalesced store only
+// takes the read lock and is not counted as a replacement.
+//
+// equal must use the same value type as the [Map], otherwise the constructor panics.
+func WithWriteCoalescing[V any](window time.Duration, equal func(a, b V) bool) Option {
+	return func(o *options) {
+		o.coalesceWindow = window
+		o.coalesceEqual = equal
+	}
+}
+
+// WithPrefixIndex makes a [Map] with string keys maintain an index of its keys ordered as a trie,
+// so that [LoadPrefix] and [RangePrefix] visit only the keys with the prefix, in lexicographic
+// order, rather than every key. This adds the cost of maintaining the index to every insertion and
+// removal of a key, and the memory it uses.
+//
+// K must be the key type of the [Map], otherwise the constructor panics.
+func WithPrefixIndex[K ~string]() Option {
+	return func(o *options) {
+		o.prefixKey = func(key K) string {
+			return string(key)
+		}
+	}
+}
+
+// WithSampleIndex makes a [Map] maintain an array of its keys, so that [Map.RandomEntry] returns
+// each unexpired entry with the same probability rather than approximately. This adds the cost of
+// maintaining the array to every insertion and removal of a key, and the memory it uses.
+func WithSampleIndex() Option {
+	return func(o *options) {
+		o.sampleIndex = true
+	}
+}
+
+// apply applies each of opts to o in order.
+func (o *options) apply(opts []Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// jitteredPruneInterval returns the prune interval, randomized according to the prune jitter.
+func (o *options) jitteredPruneInterval() time.Duration {
+	if o.pruneJitter == 0 {
+		return o.pruneInterval
+	}
+
+	return time.Duration(float64(o.pruneInterval) * (1 + o.pruneJitter*(2*rand.Float64()-1)))
+}
+
+// logMisconfiguration warns the logger, if one is set, of options that are likely to be mistakes.
+func (o *options) logMisconfiguration() {
+	if o.logger == nil {
+		return
+	}
+
+	bounded := o.maxEntries > 0 || o.maxCost > 0
+
+	if o.pruneInterval <= 0 && !o.lazyExpiry {
+		o.logger.Warn("background pruning and lazy expiry are both disabled, so expired items " +
+			"remain visible until they are deleted")
+	}
+
+	if o.admission != AdmitAll && !bounded {
+		o.logger.Warn("the admission policy has no effect without a capacity limit such as " +
+			"WithMaxEntries")
+	}
+
+	if o.pruneBatch > 0 && o.pruneStrategy == PruneSampled {
+		o.logger.Warn("the prune batch size has no effect with the prune strategy",
+			"strategy", o.pruneStrategy)
+	}
+
+	if o.pruneInterval <= 0 && o.pruneStrategy != PruneFullScan {
+		o.logger.Warn("the prune strategy has no effect when background pruning is disabled",
+			"strategy", o.pruneStrategy)
+	}
+}
+
+// typedOption asserts that the generic option value v has type T, panicking with a descriptive
+// message if it does not. It returns the zero value of T if v is nil.
+func typedOption[T any](name string, v any) T {
+	if v == nil {
+		var zero T
+		return zero
+	}
+
+	t, ok := v.(T)
+	if !ok {
+		panic(fmt.Sprintf("ttl: %s expects %T, got %T", name, *new(T), v))
+	}
+
+	return t
+}
@@ -0,0 +1,1072 @@
+package ttl_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestWithMissValue() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](
+		s.maxTTL,
+		s.startSize,
+		s.pruneInterval,
+		refreshOnLoad,
+		ttl.WithMissValue(func(key string) int {
+			return len(key)
+		}))
+	defer tm.Close()
+
+	tm.Store("present", 1)
+
+	v, ok := tm.Load("present")
+	s.True(ok)
+	s.Equal(1, v)
+
+	v, ok = tm.Load("four")
+	s.False(ok)
+	s.Equal(4, v)
+
+	v, ok = tm.LoadPassive("three")
+	s.False(ok)
+	s.Equal(5, v)
+
+	s.Equal(1, tm.Length(), "miss values must not be stored")
+}
+
+func (s *MapTestSuite) TestWithMissValueTypeMismatch() {
+	refreshOnLoad := true
+
+	s.Panics(func() {
+		ttl.NewMap[string, int](
+			s.maxTTL,
+			s.startSize,
+			s.pruneInterval,
+			refreshOnLoad,
+			ttl.WithMissValue(func(key int) int {
+				return key
+			}))
+	})
+}
+
+func (s *MapTestSuite) TestLazyExpiry() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](s.pruneInterval, s.startSize, time.Minute, refreshOnLoad)
+	defer tm.Close()
+
+	tm.Store("expired", 1)
+
+	time.Sleep(2 * s.pruneInterval)
+
+	// Not pruned yet, but invisible
+	s.Equal(1, tm.Length())
+
+	_, ok := tm.Load("expired")
+	s.False(ok)
+	_, ok = tm.LoadPassive("expired")
+	s.False(ok)
+	_, ok = tm.TTL("expired")
+	s.False(ok)
+	s.False(tm.Touch("expired"))
+
+	tm.Range(func(key string, value int) bool {
+		s.Fail("expired entries must not be visited")
+		return true
+	})
+}
+
+func (s *MapTestSuite) TestWithLazyExpiryDisabled() {
+	refreshOnLoad := false
+	tm := ttl.NewMap[string, int](
+		s.pruneInterval,
+		s.startSize,
+		time.Minute,
+		refreshOnLoad,
+		ttl.WithLazyExpiry(false))
+	defer tm.Close()
+
+	tm.Store("expired", 1)
+
+	time.Sleep(2 * s.pruneInterval)
+
+	v, ok := tm.LoadPassive("expired")
+	if s.True(ok) {
+		s.Equal(1, v)
+	}
+
+	remaining, ok := tm.TTL("expired")
+	if s.True(ok) {
+		s.Zero(remaining)
+	}
+}
+
+func (s *MapTestSuite) TestNew() {
+	tm := ttl.New[string, int](
+		ttl.WithTTL(s.maxTTL),
+		ttl.WithCapacity(s.startSize),
+		ttl.WithPruneInterval(s.pruneInterval),
+		ttl.WithRefreshOnLoad(false))
+	defer tm.Close()
+
+	tm.Store("key", 1)
+
+	remaining, ok := tm.TTL("key")
+	if s.True(ok) {
+		s.LessOrEqual(remaining, s.maxTTL)
+	}
+
+	time.Sleep(s.sleepTime)
+
+	s.Zero(tm.Length())
+}
+
+func (s *MapTestSuite) TestNewDefaults() {
+	tm := ttl.New[string, int]()
+	defer tm.Close()
+
+	tm.Store("key", 1)
+
+	remaining, ok := tm.TTL("key")
+	if s.True(ok) {
+		s.Greater(remaining, ttl.DefaultTTL-time.Second)
+	}
+}
+
+func (s *MapTestSuite) TestNewWithContext() {
+	cancellableCtx, cancelFunc := context.WithCancel(context.Background())
+	tm := ttl.New[string, int](
+		ttl.WithContext(cancellableCtx),
+		ttl.WithTTL(s.maxTTL),
+		ttl.WithPruneInterval(s.pruneInterval))
+
+	tm.Store("key", 1)
+
+	cancelFunc()
+	tm.CloseWait()
+}
+
+func (s *MapTestSuite) TestWithWriteCoalescing() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](
+		s.maxTTL,
+		s.startSize,
+		s.pruneInterval,
+		refreshOnLoad,
+		ttl.WithWriteCoalescing(time.Minute, func(a, b int) bool {
+			return a == b
+		}))
+	defer tm.Close()
+
+	tm.Store("hot", 1)
+	for i := 0; i < 100; i++ {
+		tm.Store("hot", 1)
+	}
+
+	tm.StoreWithTTL("hotTTL", 1, time.Minute)
+	for i := 0; i < 100; i++ {
+		tm.StoreWithTTL("hotTTL", 1, time.Minute)
+	}
+
+	s.Zero(tm.Stats().Removals.Replaced, "identical stores should be coalesced")
+
+	tm.Store("hot", 2)
+	tm.StoreWithTTL("hotTTL", 1, 2*time.Minute)
+
+	s.Equal(uint64(2), tm.Stats().Removals.Replaced)
+
+	v, _ := tm.Load("hot")
+	s.Equal(2, v)
+
+	remaining, _ := tm.TTL("hotTTL")
+	s.Greater(remaining, time.Minute)
+}
+
+func (s *MapTestSuite) TestWithWriteCoalescingTouches() {
+	refreshOnLoad := false
+	tm := ttl.NewMap[string, int](
+		s.maxTTL,
+		s.startSize,
+		s.pruneInterval,
+		refreshOnLoad,
+		ttl.WithWriteCoalescing(time.Minute, func(a, b int) bool {
+			return a == b
+		}))
+	defer tm.Close()
+
+	tm.Store("hot", 1)
+
+	doneCh := make(chan struct{})
+
+	go func() {
+		for start := time.Now(); time.Since(start) < s.sleepTime; {
+			time.Sleep(50 * time.Millisecond)
+			tm.Store("hot", 1)
+		}
+		close(doneCh)
+	}()
+
+	<-doneCh
+
+	s.Equal(1, tm.Length(), "coalesced stores must still extend the lifetime")
+}
+
+func (s *MapTestSuite) TestWithPruneBatchSize() {
+	const (
+		items     = 10
+		batchSize = 3
+	)
+
+	tm := ttl.New[int, int](
+		ttl.WithTTL(s.maxTTL),
+		ttl.WithPruneInterval(s.pruneInterval),
+		ttl.WithPruneBatchSize(batchSize),
+		ttl.WithLazyExpiry(false))
+	defer tm.Close()
+
+	for i := 0; i < items; i++ {
+		tm.Store(i, i)
+	}
+
+	time.Sleep(s.maxTTL + s.pruneInterval + s.pruneInterval/2)
+
+	// At most two prune passes have run since the items expired
+	s.GreaterOrEqual(tm.Length(), items-2*batchSize)
+
+	time.Sleep(4 * s.pruneInterval)
+
+	s.Zero(tm.Length())
+	s.Equal(uint64(items), tm.Stats().Removals.Expired)
+}
+
+func (s *MapTestSuite) TestWithPruneStrategySampled() {
+	const items = 1000
+
+	tm := ttl.New[int, int](
+		ttl.WithTTL(s.maxTTL),
+		ttl.WithPruneInterval(s.pruneInterval),
+		ttl.WithPruneStrategy(ttl.PruneSampled),
+		ttl.WithLazyExpiry(false))
+	defer tm.Close()
+
+	for i := 0; i < items; i++ {
+		tm.Store(i, i)
+	}
+	tm.StoreWithTTL(items, items, time.Minute)
+
+	time.Sleep(s.sleepTime)
+
+	// All but the long-lived item have expired, so sampling repeats until they are removed
+	s.Equal(1, tm.Length())
+	s.Equal(uint64(items), tm.Stats().Removals.Expired)
+}
+
+func (s *MapTestSuite) TestWithPruneStrategyHeap() {
+	s.testIndexedPruneStrategy(ttl.PruneHeap)
+}
+
+func (s *MapTestSuite) TestWithPruneStrategyTimingWheel() {
+	s.testIndexedPruneStrategy(ttl.PruneTimingWheel)
+}
+
+func (s *MapTestSuite) testIndexedPruneStrategy(strategy ttl.PruneStrategy) {
+	tm := ttl.New[string, int](
+		ttl.WithTTL(s.maxTTL),
+		ttl.WithPruneInterval(s.pruneInterval),
+		ttl.WithPruneStrategy(strategy),
+		ttl.WithLazyExpiry(false))
+	defer tm.Close()
+
+	tm.Store("expires", 1)
+	tm.Store("touched", 2)
+	tm.Store("renamed", 4)
+	tm.Store("pinned", 5)
+	tm.StoreWithTTL("immortal", 6, 0)
+	tm.StoreWithTTL("long", 7, time.Minute)
+
+	s.True(tm.Pin("pinned"))
+	s.True(tm.Rename("renamed", "newName"))
+
+	doneCh := make(chan struct{})
+
+	go func() {
+		for start := time.Now(); time.Since(start) < s.sleepTime; {
+			time.Sleep(s.pruneInterval / 2)
+			tm.Touch("touched")
+		}
+		close(doneCh)
+	}()
+
+	time.Sleep(s.pruneInterval)
+	s.True(tm.SetTTL("long", s.maxTTL))
+
+	<-doneCh
+
+	for _, key := range []string{"expires", "newName", "long"} {
+		_, ok := tm.LoadPassive(key)
+		s.False(ok, key)
+	}
+
+	for _, key := range []string{"touched", "pinned", "immortal"} {
+		_, ok := tm.LoadPassive(key)
+		s.True(ok, key)
+	}
+
+	s.True(tm.Unpin("pinned"))
+	s.True(tm.Expire("immortal"))
+
+	time.Sleep(s.sleepTime)
+
+	s.Zero(tm.Length())
+}
+
+func (s *MapTestSuite) TestWithReadMostly() {
+	tm := ttl.New[string, int](
+		ttl.WithTTL(s.maxTTL),
+		ttl.WithPruneInterval(s.pruneInterval),
+		ttl.WithReadMostly(true))
+	defer tm.Close()
+
+	tm.Store("a", 1)
+	tm.Store("b", 2)
+
+	v, ok := tm.Load("a")
+	s.True(ok)
+	s.Equal(1, v)
+
+	tm.Store("a", 10)
+	tm.Delete("b")
+
+	v, ok = tm.Load("a")
+	s.True(ok)
+	s.Equal(10, v)
+
+	_, ok = tm.Load("b")
+	s.False(ok)
+
+	doneCh := make(chan struct{})
+
+	go func() {
+		for start := time.Now(); time.Since(start) < s.sleepTime; {
+			time.Sleep(s.pruneInterval / 2)
+			tm.Load("a")
+		}
+		close(doneCh)
+	}()
+
+	<-doneCh
+
+	_, ok = tm.LoadPassive("a")
+	s.True(ok, "loads from the read view should refresh the entry")
+
+	time.Sleep(s.sleepTime)
+
+	_, ok = tm.LoadPassive("a")
+	s.False(ok)
+	s.Zero(tm.Length())
+}
+
+func (s *MapTestSuite) TestWithIterationBatchSize() {
+	const items = 100
+
+	tm := ttl.New[int, int](
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(s.pruneInterval),
+		ttl.WithIterationBatchSize(7))
+	defer tm.Close()
+
+	for i := 0; i < items; i++ {
+		tm.Store(i, i)
+	}
+
+	loaded := make(chan struct{})
+
+	visited := 0
+	tm.Range(func(int, int) bool {
+		visited++
+
+		switch {
+		case visited == 1:
+			go func() {
+				tm.Load(0)
+				close(loaded)
+			}()
+
+		case visited <= 7:
+			// Give the load time to block on the first batch
+			time.Sleep(5 * time.Millisecond)
+
+		case visited == 10:
+			select {
+			case <-loaded:
+			case <-time.After(time.Second):
+				s.Fail("load should have completed between batches")
+			}
+		}
+
+		return true
+	})
+	s.Equal(items, visited)
+
+	visited = 0
+	tm.Range(func(int, int) bool {
+		visited++
+		return visited < 20
+	})
+	s.Equal(20, visited)
+
+	tm.DeleteFunc(func(key int, _ int) bool {
+		return key%2 == 0
+	})
+	s.Equal(items/2, tm.Length())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.ErrorIs(tm.RangeContext(ctx, func(int, int) bool { return true }), context.Canceled)
+}
+
+func (s *MapTestSuite) TestWithOnExpire() {
+	var (
+		mtx     sync.Mutex
+		expired = make(map[string]int)
+	)
+
+	var tm *ttl.Map[string, int]
+	tm = ttl.New[string, int](
+		ttl.WithTTL(s.maxTTL),
+		ttl.WithPruneInterval(s.pruneInterval),
+		ttl.WithOnExpire(func(key string, value int) {
+			tm.Length() // the lock is not held
+
+			mtx.Lock()
+			expired[key] = value
+			mtx.Unlock()
+		}))
+	defer tm.Close()
+
+	tm.Store("a", 1)
+	tm.Store("b", 2)
+	tm.Store("deleted", 3)
+	tm.StoreWithTTL("kept", 4, time.Minute)
+	tm.Delete("deleted")
+
+	time.Sleep(s.sleepTime)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	s.Equal(map[string]int{"a": 1, "b": 2}, expired)
+}
+
+func (s *MapTestSuite) TestWithOnRemove() {
+	type removal struct {
+		value  int
+		reason ttl.RemovalReason
+	}
+
+	var (
+		mtx     sync.Mutex
+		removed = make(map[string][]removal)
+	)
+
+	tm := ttl.New[string, int](
+		ttl.WithTTL(s.maxTTL),
+		ttl.WithPruneInterval(s.pruneInterval),
+		ttl.WithOnRemove(func(key string, value int, reason ttl.RemovalReason) {
+			mtx.Lock()
+			removed[key] = append(removed[key], removal{value, reason})
+			mtx.Unlock()
+		}))
+	defer tm.Close()
+
+	tm.Store("expired", 1)
+	tm.StoreWithTTL("deleted", 2, time.Minute)
+	tm.StoreWithTTL("replaced", 3, time.Minute)
+	tm.StoreWithTTL("replaced", 4, time.Minute)
+	tm.Delete("deleted")
+
+	time.Sleep(s.sleepTime)
+
+	tm.StoreWithTTL("cleared", 5, time.Minute)
+	tm.Clear()
+
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	s.Equal(map[string][]removal{
+		"expired":  {{1, ttl.ReasonExpired}},
+		"deleted":  {{2, ttl.ReasonDeleted}},
+		"replaced": {{3, ttl.ReasonReplaced}, {4, ttl.ReasonCleared}},
+		"cleared":  {{5, ttl.ReasonCleared}},
+	}, removed)
+	s.Equal("replaced", ttl.ReasonReplaced.String())
+}
+
+func (s *MapTestSuite) TestWithExpiredChannel() {
+	tm := ttl.New[string, int](
+		ttl.WithTTL(s.maxTTL),
+		ttl.WithPruneInterval(s.pruneInterval),
+		ttl.WithExpiredChannel(2))
+
+	tm.Store("a", 1)
+	tm.Store("b", 2)
+	tm.Store("c", 3)
+	tm.StoreWithTTL("kept", 4, time.Minute)
+
+	time.Sleep(s.sleepTime)
+
+	expired := make(map[string]int)
+	for i := 0; i < 2; i++ {
+		e := <-tm.Expired()
+		expired[e.Key] = e.Value
+	}
+
+	s.Len(expired, 2)
+	s.NotContains(expired, "kept")
+	s.Equal(uint64(1), tm.Stats().ExpiredDropped)
+
+	tm.Close()
+
+	_, ok := <-tm.Expired()
+	s.False(ok)
+
+	disabled := ttl.New[string, int]()
+	defer disabled.Close()
+
+	s.Nil(disabled.Expired())
+}
+
+func (s *MapTestSuite) TestWithMaxEntries() {
+	tm := ttl.New[string, int](
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0),
+		ttl.WithMaxEntries(3))
+	defer tm.Close()
+
+	for i, key := range []string{"a", "b", "c"} {
+		tm.Store(key, i)
+		time.Sleep(time.Millisecond)
+	}
+
+	// a is now the most recently accessed, so b is evicted to make room for d
+	tm.Load("a")
+	tm.Store("d", 3)
+
+	s.Equal(3, tm.Length())
+	s.Equal(uint64(1), tm.Stats().Removals.Evicted)
+
+	_, ok := tm.Load("b")
+	s.False(ok)
+
+	for _, key := range []string{"a", "c", "d"} {
+		_, ok := tm.Load(key)
+		s.True(ok, key)
+	}
+}
+
+func (s *MapTestSuite) TestWithAdmissionPolicyTinyLFU() {
+	const capacity = 10
+
+	var (
+		mtx     sync.Mutex
+		evicted []int
+	)
+
+	tm := ttl.New[int, int](
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0),
+		ttl.WithMaxEntries(capacity),
+		ttl.WithAdmissionPolicy(ttl.AdmitTinyLFU),
+		ttl.WithHasher(func(key int) uint64 { return uint64(key) }),
+		ttl.WithOnRemove(func(key int, _ int, reason ttl.RemovalReason) {
+			mtx.Lock()
+			defer mtx.Unlock()
+
+			s.Equal(ttl.ReasonEvicted, reason)
+			evicted = append(evicted, key)
+		}))
+	defer tm.Close()
+
+	for key := 0; key < capacity; key++ {
+		tm.Store(key, key)
+		for i := 0; i < 5; i++ {
+			tm.Load(key)
+		}
+	}
+
+	// Keys that are used once are not admitted in place of frequently used keys
+	for key := capacity; key < 3*capacity; key++ {
+		tm.Store(key, key)
+	}
+
+	s.Equal(capacity, tm.Length())
+
+	for key := 0; key < capacity; key++ {
+		_, ok := tm.Load(key)
+		s.True(ok, key)
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	s.Len(evicted, 2*capacity)
+}
+
+func (s *MapTestSuite) TestWithMaxCost() {
+	tm := ttl.New[string, string](
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0),
+		ttl.WithMaxCost(10, func(_ string, value string) int64 { return int64(len(value)) }))
+	defer tm.Close()
+
+	tm.Store("a", "aaaa")
+	time.Sleep(time.Millisecond)
+	tm.Store("b", "bbbb")
+	s.Equal(int64(8), tm.Stats().Cost)
+
+	// Replacing a value updates its cost
+	tm.Store("b", "bb")
+	s.Equal(int64(6), tm.Stats().Cost)
+
+	// a is the least recently accessed, so it is evicted to make room for c
+	tm.Store("c", "cccccc")
+	s.Equal(int64(8), tm.Stats().Cost)
+	s.Equal(2, tm.Length())
+
+	_, ok := tm.Load("a")
+	s.False(ok)
+
+	// A value that exceeds the budget on its own is evicted immediately
+	tm.Store("d", "ddddddddddd")
+
+	_, ok = tm.Load("d")
+	s.False(ok)
+	s.LessOrEqual(tm.Stats().Cost, int64(10))
+
+	tm.Delete("b")
+	tm.Delete("c")
+	s.Equal(int64(0), tm.Stats().Cost)
+}
+
+func (s *MapTestSuite) TestWithLogger() {
+	var (
+		mtx sync.Mutex
+		buf bytes.Buffer
+	)
+
+	logger := slog.New(slog.NewTextHandler(writerFunc(func(p []byte) (int, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		return buf.Write(p)
+	}), &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	tm := ttl.New[string, int](
+		ttl.WithTTL(s.maxTTL),
+		ttl.WithPruneInterval(s.pruneInterval),
+		ttl.WithMaxEntries(1),
+		ttl.WithPruneBatchSize(10),
+		ttl.WithPruneStrategy(ttl.PruneHeap),
+		ttl.WithLogger(logger),
+		ttl.WithOnRemove(func(key string, _ int, _ ttl.RemovalReason) {
+			panic("callback failed for " + key)
+		}))
+	defer tm.Close()
+
+	tm.Store("a", 1)
+	tm.Store("b", 2) // evicts a, and the callback's panic is recovered
+
+	time.Sleep(s.sleepTime)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	logged := buf.String()
+	s.NotContains(logged, "the prune batch size has no effect")
+	s.Contains(logged, `level=DEBUG msg="evicted entries to stay within capacity" evicted=1`)
+	s.Contains(logged, `level=ERROR msg="removal callback panicked" key=a reason=evicted panic="callback failed for a"`)
+	s.Contains(logged, `level=DEBUG msg="prune pass" pruned=1`)
+
+	// Only sampled pruning ignores the prune batch size
+	var sampledBuf bytes.Buffer
+
+	sampled := ttl.New[string, int](
+		ttl.WithPruneBatchSize(10),
+		ttl.WithPruneStrategy(ttl.PruneSampled),
+		ttl.WithLogger(slog.New(slog.NewTextHandler(&sampledBuf, nil))))
+	sampled.Close()
+
+	s.Contains(sampledBuf.String(),
+		`level=WARN msg="the prune batch size has no effect with the prune strategy" strategy=PruneSampled`)
+}
+
+// writerFunc adapts a function to an io.Writer.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func (s *MapTestSuite) TestWithClock() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	tm := ttl.New[string, int](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(time.Second))
+	defer tm.Close()
+
+	tm.Store("a", 1)
+	tm.StoreWithTTL("b", 2, time.Hour)
+
+	clock.Advance(30 * time.Second)
+	_, ok := tm.Load("a") // refreshes a
+	s.True(ok)
+
+	expireAt, _ := tm.ExpirationTime("a")
+	s.Equal(clock.Now().Add(time.Minute), expireAt)
+
+	clock.Advance(45 * time.Second)
+	_, ok = tm.LoadPassive("a")
+	s.True(ok)
+
+	clock.Advance(16 * time.Second)
+	_, ok = tm.LoadPassive("a")
+	s.False(ok)
+
+	// The tick from advancing the clock prunes a in the background
+	s.Eventually(func() bool {
+		return tm.Length() == 1
+	}, time.Second, time.Millisecond)
+
+	clock.Advance(time.Hour)
+	s.Equal(1, tm.Prune())
+	s.Zero(tm.Length())
+}
+
+// tickerRecorder is a fake clock that records the intervals of the tickers it creates.
+type tickerRecorder struct {
+	*ttltest.Clock
+
+	mtx       sync.Mutex
+	intervals []time.Duration
+}
+
+func (r *tickerRecorder) NewTicker(d time.Duration) ttl.Ticker {
+	r.mtx.Lock()
+	r.intervals = append(r.intervals, d)
+	r.mtx.Unlock()
+
+	return r.Clock.NewTicker(d)
+}
+
+func (s *MapTestSuite) TestWithPruneJitter() {
+	clock := &tickerRecorder{Clock: ttltest.NewClock(time.Now())}
+
+	for i := 0; i < 20; i++ {
+		tm := ttl.New[string, int](
+			ttl.WithClock(clock),
+			ttl.WithPruneInterval(time.Second),
+			ttl.WithPruneJitter(0.1))
+		tm.Close()
+	}
+
+	distinct := make(map[time.Duration]bool)
+	for _, interval := range clock.intervals {
+		s.GreaterOrEqual(interval, 900*time.Millisecond)
+		s.LessOrEqual(interval, 1100*time.Millisecond)
+		distinct[interval] = true
+	}
+
+	s.Greater(len(distinct), 1)
+}
+
+func (s *MapTestSuite) TestWithTTLJitter() {
+	clock := ttltest.NewClock(time.Now())
+
+	tm := ttl.New[int, int](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0),
+		ttl.WithTTLJitter(0.1))
+	defer tm.Close()
+
+	for i := 0; i < 100; i++ {
+		if i%2 == 0 {
+			tm.Store(i, i)
+		} else {
+			tm.StoreWithTTL(i, i, time.Minute)
+		}
+	}
+
+	tm.StoreWithTTL(-1, -1, 0)
+
+	distinct := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		remaining, ok := tm.TTL(i)
+		s.True(ok)
+		s.GreaterOrEqual(remaining, 54*time.Second)
+		s.LessOrEqual(remaining, 66*time.Second)
+		distinct[remaining] = true
+	}
+
+	s.Greater(len(distinct), 50)
+
+	remaining, _ := tm.TTL(-1)
+	s.Equal(ttl.NoExpiry, remaining)
+}
+
+func (s *MapTestSuite) TestWithWriteThrough() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+	store := ttltest.NewStore[string, int](clock)
+
+	tm := ttl.New[string, int](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0),
+		ttl.WithMaxEntries(2),
+		ttl.WithWriteThrough[string, int](store))
+	defer tm.Close()
+
+	tm.Store("a", 1)
+	clock.Advance(time.Second)
+	tm.StoreWithTTL("b", 2, time.Hour)
+	tm.StoreWithTTL("c", 3, 0)
+
+	// Evicting a from the Map leaves it in the store
+	s.Equal(2, tm.Length())
+	s.Equal(3, store.Len())
+
+	_, TTL, ok, err := store.Get(context.Background(), "b")
+	s.NoError(err)
+	s.True(ok)
+	s.Equal(time.Hour, TTL)
+
+	_, TTL, _, _ = store.Get(context.Background(), "c")
+	s.Zero(TTL)
+
+	tm.Rename("b", "d")
+	_, _, ok, _ = store.Get(context.Background(), "b")
+	s.False(ok)
+	v, _, _, _ := store.Get(context.Background(), "d")
+	s.Equal(2, v)
+
+	tm.Delete("c")
+	tm.Clear()
+	s.Equal(1, store.Len()) // a
+
+	clock.Advance(time.Minute)
+	s.Zero(store.Len())
+}
+
+func (s *MapTestSuite) TestWarm() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+	store := ttltest.NewStore[int, int](clock)
+
+	for i := 0; i < 2000; i++ {
+		s.Require().NoError(store.Set(context.Background(), i, i, time.Duration(i+1)*time.Second))
+	}
+
+	var writes atomic.Int64
+	counting := &countingStore[int, int]{RangeStore: store, sets: &writes}
+
+	tm := ttl.New[int, int](
+		ttl.WithClock(clock),
+		ttl.WithPruneInterval(0),
+		ttl.WithWriteThrough[int, int](counting))
+	defer tm.Close()
+
+	s.NoError(tm.Warm(context.Background(), counting))
+	s.Equal(2000, tm.Length())
+	s.Zero(writes.Load())
+
+	remaining, _ := tm.TTL(99)
+	s.Equal(100*time.Second, remaining)
+
+	store.Fail(errors.New("unavailable"))
+	s.Error(tm.Warm(context.Background(), counting))
+}
+
+// countingStore is a ttl.RangeStore that counts the values set in it.
+type countingStore[K comparable, V any] struct {
+	ttl.RangeStore[K, V]
+	sets *atomic.Int64
+}
+
+func (c *countingStore[K, V]) Set(ctx context.Context, key K, value V, TTL time.Duration) error {
+	c.sets.Add(1)
+	return c.RangeStore.Set(ctx, key, value, TTL)
+}
+
+func (s *MapTestSuite) TestWithReadThrough() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+	store := ttltest.NewStore[string, int](clock)
+	s.Require().NoError(store.Set(context.Background(), "a", 1, time.Hour))
+
+	var writes atomic.Int64
+	tm := ttl.New[string, int](
+		ttl.WithClock(clock),
+		ttl.WithPruneInterval(0),
+		ttl.WithStats(true),
+		ttl.WithReadThrough[string, int](store),
+		ttl.WithWriteThrough[string, int](&countingStore[string, int]{RangeStore: store, sets: &writes}))
+	defer tm.Close()
+
+	clock.Advance(time.Minute)
+
+	v, ok := tm.Load("a")
+	s.True(ok)
+	s.Equal(1, v)
+	s.Zero(writes.Load())
+
+	remaining, _ := tm.TTL("a")
+	s.Equal(59*time.Minute, remaining)
+
+	_, ok = tm.Load("a")
+	s.True(ok)
+
+	_, ok = tm.LoadPassive("b")
+	s.False(ok)
+
+	stats := tm.Stats()
+	s.Equal(uint64(1), stats.Hits)
+	s.Equal(uint64(2), stats.Misses)
+
+	store.Fail(errors.New("unavailable"))
+	_, ok = tm.Load("c")
+	s.False(ok)
+}
+
+func (s *MapTestSuite) TestLoadContext() {
+	var reads atomic.Int64
+	release := make(chan struct{})
+	unavailable := errors.New("unavailable")
+
+	loader := ttl.LoaderFunc[string, int](func(ctx context.Context, key string) (int, time.Duration, bool, error) {
+		reads.Add(1)
+		<-release
+
+		switch key {
+		case "missing":
+			return 0, 0, false, nil
+		case "failing":
+			return 0, 0, false, unavailable
+		}
+
+		return len(key), time.Hour, true, nil
+	})
+
+	tm := ttl.NewShardedMap[string, int](
+		ttl.WithPruneInterval(0),
+		ttl.WithReadThrough[string, int](loader))
+	defer tm.Close()
+
+	// Concurrent misses of the same key share a single read
+	var started, wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		started.Add(1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started.Done()
+
+			v, ok, err := tm.LoadContext(context.Background(), "abc")
+			s.NoError(err)
+			s.True(ok)
+			s.Equal(3, v)
+		}()
+	}
+
+	started.Wait()
+	s.Eventually(func() bool { return reads.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	s.Equal(int64(1), reads.Load())
+	v, ok := tm.LoadPassive("abc")
+	s.True(ok)
+	s.Equal(3, v)
+
+	_, ok, err := tm.LoadContext(context.Background(), "missing")
+	s.NoError(err)
+	s.False(ok)
+
+	_, ok, err = tm.LoadContext(context.Background(), "failing")
+	s.ErrorIs(err, unavailable)
+	s.False(ok)
+
+	_, ok = tm.Load("failing")
+	s.False(ok)
+}
+
+func (s *MapTestSuite) TestWithWriteBehind() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+	store := ttltest.NewStore[int, int](clock)
+
+	var writes atomic.Int64
+	tm := ttl.New[int, int](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Hour),
+		ttl.WithPruneInterval(0),
+		ttl.WithWriteBehind[int, int](&countingStore[int, int]{RangeStore: store, sets: &writes}, time.Minute))
+
+	for i := 0; i < 10; i++ {
+		tm.Store(1, i)
+		tm.Store(i+2, i)
+	}
+	tm.Delete(2)
+
+	s.Zero(store.Len())
+
+	// Only the last write to each key is made when the tick from advancing the clock is received
+	clock.Advance(time.Minute)
+	s.Eventually(func() bool {
+		return store.Len() == 10
+	}, time.Second, time.Millisecond)
+	s.Equal(int64(10), writes.Load())
+
+	v, _, _, _ := store.Get(context.Background(), 1)
+	s.Equal(9, v)
+
+	tm.Store(1, 10)
+	tm.CloseWait()
+
+	v, _, _, _ = store.Get(context.Background(), 1)
+	s.Equal(10, v)
+
+	// Writes after closing are made immediately
+	tm.Delete(1)
+	s.Equal(9, store.Len())
+}
+
+func (s *MapTestSuite) TestWithWriteBehindFullQueue() {
+	store := ttltest.NewStore[int, int](ttltest.NewClock(time.Now()))
+
+	tm := ttl.NewShardedMap[int, int](
+		ttl.WithTTL(time.Hour),
+		ttl.WithWriteBehind[int, int](store, time.Hour))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < ttl.WriteBehindQueue; i++ {
+				tm.Store(g*ttl.WriteBehindQueue+i, i)
+			}
+		}(g)
+	}
+
+	wg.Wait()
+	tm.CloseWait()
+
+	s.Equal(4*ttl.WriteBehindQueue, store.Len())
+}
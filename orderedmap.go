@@ -0,0 +1,316 @@
+package ttl
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OrderedMap is a "time-to-live" map like [Map] that remembers the order in which its keys were
+// inserted, so that [OrderedMap.Range] and [OrderedMap.Keys] visit them in that order (for example,
+// for serialization or listing in a user interface). Storing a key that is already present
+// replaces its value without changing its position.
+//
+// An OrderedMap is configured using the same [Option]s as a [Map]. [WithContext], [WithTTL],
+// [WithCapacity], [WithPruneInterval], [WithPruneJitter], [WithRefreshOnLoad] and [WithClock]
+// apply; other options are ignored. Expired entries are never visible, whether or not they have
+// been pruned.
+//
+// OrderedMap is safe for concurrent use.
+type OrderedMap[K comparable, V any] struct {
+	clock         Clock
+	defaultTTL    time.Duration
+	refreshOnLoad bool
+
+	mtx   sync.RWMutex
+	m     map[K]*list.Element // holding an *orderedEntry[K, V]
+	order *list.List          // of the entries, in insertion order
+
+	bg background
+}
+
+type orderedEntry[K comparable, V any] struct {
+	key        K
+	value      V
+	TTL        time.Duration // zero or negative if the entry never expires
+	lastAccess atomic.Int64  // in Unix nanoseconds
+}
+
+// expired reports whether the entry has expired as of now, in Unix nanoseconds.
+func (e *orderedEntry[K, V]) expired(now int64) bool {
+	return e.TTL > 0 && e.TTL <= time.Duration(now-e.lastAccess.Load())
+}
+
+// NewOrderedMap returns a new, empty [OrderedMap] configured by opts, like [New].
+func NewOrderedMap[K comparable, V any](opts ...Option) *OrderedMap[K, V] {
+	o := defaultOptions()
+	o.apply(opts)
+	o.logMisconfiguration()
+
+	om := &OrderedMap[K, V]{
+		clock:         o.clock,
+		defaultTTL:    o.ttl,
+		refreshOnLoad: o.refreshOnLoad,
+		m:             make(map[K]*list.Element, max(o.capacity, 0)),
+		order:         list.New(),
+		bg:            newBackground(),
+	}
+
+	om.bg.start(o, func(now time.Time) {
+		om.prune(now)
+	})
+
+	return om
+}
+
+// Store inserts a value into the [OrderedMap] with the default time to live. If the key is already
+// present, its value is replaced and its last access time updated, but its position and TTL are
+// unchanged. Store is safe for concurrent use.
+func (om *OrderedMap[K, V]) Store(key K, value V) {
+	now := om.clock.Now().UnixNano()
+
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+
+	if e, ok := om.liveEntryLocked(key, now); ok {
+		e.value = value
+		e.lastAccess.Store(now)
+		return
+	}
+
+	om.insertLocked(key, value, om.defaultTTL, now)
+}
+
+// StoreWithTTL inserts a value into the [OrderedMap] with a custom time to live. A zero or negative
+// TTL means the entry never expires. If the key is already present, its value and TTL are replaced
+// and its last access time updated, but its position is unchanged. StoreWithTTL is safe for
+// concurrent use.
+func (om *OrderedMap[K, V]) StoreWithTTL(key K, value V, TTL time.Duration) {
+	now := om.clock.Now().UnixNano()
+
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+
+	if e, ok := om.liveEntryLocked(key, now); ok {
+		e.value, e.TTL = value, TTL
+		e.lastAccess.Store(now)
+		return
+	}
+
+	om.insertLocked(key, value, TTL, now)
+}
+
+// Load retrieves a value from the [OrderedMap], as well as a bool indicating whether the key was
+// found. If the [OrderedMap] refreshes on load, the last access time of the key is updated. Load is
+// safe for concurrent use.
+func (om *OrderedMap[K, V]) Load(key K) (value V, ok bool) {
+	return om.load(key, om.refreshOnLoad)
+}
+
+// LoadPassive is like [OrderedMap.Load], but never updates the last access time of the key.
+// LoadPassive is safe for concurrent use.
+func (om *OrderedMap[K, V]) LoadPassive(key K) (value V, ok bool) {
+	return om.load(key, false)
+}
+
+// TTL returns the time to live remaining for key, as well as a bool indicating whether the key was
+// found. If the key never expires, the remaining time returned is [NoExpiry]. TTL is safe for
+// concurrent use.
+func (om *OrderedMap[K, V]) TTL(key K) (remaining time.Duration, ok bool) {
+	now := om.clock.Now().UnixNano()
+
+	om.mtx.RLock()
+	defer om.mtx.RUnlock()
+
+	e, ok := om.liveEntryLocked(key, now)
+	switch {
+	case !ok:
+		return 0, false
+	case e.TTL <= 0:
+		return NoExpiry, true
+	default:
+		return e.TTL - time.Duration(now-e.lastAccess.Load()), true
+	}
+}
+
+// Delete removes key from the [OrderedMap]. Delete is safe for concurrent use.
+func (om *OrderedMap[K, V]) Delete(key K) {
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+
+	if el, ok := om.m[key]; ok {
+		om.removeLocked(el)
+	}
+}
+
+// Clear removes every entry from the [OrderedMap]. Clear is safe for concurrent use.
+func (om *OrderedMap[K, V]) Clear() {
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+
+	clear(om.m)
+	om.order.Init()
+}
+
+// Length returns the number of entries in the [OrderedMap], including any that have expired but
+// have not been pruned yet. Length is safe for concurrent use.
+func (om *OrderedMap[K, V]) Length() int {
+	om.mtx.RLock()
+	defer om.mtx.RUnlock()
+
+	return len(om.m)
+}
+
+// Keys returns the unexpired keys of the [OrderedMap] in insertion order. Keys is safe for
+// concurrent use.
+func (om *OrderedMap[K, V]) Keys() []K {
+	now := om.clock.Now().UnixNano()
+
+	om.mtx.RLock()
+	defer om.mtx.RUnlock()
+
+	keys := make([]K, 0, len(om.m))
+	for el := om.order.Front(); el != nil; el = el.Next() {
+		if e := el.Value.(*orderedEntry[K, V]); !e.expired(now) {
+			keys = append(keys, e.key)
+		}
+	}
+
+	return keys
+}
+
+// Range calls f sequentially for each unexpired key and value in insertion order. If f returns
+// false, Range stops the iteration. Range iterates over a copy of the entries taken when it is
+// called, so f may modify the [OrderedMap]. Range does not update the last access time of any key.
+// Range is safe for concurrent use.
+func (om *OrderedMap[K, V]) Range(f func(key K, value V) bool) {
+	_ = om.RangeContext(context.Background(), f)
+}
+
+// RangeContext is like [OrderedMap.Range], but stops and returns ctx.Err() if ctx is done before
+// the iteration completes.
+func (om *OrderedMap[K, V]) RangeContext(ctx context.Context, f func(key K, value V) bool) error {
+	type entry struct {
+		key   K
+		value V
+	}
+
+	now := om.clock.Now().UnixNano()
+
+	om.mtx.RLock()
+
+	entries := make([]entry, 0, len(om.m))
+	for el := om.order.Front(); el != nil; el = el.Next() {
+		if e := el.Value.(*orderedEntry[K, V]); !e.expired(now) {
+			entries = append(entries, entry{e.key, e.value})
+		}
+	}
+
+	om.mtx.RUnlock()
+
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !f(e.key, e.value) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Prune removes the expired entries from the [OrderedMap], returning the number removed. Prune is
+// safe for concurrent use.
+func (om *OrderedMap[K, V]) Prune() int {
+	return om.prune(om.clock.Now())
+}
+
+// Close stops the [OrderedMap] from pruning in the background. If Close is not called on an
+// [OrderedMap] after it's no longer needed, its prune goroutine will leak (unless the context has
+// been cancelled). Close may be called multiple times.
+func (om *OrderedMap[K, V]) Close() {
+	om.bg.close()
+}
+
+// CloseWait is like [OrderedMap.Close], but also waits for the prune goroutine to exit.
+func (om *OrderedMap[K, V]) CloseWait() {
+	om.bg.closeWait()
+}
+
+func (om *OrderedMap[K, V]) load(key K, update bool) (value V, ok bool) {
+	now := om.clock.Now().UnixNano()
+
+	om.mtx.RLock()
+	defer om.mtx.RUnlock()
+
+	e, ok := om.liveEntryLocked(key, now)
+	if !ok {
+		return
+	}
+
+	if update {
+		e.lastAccess.Store(now)
+	}
+
+	return e.value, true
+}
+
+// prune removes the entries that have expired as of now, returning the number removed.
+func (om *OrderedMap[K, V]) prune(now time.Time) (pruned int) {
+	nowNano := now.UnixNano()
+
+	om.mtx.Lock()
+	defer om.mtx.Unlock()
+
+	for el := om.order.Front(); el != nil; {
+		next := el.Next()
+
+		if el.Value.(*orderedEntry[K, V]).expired(nowNano) {
+			om.removeLocked(el)
+			pruned++
+		}
+
+		el = next
+	}
+
+	return
+}
+
+// liveEntryLocked returns the entry of key if it has not expired as of now, in Unix nanoseconds.
+// The caller must hold the lock.
+func (om *OrderedMap[K, V]) liveEntryLocked(key K, now int64) (*orderedEntry[K, V], bool) {
+	el, ok := om.m[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*orderedEntry[K, V])
+	if e.expired(now) {
+		return nil, false
+	}
+
+	return e, true
+}
+
+// insertLocked adds key to the end of the order, replacing any expired entry of the key. The
+// caller must hold the write lock.
+func (om *OrderedMap[K, V]) insertLocked(key K, value V, TTL time.Duration, now int64) {
+	if el, ok := om.m[key]; ok {
+		om.removeLocked(el)
+	}
+
+	e := &orderedEntry[K, V]{key: key, value: value, TTL: TTL}
+	e.lastAccess.Store(now)
+
+	om.m[key] = om.order.PushBack(e)
+}
+
+// removeLocked removes the entry held by el. The caller must hold the write lock.
+func (om *OrderedMap[K, V]) removeLocked(el *list.Element) {
+	delete(om.m, el.Value.(*orderedEntry[K, V]).key)
+	om.order.Remove(el)
+}
@@ -0,0 +1,65 @@
+package ttl_test
+
+import (
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestOrderedMap() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	om := ttl.NewOrderedMap[string, int](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0))
+	defer om.Close()
+
+	for i, key := range []string{"c", "a", "d", "b"} {
+		om.Store(key, i)
+	}
+	om.StoreWithTTL("e", 4, 10*time.Second)
+
+	s.Equal([]string{"c", "a", "d", "b", "e"}, om.Keys())
+
+	// Storing an existing key keeps its position
+	om.Store("a", 10)
+	v, ok := om.Load("a")
+	s.True(ok)
+	s.Equal(10, v)
+	s.Equal([]string{"c", "a", "d", "b", "e"}, om.Keys())
+
+	om.Delete("d")
+
+	clock.Advance(10 * time.Second)
+	var keys []string
+	var values []int
+	om.Range(func(key string, value int) bool {
+		keys = append(keys, key)
+		values = append(values, value)
+		return true
+	})
+	s.Equal([]string{"c", "a", "b"}, keys)
+	s.Equal([]int{0, 10, 3}, values)
+
+	// An expired key that is stored again moves to the end
+	om.Store("e", 5)
+	s.Equal([]string{"c", "a", "b", "e"}, om.Keys())
+
+	// Loading a key refreshes it
+	clock.Advance(40 * time.Second)
+	_, ok = om.Load("c")
+	s.True(ok)
+
+	clock.Advance(20 * time.Second)
+	s.Equal([]string{"c"}, om.Keys())
+	s.Equal(3, om.Prune())
+
+	remaining, ok := om.TTL("c")
+	s.True(ok)
+	s.Equal(40*time.Second, remaining)
+
+	om.Clear()
+	s.Zero(om.Length())
+}
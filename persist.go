@@ -0,0 +1,159 @@
+package ttl
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrSnapshotFormat is the error wrapped by [Map.LoadFrom] and [Map.LoadFile] when their input is
+// not a snapshot written by [Map.SaveTo], or was written in an unsupported version of the format.
+var ErrSnapshotFormat = errors.New("ttl: invalid snapshot format")
+
+const (
+	// snapshotMagic identifies the binary snapshot format written by SaveTo.
+	snapshotMagic = "TTLS"
+
+	// snapshotVersion is the version of the binary snapshot format written by SaveTo.
+	snapshotVersion = 1
+
+	// loadBatch is the number of entries LoadFrom decodes before restoring them into the Map.
+	loadBatch = 1024
+)
+
+// SaveTo writes the unexpired entries of the [Map] to w in a compact, versioned binary format,
+// along with the time to live each has remaining, so that they can be restored with
+// [Map.LoadFrom]. Entries are encoded and written one at a time, so the encoding of the whole
+// [Map] is never held in memory. Keys and values are encoded using encoding/gob, so interface
+// types held in them must be registered with [gob.Register].
+//
+// Like [Map.Snapshot], SaveTo does not update the last access time of any entry. The [Map] is not
+// locked while writing to w.
+func (m *Map[K, V]) SaveTo(w io.Writer) error {
+	return saveSnapshot(w, m.Snapshot())
+}
+
+// LoadFrom reads a snapshot written by [Map.SaveTo] from r and stores its entries in the [Map],
+// each with the time to live it had remaining when it was saved, counted from the time of the
+// call. Loaded entries replace any existing entries with the same key, and entries that had
+// expired are skipped. Entries are restored in batches as they are decoded, so a large snapshot
+// is never held in memory in full.
+//
+// Each entry resumes with the time to live it had remaining, however long ago the snapshot was
+// saved; use [Map.LoadFile] to count that time against it.
+//
+// LoadFrom returns an error wrapping [ErrSnapshotFormat] if r does not hold a snapshot. If it
+// returns any other error, the entries decoded before the error may have been stored. LoadFrom
+// may read past the end of the snapshot in r.
+func (m *Map[K, V]) LoadFrom(r io.Reader) error {
+	return loadSnapshot(r, m.clock.Now(), false, func(s Snapshot[K, V]) {
+		m.Restore(s, RebasePolicy{Mode: RebaseResume})
+	})
+}
+
+// SaveTo is like [Map.SaveTo].
+func (s *ShardedMap[K, V]) SaveTo(w io.Writer) error {
+	return saveSnapshot(w, s.Snapshot())
+}
+
+// LoadFrom is like [Map.LoadFrom].
+func (s *ShardedMap[K, V]) LoadFrom(r io.Reader) error {
+	return loadSnapshot(r, s.shards[0].clock.Now(), false, func(snapshot Snapshot[K, V]) {
+		s.Restore(snapshot, RebasePolicy{Mode: RebaseResume})
+	})
+}
+
+// saveSnapshot writes s to w: the magic number, the format version, the number of entries and the
+// time the snapshot was taken in Unix nanoseconds, followed by each entry as a gob-encoded
+// encodedEntry.
+func saveSnapshot[K comparable, V any](w io.Writer, s Snapshot[K, V]) error {
+	header := append([]byte(snapshotMagic), snapshotVersion)
+	header = binary.AppendUvarint(header, uint64(len(s.Entries)))
+	header = binary.AppendVarint(header, s.Taken.UnixNano())
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	enc := gob.NewEncoder(w)
+	for _, e := range s.Entries {
+		ee := encodeEntry(e, s.Taken)
+		if err := enc.Encode(&ee); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadSnapshot reads a snapshot written by saveSnapshot from r, passing its entries to restore in
+// batches, each as a Snapshot taken at now. If elapse is true, the time between when the snapshot
+// was taken and now is counted against each entry's time to live; otherwise, each entry resumes
+// with the time to live it had remaining.
+func loadSnapshot[K comparable, V any](
+	r io.Reader,
+	now time.Time,
+	elapse bool,
+	restore func(Snapshot[K, V]),
+) error {
+	br := bufio.NewReader(r)
+
+	var header [len(snapshotMagic) + 1]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return fmt.Errorf("%w: reading header: %w", ErrSnapshotFormat, err)
+	}
+
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return fmt.Errorf("%w: not a ttl snapshot", ErrSnapshotFormat)
+	}
+
+	if version := header[len(snapshotMagic)]; version != snapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrSnapshotFormat, version)
+	}
+
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return fmt.Errorf("%w: reading entry count: %w", ErrSnapshotFormat, err)
+	}
+
+	takenNano, err := binary.ReadVarint(br)
+	if err != nil {
+		return fmt.Errorf("%w: reading snapshot time: %w", ErrSnapshotFormat, err)
+	}
+
+	base := now
+	if elapse {
+		base = time.Unix(0, takenNano)
+	}
+
+	dec := gob.NewDecoder(br)
+	batch := Snapshot[K, V]{Taken: now}
+
+	for ; n > 0; n-- {
+		// Decode into a new entry each time, as gob does not overwrite fields with zero values
+		var ee encodedEntry[K, V]
+		if err := dec.Decode(&ee); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+
+		batch.Entries = append(batch.Entries, decodeEntry(ee, base))
+
+		if len(batch.Entries) == loadBatch {
+			restore(batch)
+			batch.Entries = batch.Entries[:0]
+		}
+	}
+
+	if len(batch.Entries) > 0 {
+		restore(batch)
+	}
+
+	return nil
+}
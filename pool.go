@@ -0,0 +1,207 @@
+package ttl
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Pool holds idle objects for reuse, such as network connections, and destroys those that are not
+// reused within their time to live by passing them to the callback set using [WithOnDrop], which
+// should release them (for example, by closing the connection). [Pool.Get] returns the most
+// recently put object, so that objects beyond those needed stay idle and are destroyed.
+//
+// A Pool is configured using the same [Option]s as a [Map]. [WithContext], [WithTTL] (the idle
+// timeout), [WithMaxEntries] (the most idle objects held), [WithPruneInterval], [WithPruneJitter],
+// [WithClock] and [WithOnDrop] apply; other options are ignored.
+//
+// Pool is safe for concurrent use.
+type Pool[T any] struct {
+	clock      Clock
+	defaultTTL time.Duration
+	maxIdle    int
+	onDrop     func(T)
+
+	mtx         sync.Mutex
+	seq         sequence[T]
+	closed      bool
+	stopContext func() bool // unregisters Close from the context given with WithContext
+
+	bg background
+}
+
+// NewPool returns a new, empty [Pool] configured by opts, like [New].
+func NewPool[T any](opts ...Option) *Pool[T] {
+	o := defaultOptions()
+	o.apply(opts)
+	o.logMisconfiguration()
+
+	p := &Pool[T]{
+		clock:      o.clock,
+		defaultTTL: o.ttl,
+		maxIdle:    o.maxEntries,
+		onDrop:     typedOption[func(T)]("WithOnDrop", o.onDrop),
+		seq:        newSequence[T](),
+		bg:         newBackground(),
+	}
+
+	p.bg.start(o, func(now time.Time) {
+		p.prune(now)
+	})
+
+	// The idle objects are destroyed when the context is done, not only when pruning stops
+	p.mtx.Lock()
+	p.stopContext = context.AfterFunc(o.ctx, p.Close)
+	p.mtx.Unlock()
+
+	return p
+}
+
+// Get removes and returns the most recently put idle object, as well as a bool indicating whether
+// there was one. Objects that have been idle for longer than their TTL are destroyed instead of
+// being returned. Get is safe for concurrent use.
+func (p *Pool[T]) Get() (x T, ok bool) {
+	// Destroyed objects are passed to the callback after the lock is released by the deferred
+	// Unlock below
+	var destroyed []T
+	defer func() {
+		p.destroy(destroyed)
+	}()
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	now := p.clock.Now().UnixNano()
+
+	for len(p.seq.elements) > 0 {
+		e := p.seq.popBack()
+		if !e.expired(now) {
+			return e.value, true
+		}
+
+		destroyed = append(destroyed, e.value)
+	}
+
+	return
+}
+
+// Put returns x to the [Pool] to be reused, idle for up to the default time to live. If the [Pool]
+// already holds as many idle objects as allowed by [WithMaxEntries], the one idle the longest is
+// destroyed. If the [Pool] has been closed, x is destroyed. Put is safe for concurrent use.
+func (p *Pool[T]) Put(x T) {
+	p.PutWithTTL(x, p.defaultTTL)
+}
+
+// PutWithTTL is like [Pool.Put], with a custom time to live. A zero or negative TTL means x is
+// never destroyed for being idle. PutWithTTL is safe for concurrent use.
+func (p *Pool[T]) PutWithTTL(x T, TTL time.Duration) {
+	e := newSequenceElement(x, p.clock.Now(), TTL)
+
+	var destroyed []T
+	defer func() {
+		p.destroy(destroyed)
+	}()
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.closed {
+		destroyed = append(destroyed, x)
+		return
+	}
+
+	if p.maxIdle > 0 && len(p.seq.elements) >= p.maxIdle {
+		destroyed = append(destroyed, p.seq.pop().value)
+	}
+
+	p.seq.push(e)
+}
+
+// Length returns the number of idle objects in the [Pool], including any that have been idle for
+// longer than their TTL but have not been destroyed yet. Length is safe for concurrent use.
+func (p *Pool[T]) Length() int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	return len(p.seq.elements)
+}
+
+// Prune destroys the objects that have been idle for longer than their TTL, returning the number
+// destroyed. Prune is safe for concurrent use.
+func (p *Pool[T]) Prune() int {
+	return p.prune(p.clock.Now())
+}
+
+// Drain destroys every idle object. Drain is safe for concurrent use.
+func (p *Pool[T]) Drain() {
+	p.mtx.Lock()
+	destroyed := p.drainLocked()
+	p.mtx.Unlock()
+
+	p.destroy(destroyed)
+}
+
+// Close stops the [Pool] from pruning in the background and destroys every idle object. Objects
+// put after Close are destroyed immediately. Close is called when the context set using
+// [WithContext] is done. If Close is not called on a [Pool] after it's no longer needed, its prune
+// goroutine and idle objects will leak. Close may be called multiple times.
+func (p *Pool[T]) Close() {
+	p.bg.close()
+
+	p.mtx.Lock()
+	p.closed = true
+	destroyed := p.drainLocked()
+	stopContext := p.stopContext
+	p.stopContext = nil
+	p.mtx.Unlock()
+
+	// Otherwise the Pool would stay reachable from the context until it is done
+	if stopContext != nil {
+		stopContext()
+	}
+
+	p.destroy(destroyed)
+}
+
+// CloseWait is like [Pool.Close], but also waits for the prune goroutine to exit.
+func (p *Pool[T]) CloseWait() {
+	p.Close()
+	p.bg.closeWait()
+}
+
+// prune destroys the objects that have expired as of now, returning the number destroyed.
+func (p *Pool[T]) prune(now time.Time) int {
+	var destroyed []T
+
+	p.mtx.Lock()
+	pruned := p.seq.prune(now.UnixNano(), &destroyed)
+	p.mtx.Unlock()
+
+	p.destroy(destroyed)
+
+	return pruned
+}
+
+// drainLocked removes every idle object, returning them. The caller must hold the lock.
+func (p *Pool[T]) drainLocked() []T {
+	drained := make([]T, len(p.seq.elements))
+	for i, e := range p.seq.elements {
+		drained[i] = e.value
+	}
+
+	p.seq.clear()
+
+	return drained
+}
+
+// destroy calls the callback set using WithOnDrop with each of destroyed. The caller must not hold
+// the lock.
+func (p *Pool[T]) destroy(destroyed []T) {
+	if p.onDrop == nil {
+		return
+	}
+
+	for _, x := range destroyed {
+		p.onDrop(x)
+	}
+}
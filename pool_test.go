@@ -0,0 +1,85 @@
+package ttl_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestPool() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	var mtx sync.Mutex
+	var destroyed []int
+	pool := ttl.NewPool[int](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithMaxEntries(3),
+		ttl.WithPruneInterval(0),
+		ttl.WithOnDrop(func(x int) {
+			mtx.Lock()
+			defer mtx.Unlock()
+
+			destroyed = append(destroyed, x)
+		}))
+
+	_, ok := pool.Get()
+	s.False(ok)
+
+	pool.Put(1)
+	clock.Advance(30 * time.Second)
+	pool.Put(2)
+	pool.Put(3)
+
+	// The most recently put object is reused first
+	x, ok := pool.Get()
+	s.True(ok)
+	s.Equal(3, x)
+	pool.Put(3)
+
+	// Beyond the limit, the object idle the longest is destroyed
+	pool.Put(4)
+	s.Equal([]int{1}, destroyed)
+	s.Equal(3, pool.Length())
+
+	clock.Advance(time.Minute)
+	pool.PutWithTTL(5, 0)
+	s.Equal([]int{1, 2}, destroyed)
+
+	x, ok = pool.Get()
+	s.True(ok)
+	s.Equal(5, x)
+
+	s.Equal(2, pool.Prune())
+	s.Equal([]int{1, 2, 3, 4}, destroyed)
+
+	pool.Put(6)
+	pool.Close()
+	pool.Put(7)
+	s.Equal([]int{1, 2, 3, 4, 6, 7}, destroyed)
+}
+
+func (s *MapTestSuite) TestPoolContext() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	closed := make(chan int, 1)
+	pool := ttl.NewPool[int](
+		ttl.WithContext(ctx),
+		ttl.WithOnDrop(func(x int) {
+			closed <- x
+		}))
+	defer pool.CloseWait()
+
+	pool.Put(1)
+	cancel()
+
+	select {
+	case x := <-closed:
+		s.Equal(1, x)
+	case <-time.After(time.Second):
+		s.Fail("the idle object was not destroyed")
+	}
+}
@@ -0,0 +1,236 @@
+package ttl
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// LoadPrefix returns the unexpired entries of m whose keys start with prefix, such as every key of
+// a namespace like "user:123:", in lexicographic order of the keys. It does not update the last
+// access time of any key, like [Map.Range]. If m was created using [WithPrefixIndex], only the keys
+// with the prefix are visited; otherwise every key is, and the entries are sorted afterwards.
+// LoadPrefix is safe for concurrent use.
+func LoadPrefix[K ~string, V any](m *Map[K, V], prefix string) (entries []Entry[K, V]) {
+	_ = rangePrefix(context.Background(), m, prefix, func(key K, item *mapItem[V], now int64) bool {
+		entries = append(entries, newEntry(key, item, now))
+		return true
+	})
+
+	if m.prefixes == nil {
+		slices.SortFunc(entries, func(a, b Entry[K, V]) int {
+			return cmp.Compare(a.Key, b.Key)
+		})
+	}
+
+	return entries
+}
+
+// RangePrefix calls f sequentially for each entry of m whose key starts with prefix, in
+// lexicographic order of the keys if m was created using [WithPrefixIndex], and in no particular
+// order otherwise. If f returns false, RangePrefix stops the iteration. RangePrefix is safe for
+// concurrent use, with the same restrictions as [Map.Range].
+func RangePrefix[K ~string, V any](m *Map[K, V], prefix string, f func(key K, value V) bool) {
+	_ = RangePrefixContext(context.Background(), m, prefix, f)
+}
+
+// RangePrefixContext is like [RangePrefix], but checks ctx before visiting each entry and stops
+// early if ctx is done, returning ctx.Err(), like [Map.RangeContext].
+func RangePrefixContext[K ~string, V any](
+	ctx context.Context,
+	m *Map[K, V],
+	prefix string,
+	f func(key K, value V) bool,
+) error {
+	return rangePrefix(ctx, m, prefix, func(key K, item *mapItem[V], _ int64) bool {
+		return f(key, item.value)
+	})
+}
+
+// rangePrefix calls visit for each item of m whose key starts with prefix, as RangePrefixContext
+// does, with the lock held.
+func rangePrefix[K ~string, V any](
+	ctx context.Context,
+	m *Map[K, V],
+	prefix string,
+	visit func(key K, item *mapItem[V], now int64) bool,
+) error {
+	live := func(key K, item *mapItem[V], now int64) bool {
+		if m.lazyExpiry && item.expired(now) {
+			return true
+		}
+
+		return visit(key, item, now)
+	}
+
+	if m.prefixes == nil {
+		return m.iterate(ctx, func(key K, item *mapItem[V], now int64) bool {
+			return !strings.HasPrefix(string(key), prefix) || live(key, item, now)
+		})
+	}
+
+	m.mtx.RLock()
+	keys := m.prefixes.keys(prefix)
+	m.mtx.RUnlock()
+
+	_, err := m.iterateKeys(ctx, keys, live)
+
+	return err
+}
+
+// DeletePrefix removes the entries of m whose keys start with prefix, such as every key of a
+// namespace like "tenant:42:", in a single locked operation, returning the number removed. Entries
+// that have expired but have not been pruned yet are removed as expired and are not counted. If m
+// was created using [WithPrefixIndex], only the keys with the prefix are visited; otherwise every
+// key is. DeletePrefix is safe for concurrent use.
+func DeletePrefix[K ~string, V any](m *Map[K, V], prefix string) (deleted int) {
+	m.lock()
+	defer m.unlock()
+
+	var keys []K
+	if m.prefixes != nil {
+		keys = m.prefixes.keys(prefix)
+	} else {
+		for key := range m.m {
+			if strings.HasPrefix(string(key), prefix) {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	now := m.now()
+
+	for _, key := range keys {
+		if m.m[key].expired(now) {
+			m.removeLocked(key, ReasonExpired)
+			continue
+		}
+
+		m.removeLocked(key, ReasonDeleted)
+		deleted++
+	}
+
+	return
+}
+
+// prefixIndex is a trie of the keys of a map, used by WithPrefixIndex to find the keys with a
+// prefix without visiting every key. It is not safe for concurrent use.
+type prefixIndex[K comparable] struct {
+	root      prefixNode[K]
+	prefixKey func(K) string
+}
+
+// prefixNode is a node of a prefixIndex, holding the key spelled by the path to it, if any.
+type prefixNode[K comparable] struct {
+	children []*prefixNode[K] // ordered by label
+	label    byte
+	key      K
+	present  bool
+}
+
+func newPrefixIndex[K comparable](prefixKey func(K) string) *prefixIndex[K] {
+	return &prefixIndex[K]{prefixKey: prefixKey}
+}
+
+// child returns the child of n labelled c, creating it if create is set.
+func (n *prefixNode[K]) child(c byte, create bool) *prefixNode[K] {
+	i := sort.Search(len(n.children), func(i int) bool {
+		return n.children[i].label >= c
+	})
+
+	if i < len(n.children) && n.children[i].label == c {
+		return n.children[i]
+	}
+
+	if !create {
+		return nil
+	}
+
+	child := &prefixNode[K]{label: c}
+	n.children = append(n.children, nil)
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = child
+
+	return child
+}
+
+// insert adds key to the index.
+func (idx *prefixIndex[K]) insert(key K) {
+	n := &idx.root
+	s := idx.prefixKey(key)
+
+	for i := 0; i < len(s); i++ {
+		n = n.child(s[i], true)
+	}
+
+	n.key, n.present = key, true
+}
+
+// remove removes key from the index, along with the nodes left without keys.
+func (idx *prefixIndex[K]) remove(key K) {
+	s := idx.prefixKey(key)
+
+	path := make([]*prefixNode[K], 0, len(s)+1)
+	path = append(path, &idx.root)
+
+	for i := 0; i < len(s); i++ {
+		n := path[len(path)-1].child(s[i], false)
+		if n == nil {
+			return
+		}
+
+		path = append(path, n)
+	}
+
+	var zero K
+	last := path[len(path)-1]
+	last.key, last.present = zero, false
+
+	// Remove the nodes that no longer lead to a key, from the deepest
+	for i := len(path) - 1; i > 0; i-- {
+		n, parent := path[i], path[i-1]
+		if n.present || len(n.children) > 0 {
+			break
+		}
+
+		for j, child := range parent.children {
+			if child == n {
+				parent.children = append(parent.children[:j], parent.children[j+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// keys returns the keys with prefix, in lexicographic order.
+func (idx *prefixIndex[K]) keys(prefix string) (keys []K) {
+	n := &idx.root
+	for i := 0; i < len(prefix) && n != nil; i++ {
+		n = n.child(prefix[i], false)
+	}
+
+	if n == nil {
+		return nil
+	}
+
+	var walk func(n *prefixNode[K])
+	walk = func(n *prefixNode[K]) {
+		if n.present {
+			keys = append(keys, n.key)
+		}
+
+		for _, child := range n.children {
+			walk(child)
+		}
+	}
+	walk(n)
+
+	return keys
+}
+
+// clear removes every key from the index.
+func (idx *prefixIndex[K]) clear() {
+	idx.root = prefixNode[K]{}
+}
@@ -0,0 +1,157 @@
+package ttl_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestLoadPrefix() {
+	type key string
+
+	for _, indexed := range []bool{false, true} {
+		clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+		opts := []ttl.Option{ttl.WithClock(clock), ttl.WithTTL(time.Minute), ttl.WithPruneInterval(0)}
+		if indexed {
+			opts = append(opts, ttl.WithPrefixIndex[key]())
+		}
+
+		m := ttl.New[key, int](opts...)
+
+		// load returns the keys and values LoadPrefix returns for prefix, in order
+		load := func(prefix string) (keys []key, values []int) {
+			for _, e := range ttl.LoadPrefix(m, prefix) {
+				keys = append(keys, e.Key)
+				values = append(values, e.Value)
+			}
+
+			return keys, values
+		}
+
+		m.Store("user:1:profile", 1)
+		m.Store("user:1:settings", 2)
+		m.Store("user:12:profile", 3)
+		m.Store("user:2:profile", 4)
+		m.Store("user:", 5)
+		m.StoreWithTTL("user:1:session", 6, time.Second)
+
+		keys, values := load("user:1:")
+		s.Equal([]key{"user:1:profile", "user:1:session", "user:1:settings"}, keys, "indexed: %v",
+			indexed)
+		s.Equal([]int{1, 6, 2}, values)
+
+		entries := ttl.LoadPrefix(m, "user:1:session")
+		s.Require().Len(entries, 1)
+		s.Equal(time.Second, entries[0].Remaining)
+
+		keys, _ = load("user:")
+		s.Equal([]key{
+			"user:",
+			"user:12:profile", // '2' sorts before ':'
+			"user:1:profile",
+			"user:1:session",
+			"user:1:settings",
+			"user:2:profile",
+		}, keys)
+		s.Len(ttl.LoadPrefix(m, ""), 6)
+		s.Empty(ttl.LoadPrefix(m, "group:"))
+		s.Empty(ttl.LoadPrefix(m, "user:1:profile:"))
+
+		// Expired, deleted and renamed keys are not visited
+		clock.Advance(time.Second)
+		m.Delete("user:1:settings")
+		m.Rename("user:12:profile", "user:1:other")
+		keys, values = load("user:1")
+		s.Equal([]key{"user:1:other", "user:1:profile"}, keys)
+		s.Equal([]int{3, 1}, values)
+
+		m.Clear()
+		s.Empty(ttl.LoadPrefix(m, "user:"))
+
+		m.Store("user:1:profile", 7)
+		keys, values = load("user:1")
+		s.Equal([]key{"user:1:profile"}, keys)
+		s.Equal([]int{7}, values)
+
+		m.Close()
+	}
+}
+
+func (s *MapTestSuite) TestRangePrefixOrdered() {
+	m := ttl.New[string, int](ttl.WithPrefixIndex[string](), ttl.WithPruneInterval(0))
+	defer m.Close()
+
+	for i, k := range []string{"b:2", "a:1", "b:10", "b:1", "b", "c:1"} {
+		m.Store(k, i)
+	}
+
+	var keys []string
+	ttl.RangePrefix(m, "b", func(key string, _ int) bool {
+		keys = append(keys, key)
+		return len(keys) < 3
+	})
+	s.Equal([]string{"b", "b:1", "b:10"}, keys)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.ErrorIs(ttl.RangePrefixContext(ctx, m, "b", func(string, int) bool {
+		return true
+	}), context.Canceled)
+
+	// The index must match the key type
+	type key string
+	s.Panics(func() {
+		ttl.New[string, int](ttl.WithPrefixIndex[key]())
+	})
+}
+
+func (s *MapTestSuite) TestDeletePrefix() {
+	for _, indexed := range []bool{false, true} {
+		clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+		var removed []string
+
+		opts := []ttl.Option{
+			ttl.WithClock(clock),
+			ttl.WithTTL(time.Minute),
+			ttl.WithPruneInterval(0),
+			ttl.WithOnRemove(func(key string, _ int, reason ttl.RemovalReason) {
+				removed = append(removed, key+" "+reason.String())
+			}),
+		}
+		if indexed {
+			opts = append(opts, ttl.WithPrefixIndex[string]())
+		}
+
+		m := ttl.New[string, int](opts...)
+
+		m.Store("tenant:42:a", 1)
+		m.Store("tenant:42:b", 2)
+		m.StoreWithTTL("tenant:42:c", 3, time.Second)
+		m.Store("tenant:420:a", 4)
+		m.Store("tenant:7:a", 5)
+
+		clock.Advance(time.Second)
+
+		s.Equal(2, ttl.DeletePrefix(m, "tenant:42:"), "indexed: %v", indexed)
+		s.ElementsMatch([]string{
+			"tenant:42:a deleted",
+			"tenant:42:b deleted",
+			"tenant:42:c expired",
+		}, removed)
+		s.Equal(2, m.Length())
+		entries := ttl.LoadPrefix(m, "tenant:")
+		s.Require().Len(entries, 2)
+		s.Equal("tenant:420:a", entries[0].Key)
+		s.Equal("tenant:7:a", entries[1].Key)
+
+		s.Zero(ttl.DeletePrefix(m, "tenant:42:"))
+		s.Equal(2, ttl.DeletePrefix(m, ""))
+		s.Zero(m.Length())
+
+		m.Close()
+	}
+}
@@ -0,0 +1,147 @@
+package ttl
+
+import (
+	"strconv"
+	"time"
+)
+
+// PruneStrategy determines how the background prune pass of a [Map] finds expired items.
+type PruneStrategy int
+
+const (
+	// PruneFullScan checks every item in the [Map] on each prune pass (or every item in turn, if
+	// [WithPruneBatchSize] is used). Expired items are always removed within a prune interval of
+	// expiring, but the cost of each pass grows with the size of the [Map].
+	PruneFullScan PruneStrategy = iota
+
+	// PruneSampled checks a random sample of items on each prune pass and removes those that have
+	// expired, repeating while a large fraction of the sample had expired, in the manner of Redis'
+	// active expiry. The cost of each pass depends on the number of expired items rather than the
+	// size of the [Map], but some expired items may remain for several prune intervals. Lazy
+	// expiry (see [WithLazyExpiry]) still hides them from loads.
+	PruneSampled
+
+	// PruneHeap keeps the keys of the [Map] in a min-heap ordered by expiration time (see
+	// [ExpiryList]), so each prune pass only checks items that are due to expire, plus items that
+	// were written since the previous pass. This makes pruning cost proportional to the number of
+	// expired and changed items rather than the size of the [Map], at the cost of O(log n) work and
+	// some memory per key.
+	PruneHeap
+
+	// PruneTimingWheel keeps the keys of the [Map] in a hierarchical timing wheel with a resolution
+	// of one prune interval. Like [PruneHeap], each prune pass only checks items that are due to
+	// expire, but adding, moving and removing a key takes constant time regardless of the size of
+	// the [Map]. This suits workloads with very high store rates and short TTLs, such as rate
+	// limiting, where even a heap is too expensive.
+	PruneTimingWheel
+)
+
+// String returns the name of the strategy, such as "PruneHeap".
+func (s PruneStrategy) String() string {
+	switch s {
+	case PruneFullScan:
+		return "PruneFullScan"
+	case PruneSampled:
+		return "PruneSampled"
+	case PruneHeap:
+		return "PruneHeap"
+	case PruneTimingWheel:
+		return "PruneTimingWheel"
+	default:
+		return "PruneStrategy(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+const (
+	// pruneSampleSize is the number of items checked in each round of sampled pruning.
+	pruneSampleSize = 20
+
+	// pruneSampleRepeat is the fraction of expired items in a sample, as a divisor, above which
+	// another round is run: a quarter.
+	pruneSampleRepeat = 4
+
+	// pruneSampleBudget is the fraction of the prune interval, as a divisor, that sampled pruning
+	// may spend in repeated rounds.
+	pruneSampleBudget = 4
+)
+
+// pruneSampled runs rounds of sampled pruning for at most a fraction of pruneInterval, stopping
+// when few of the sampled items had expired. It returns the number of items removed. The lock is
+// released between rounds so that loads and stores are never blocked for more than one round.
+func (m *Map[K, V]) pruneSampled(pruneInterval time.Duration) (pruned int) {
+	start := time.Now()
+
+	for {
+		sampled, expired := m.pruneSample(m.now())
+		pruned += expired
+
+		if expired*pruneSampleRepeat <= sampled || time.Since(start) > pruneInterval/pruneSampleBudget {
+			return
+		}
+	}
+}
+
+// pruneSample checks up to pruneSampleSize items, starting at a random position in the map,
+// removing those that have expired as of now, expressed in Unix nanoseconds. It returns the
+// number of items checked and removed.
+func (m *Map[K, V]) pruneSample(now int64) (sampled int, expired int) {
+	m.mtx.Lock()
+	defer m.unlock()
+
+	// Iteration over a Go map starts at a random position
+	for key, item := range m.m {
+		if sampled == pruneSampleSize {
+			break
+		}
+
+		sampled++
+
+		if item.expired(now) {
+			m.removeLocked(key, ReasonExpired)
+			expired++
+		}
+	}
+
+	return
+}
+
+// recheckLocked schedules key to be checked by the next prune pass, if the PruneHeap or
+// PruneTimingWheel strategy is used. It must be called whenever an item is added or its expiration
+// time may have moved earlier. The index only ever holds a lower bound of each item's expiration
+// time, since loads and touches move it later without the write lock; the item is rescheduled when
+// it is found not to have expired. The caller must hold the write lock.
+func (m *Map[K, V]) recheckLocked(key K) {
+	if m.expiry != nil {
+		m.expiry.set(key, m.now())
+	}
+}
+
+// pruneIndexed removes the items that have expired as of now, expressed in Unix nanoseconds,
+// checking only the keys that are due according to the expiry index. If [WithPruneBatchSize] was
+// used, at most that many keys are checked. It returns the number of items removed.
+func (m *Map[K, V]) pruneIndexed(now int64) (pruned int) {
+	m.mtx.Lock()
+	defer m.unlock()
+
+	for _, key := range m.expiry.popExpired(now, m.pruneBatch) {
+		it, ok := m.m[key]
+		switch {
+		case !ok:
+			continue
+
+		case it.expired(now):
+			m.removeLocked(key, ReasonExpired)
+			pruned++
+
+		case it.pinned || it.immortal():
+			// Not rescheduled until the item is unpinned or its TTL is changed
+			continue
+
+		default:
+			// Accessed since it was scheduled, or scheduled early
+			m.expiry.set(key, it.expiresAt())
+		}
+	}
+
+	return
+}
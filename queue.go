@@ -0,0 +1,182 @@
+package ttl
+
+import (
+	"sync"
+	"time"
+)
+
+// Queue is a first-in, first-out queue whose elements are dropped if they are not dequeued within
+// their time to live, for example so that stale jobs in a work queue are never run. A callback set
+// using [WithOnDrop] is called with each dropped element.
+//
+// A Queue is configured using the same [Option]s as a [Map]. [WithContext], [WithTTL],
+// [WithPruneInterval], [WithPruneJitter], [WithClock] and [WithOnDrop] apply; other options are
+// ignored. Expired elements are never dequeued, whether or not they have been pruned.
+//
+// Queue is safe for concurrent use.
+type Queue[T any] struct {
+	clock      Clock
+	defaultTTL time.Duration
+	onDrop     func(T)
+
+	mtx sync.Mutex
+	seq sequence[T]
+
+	bg background
+}
+
+// NewQueue returns a new, empty [Queue] configured by opts, like [New].
+func NewQueue[T any](opts ...Option) *Queue[T] {
+	o := defaultOptions()
+	o.apply(opts)
+	o.logMisconfiguration()
+
+	q := &Queue[T]{
+		clock:      o.clock,
+		defaultTTL: o.ttl,
+		onDrop:     typedOption[func(T)]("WithOnDrop", o.onDrop),
+		seq:        newSequence[T](),
+		bg:         newBackground(),
+	}
+
+	q.bg.start(o, func(now time.Time) {
+		q.prune(now)
+	})
+
+	return q
+}
+
+// Enqueue adds value to the back of the [Queue] with the default time to live. Enqueue is safe for
+// concurrent use.
+func (q *Queue[T]) Enqueue(value T) {
+	q.EnqueueWithTTL(value, q.defaultTTL)
+}
+
+// EnqueueWithTTL adds value to the back of the [Queue] with a custom time to live. A zero or
+// negative TTL means the element never expires. EnqueueWithTTL is safe for concurrent use.
+func (q *Queue[T]) EnqueueWithTTL(value T, TTL time.Duration) {
+	e := newSequenceElement(value, q.clock.Now(), TTL)
+
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.seq.push(e)
+}
+
+// Dequeue removes and returns the unexpired element at the front of the [Queue], as well as a bool
+// indicating whether there was one. Expired elements ahead of it are dropped. Dequeue does not
+// wait for an element to be enqueued. Dequeue is safe for concurrent use.
+func (q *Queue[T]) Dequeue() (value T, ok bool) {
+	// Dropped elements are reported after the lock is released by the deferred Unlock below
+	var dropped []T
+	defer func() {
+		q.drop(dropped)
+	}()
+
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	now := q.clock.Now().UnixNano()
+
+	for len(q.seq.elements) > 0 {
+		e := q.seq.pop()
+		if !e.expired(now) {
+			return e.value, true
+		}
+
+		if q.onDrop != nil {
+			dropped = append(dropped, e.value)
+		}
+	}
+
+	return
+}
+
+// Peek returns the unexpired element at the front of the [Queue] without removing it, as well as
+// a bool indicating whether there is one. Peek is safe for concurrent use.
+func (q *Queue[T]) Peek() (value T, ok bool) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	now := q.clock.Now().UnixNano()
+
+	for _, e := range q.seq.elements {
+		if !e.expired(now) {
+			return e.value, true
+		}
+	}
+
+	return
+}
+
+// Length returns the number of unexpired elements in the [Queue]. Length is safe for concurrent
+// use.
+func (q *Queue[T]) Length() (n int) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	now := q.clock.Now().UnixNano()
+
+	for _, e := range q.seq.elements {
+		if !e.expired(now) {
+			n++
+		}
+	}
+
+	return
+}
+
+// Prune drops the expired elements from the [Queue], returning the number dropped. Prune is safe
+// for concurrent use.
+func (q *Queue[T]) Prune() int {
+	return q.prune(q.clock.Now())
+}
+
+// Clear removes every element from the [Queue], without calling the callback set using
+// [WithOnDrop]. Clear is safe for concurrent use.
+func (q *Queue[T]) Clear() {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.seq.clear()
+}
+
+// Close stops the [Queue] from pruning in the background. If Close is not called on a [Queue]
+// after it's no longer needed, its prune goroutine will leak (unless the context has been
+// cancelled). Close may be called multiple times.
+func (q *Queue[T]) Close() {
+	q.bg.close()
+}
+
+// CloseWait is like [Queue.Close], but also waits for the prune goroutine to exit.
+func (q *Queue[T]) CloseWait() {
+	q.bg.closeWait()
+}
+
+// prune drops the elements that have expired as of now, returning the number dropped.
+func (q *Queue[T]) prune(now time.Time) int {
+	var dropped []T
+
+	q.mtx.Lock()
+
+	var pruned int
+	if q.onDrop != nil {
+		pruned = q.seq.prune(now.UnixNano(), &dropped)
+	} else {
+		pruned = q.seq.prune(now.UnixNano(), nil)
+	}
+
+	q.mtx.Unlock()
+
+	q.drop(dropped)
+
+	return pruned
+}
+
+// drop calls the callback set using WithOnDrop with each of dropped. The caller must not hold the
+// lock.
+func (q *Queue[T]) drop(dropped []T) {
+	for _, value := range dropped {
+		q.onDrop(value)
+	}
+}
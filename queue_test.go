@@ -0,0 +1,65 @@
+package ttl_test
+
+import (
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestQueue() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	var dropped []int
+	q := ttl.NewQueue[int](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0),
+		ttl.WithOnDrop(func(value int) {
+			dropped = append(dropped, value)
+		}))
+	defer q.Close()
+
+	_, ok := q.Dequeue()
+	s.False(ok)
+
+	q.Enqueue(1)
+	q.EnqueueWithTTL(2, time.Second)
+	q.EnqueueWithTTL(3, 0)
+	q.Enqueue(4)
+
+	v, ok := q.Peek()
+	s.True(ok)
+	s.Equal(1, v)
+	s.Equal(4, q.Length())
+
+	v, ok = q.Dequeue()
+	s.True(ok)
+	s.Equal(1, v)
+
+	// 2 expires before it is dequeued, and is dropped
+	clock.Advance(time.Second)
+	s.Equal(2, q.Length())
+
+	v, ok = q.Peek()
+	s.True(ok)
+	s.Equal(3, v)
+
+	v, ok = q.Dequeue()
+	s.True(ok)
+	s.Equal(3, v)
+	s.Equal([]int{2}, dropped)
+
+	q.Enqueue(5)
+	clock.Advance(time.Minute)
+	s.Equal(2, q.Prune())
+	s.Equal([]int{2, 4, 5}, dropped)
+
+	_, ok = q.Dequeue()
+	s.False(ok)
+
+	q.Enqueue(6)
+	q.Clear()
+	s.Zero(q.Length())
+	s.Equal([]int{2, 4, 5}, dropped)
+}
@@ -0,0 +1,207 @@
+package ttl
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits the rate of events per key using a token bucket for each key: a bucket holds
+// up to burst tokens and is refilled at rate tokens per second, and each event takes a token.
+//
+// The buckets are held in a [Map] whose TTL is the time an empty bucket takes to refill, so a key
+// that has been idle for that long, whose bucket would be full, is pruned in the background
+// without changing the limits applied to it.
+//
+// A RateLimiter is configured using the same [Option]s as a [Map], except that the TTL is set by
+// NewRateLimiter and buckets are always refreshed on load.
+//
+// RateLimiter is safe for concurrent use.
+type RateLimiter[K comparable] struct {
+	rate    float64
+	burst   int
+	refill  time.Duration // the time an empty bucket takes to refill, or zero if it never does
+	clock   Clock
+	buckets *Map[K, *tokenBucket]
+}
+
+// tokenBucket is the bucket of a key. Its tokens are negative while events are waiting for them.
+type tokenBucket struct {
+	mtx    sync.Mutex
+	tokens float64
+	last   time.Time // the time tokens was last brought up to date
+}
+
+// NewRateLimiter returns a [RateLimiter] that allows events for each key at rate per second, with
+// bursts of up to burst events, configured by opts. If rate is zero or negative, the buckets are
+// never refilled, so each key is allowed burst events in total until it is reset.
+func NewRateLimiter[K comparable](rate float64, burst int, opts ...Option) *RateLimiter[K] {
+	l := &RateLimiter[K]{
+		rate:  max(rate, 0),
+		burst: max(burst, 0),
+	}
+
+	if l.rate > 0 {
+		l.refill = max(time.Duration(float64(l.burst)/l.rate*float64(time.Second)), 1)
+	}
+
+	l.buckets = New[K, *tokenBucket](append(append([]Option(nil), opts...),
+		WithTTL(l.refill),
+		WithRefreshOnLoad(true))...)
+	l.clock = l.buckets.clock
+
+	return l
+}
+
+// Allow reports whether an event for key may happen now, taking a token from its bucket if so.
+// Allow is safe for concurrent use.
+func (l *RateLimiter[K]) Allow(key K) bool {
+	return l.AllowN(key, 1)
+}
+
+// AllowN reports whether n events for key may happen now, taking n tokens from its bucket if so.
+// AllowN is safe for concurrent use.
+func (l *RateLimiter[K]) AllowN(key K, n int) bool {
+	b := l.bucket(key)
+	now := l.clock.Now()
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	l.advance(b, now)
+
+	if b.tokens < float64(n) {
+		return false
+	}
+
+	b.tokens -= float64(n)
+
+	return true
+}
+
+// Wait blocks until an event for key may happen, or ctx is done. Wait is safe for concurrent use.
+func (l *RateLimiter[K]) Wait(ctx context.Context, key K) error {
+	return l.WaitN(ctx, key, 1)
+}
+
+// WaitN blocks until n events for key may happen, then takes n tokens from its bucket. It returns
+// an error, without waiting, if n exceeds the burst of the [RateLimiter] or if the wait would last
+// beyond the deadline of ctx, and returns ctx.Err() if ctx is done while waiting, in which case the
+// tokens are returned to the bucket. Waiting events are served in the order in which they arrived.
+// WaitN is safe for concurrent use.
+func (l *RateLimiter[K]) WaitN(ctx context.Context, key K, n int) error {
+	if n > l.burst {
+		return fmt.Errorf("ttl: WaitN(n=%d) exceeds the burst of the rate limiter (%d)", n, l.burst)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b := l.bucket(key)
+	now := l.clock.Now()
+
+	b.mtx.Lock()
+
+	l.advance(b, now)
+
+	var wait time.Duration
+	if b.tokens < float64(n) {
+		if l.rate == 0 {
+			b.mtx.Unlock()
+			return fmt.Errorf("ttl: WaitN(n=%d) would wait forever for a bucket that is not refilled", n)
+		}
+
+		wait = time.Duration((float64(n) - b.tokens) / l.rate * float64(time.Second))
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && now.Add(wait).After(deadline) {
+		b.mtx.Unlock()
+		return fmt.Errorf("ttl: WaitN(n=%d) would exceed the context deadline", n)
+	}
+
+	// Take the tokens now, so that later events wait for tokens after them
+	b.tokens -= float64(n)
+
+	if wait == 0 {
+		b.mtx.Unlock()
+		return nil
+	}
+
+	// Keep the bucket until it has refilled, which is later than usual
+	l.buckets.SetTTL(key, time.Duration((float64(l.burst)-b.tokens)/l.rate*float64(time.Second)))
+
+	// The ticker is created before the tokens taken can be seen, so that it counts from now
+	ticker := l.clock.NewTicker(wait)
+	defer ticker.Stop()
+
+	b.mtx.Unlock()
+
+	select {
+	case <-ticker.C():
+		return nil
+	case <-ctx.Done():
+		b.mtx.Lock()
+		b.tokens += float64(n)
+		b.mtx.Unlock()
+
+		return ctx.Err()
+	}
+}
+
+// Tokens returns the number of tokens in the bucket of key, which is negative while events are
+// waiting for tokens. Tokens is safe for concurrent use.
+func (l *RateLimiter[K]) Tokens(key K) float64 {
+	b, ok := l.buckets.LoadPassive(key)
+	if !ok {
+		return float64(l.burst)
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	l.advance(b, l.clock.Now())
+
+	return b.tokens
+}
+
+// Reset refills the bucket of key, removing it. Reset is safe for concurrent use.
+func (l *RateLimiter[K]) Reset(key K) {
+	l.buckets.Delete(key)
+}
+
+// Length returns the number of keys whose buckets are held by the [RateLimiter], like
+// [Map.Length]. Length is safe for concurrent use.
+func (l *RateLimiter[K]) Length() int {
+	return l.buckets.Length()
+}
+
+// Close stops the [RateLimiter] from pruning idle buckets, like [Map.Close].
+func (l *RateLimiter[K]) Close() {
+	l.buckets.Close()
+}
+
+// CloseWait is like [RateLimiter.Close], but also waits for the prune goroutine to exit, like
+// [Map.CloseWait].
+func (l *RateLimiter[K]) CloseWait() {
+	l.buckets.CloseWait()
+}
+
+// bucket returns the bucket of key, creating a full one if the key has none.
+func (l *RateLimiter[K]) bucket(key K) *tokenBucket {
+	b, _ := l.buckets.loadOrStore(key, func() *tokenBucket {
+		return &tokenBucket{tokens: float64(l.burst), last: l.clock.Now()}
+	})
+
+	return b
+}
+
+// advance adds the tokens refilled since b was last brought up to date as of now. The caller must
+// hold the lock of b.
+func (l *RateLimiter[K]) advance(b *tokenBucket, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.tokens+elapsed.Seconds()*l.rate, float64(l.burst))
+		b.last = now
+	}
+}
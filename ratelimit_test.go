@@ -0,0 +1,86 @@
+package ttl_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestRateLimiter() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	limiter := ttl.NewRateLimiter[string](2, 4,
+		ttl.WithClock(clock),
+		ttl.WithPruneInterval(time.Second))
+	defer limiter.Close()
+
+	for i := 0; i < 4; i++ {
+		s.True(limiter.Allow("a"))
+	}
+	s.False(limiter.Allow("a"))
+	s.True(limiter.Allow("b"))
+
+	// Tokens are refilled at the rate
+	clock.Advance(time.Second)
+	s.True(limiter.AllowN("a", 2))
+	s.False(limiter.Allow("a"))
+	s.Zero(limiter.Tokens("a"))
+	s.Equal(float64(4), limiter.Tokens("b"))
+
+	// Buckets of idle keys are pruned once they have refilled
+	s.Equal(2, limiter.Length())
+	clock.Advance(2 * time.Second)
+	s.Eventually(func() bool { return limiter.Length() == 0 }, time.Second, time.Millisecond)
+	s.Equal(float64(4), limiter.Tokens("a"))
+
+	limiter.AllowN("c", 4)
+	limiter.Reset("c")
+	s.True(limiter.Allow("c"))
+}
+
+func (s *MapTestSuite) TestRateLimiterWait() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	limiter := ttl.NewRateLimiter[string](1, 1,
+		ttl.WithClock(clock),
+		ttl.WithPruneInterval(0))
+	defer limiter.Close()
+
+	ctx := context.Background()
+	s.NoError(limiter.Wait(ctx, "a"))
+	s.Error(limiter.WaitN(ctx, "a", 2))
+
+	waited := make(chan error)
+	go func() {
+		waited <- limiter.Wait(ctx, "a")
+	}()
+
+	s.Eventually(func() bool { return limiter.Tokens("a") < 0 }, time.Second, time.Millisecond)
+
+	select {
+	case <-waited:
+		s.Fail("Wait returned before a token was refilled")
+	default:
+	}
+
+	clock.Advance(time.Second)
+	s.NoError(<-waited)
+
+	// A wait beyond the deadline fails without taking a token
+	deadline, cancel := context.WithDeadline(ctx, time.Now().Add(time.Millisecond))
+	defer cancel()
+	s.Error(limiter.Wait(deadline, "a"))
+	s.Equal(float64(0), limiter.Tokens("a"))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	go func() {
+		waited <- limiter.Wait(cancelled, "a")
+	}()
+
+	s.Eventually(func() bool { return limiter.Tokens("a") < 0 }, time.Second, time.Millisecond)
+	cancel()
+	s.ErrorIs(<-waited, context.Canceled)
+	s.Equal(float64(0), limiter.Tokens("a"))
+}
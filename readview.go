@@ -0,0 +1,57 @@
+package ttl
+
+// readView is an immutable copy of the items of a [Map], used by [WithReadMostly] to serve loads
+// without taking the lock. It is discarded whenever the map may be modified and rebuilt by the next
+// load.
+type readView[K comparable, V any] map[K]viewItem[V]
+
+type viewItem[V any] struct {
+	snapshot *mapItem[V] // copy of the item when the view was built, never modified
+	live     *mapItem[V] // the item itself, whose last access time may still change
+}
+
+// loadView implements loads for maps created with WithReadMostly, rebuilding the read view first if
+// it has been discarded.
+func (m *Map[K, V]) loadView(key K, update bool) (value V, ok bool) {
+	view := m.view.Load()
+	if view == nil {
+		view = m.buildView()
+	}
+
+	it, ok := (*view)[key]
+	if !ok {
+		return
+	}
+
+	if m.lazyExpiry && it.snapshot.expiredAccessed(m.now(), it.live.lastAccess.Load()) {
+		return value, false
+	}
+
+	it.live.accesses.Add(1)
+
+	if update && it.snapshot.refreshesOnLoad(m.refreshOnLoad) {
+		it.live.touch(m.now())
+	}
+
+	return it.snapshot.value, true
+}
+
+// buildView builds and publishes a read view of the map under the read lock, unless another load
+// has just done so, and returns it.
+func (m *Map[K, V]) buildView() *readView[K, V] {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	if view := m.view.Load(); view != nil {
+		return view
+	}
+
+	view := make(readView[K, V], len(m.m))
+	for key, it := range m.m {
+		view[key] = viewItem[V]{snapshot: it.clone(), live: it}
+	}
+
+	m.view.Store(&view)
+
+	return &view
+}
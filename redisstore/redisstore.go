@@ -0,0 +1,191 @@
+// Package redisstore provides a [ttl.Store] backed by [Redis], so that a [ttl.Map] can act as a
+// process-local cache (L1) in front of a cache shared between processes:
+//
+//	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+//	store := redisstore.New[string, Session](client, redisstore.WithPrefix("sessions:"))
+//
+//	m := ttl.New[string, Session](
+//		ttl.WithTTL(time.Minute),
+//		ttl.WithWriteThrough[string, Session](store),
+//		ttl.WithReadThrough[string, Session](store))
+//	defer m.Close()
+//
+// Values stored in the Map are mirrored to Redis with the same time to live using SET with an
+// expiry, and keys that are not found in the Map are loaded from Redis.
+//
+// [Redis]: https://redis.io
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// Codec encodes values of type T to bytes for storage.
+type Codec[T any] interface {
+	Marshal(v T) ([]byte, error)
+	Unmarshal(data []byte, v *T) error
+}
+
+// JSONCodec is a [Codec] using encoding/json. It is the default, so that values can be read by
+// processes written in other languages.
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Marshal(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec[T]) Unmarshal(data []byte, v *T) error {
+	return json.Unmarshal(data, v)
+}
+
+// Option configures a [Store].
+type Option func(*options)
+
+type options struct {
+	prefix  string
+	keyFunc any // func(K) string
+	values  any // Codec[V]
+}
+
+// WithPrefix sets a prefix added to every key, so that several stores can share a Redis database.
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithKeyFunc sets the function that converts keys to Redis keys, which must return different
+// strings for different keys. The default formats keys using fmt.Sprint.
+//
+// f must use the same key type as the [Store], otherwise New panics.
+func WithKeyFunc[K any](f func(key K) string) Option {
+	return func(o *options) {
+		o.keyFunc = f
+	}
+}
+
+// WithValueCodec sets the [Codec] used to encode values. The default is [JSONCodec].
+//
+// codec must use the same value type as the [Store], otherwise New panics.
+func WithValueCodec[V any](codec Codec[V]) Option {
+	return func(o *options) {
+		o.values = codec
+	}
+}
+
+// Store is a [ttl.Store] that stores entries in Redis. Store is safe for concurrent use.
+type Store[K comparable, V any] struct {
+	client  redis.Cmdable
+	prefix  string
+	keyFunc func(K) string
+	values  Codec[V]
+}
+
+// New returns a Store that stores entries using client, which may be a client, a cluster client or
+// any other [redis.Cmdable]. The caller remains responsible for closing client, after any Map
+// using the Store has been closed.
+func New[K comparable, V any](client redis.Cmdable, opts ...Option) *Store[K, V] {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := &Store[K, V]{
+		client: client,
+		prefix: o.prefix,
+		keyFunc: func(key K) string {
+			return fmt.Sprint(key)
+		},
+		values: JSONCodec[V]{},
+	}
+
+	if o.keyFunc != nil {
+		s.keyFunc = typedOption[func(K) string]("WithKeyFunc", o.keyFunc)
+	}
+
+	if o.values != nil {
+		s.values = typedOption[Codec[V]]("WithValueCodec", o.values)
+	}
+
+	return s
+}
+
+// typedOption asserts that the option named name was given a value of type T, panicking with a
+// descriptive message if it was not.
+func typedOption[T any](name string, v any) T {
+	t, ok := v.(T)
+	if !ok {
+		panic(fmt.Sprintf("redisstore: %s given %T, which does not match the Store's type", name, v))
+	}
+
+	return t
+}
+
+func (s *Store[K, V]) key(key K) string {
+	return s.prefix + s.keyFunc(key)
+}
+
+// Get implements [ttl.Store]. The value and its time to live are read in a single round trip.
+func (s *Store[K, V]) Get(ctx context.Context, key K) (value V, TTL time.Duration, ok bool, err error) {
+	k := s.key(key)
+
+	var get *redis.StringCmd
+	var pttl *redis.DurationCmd
+
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, k)
+		pttl = pipe.PTTL(ctx, k)
+		return nil
+	})
+
+	if errors.Is(err, redis.Nil) {
+		return value, 0, false, nil
+	} else if err != nil {
+		return
+	}
+
+	// PTTL is -2 if the key expired after it was read, which makes the value a miss, and -1 if the
+	// key has no expiry
+	switch TTL = pttl.Val(); TTL {
+	case -2:
+		return value, 0, false, nil
+	case -1:
+		TTL = 0
+	}
+
+	data, err := get.Bytes()
+	if err != nil {
+		return
+	}
+
+	if err = s.values.Unmarshal(data, &value); err != nil {
+		return
+	}
+
+	return value, TTL, true, nil
+}
+
+// Set implements [ttl.Store], storing value using SET with an expiry of TTL, or none if TTL is
+// zero or negative.
+func (s *Store[K, V]) Set(ctx context.Context, key K, value V, TTL time.Duration) error {
+	data, err := s.values.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Set(ctx, s.key(key), data, max(TTL, 0)).Err()
+}
+
+// Delete implements [ttl.Store].
+func (s *Store[K, V]) Delete(ctx context.Context, key K) error {
+	return s.client.Del(ctx, s.key(key)).Err()
+}
+
+var _ ttl.Store[string, int] = (*Store[string, int])(nil)
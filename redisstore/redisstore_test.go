@@ -0,0 +1,145 @@
+package redisstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/redisstore"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/suite"
+)
+
+type StoreTestSuite struct {
+	suite.Suite
+
+	server *miniredis.Miniredis
+	client *redis.Client
+}
+
+func TestStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(StoreTestSuite))
+}
+
+func (s *StoreTestSuite) SetupTest() {
+	s.server = miniredis.RunT(s.T())
+	s.client = redis.NewClient(&redis.Options{Addr: s.server.Addr()})
+}
+
+func (s *StoreTestSuite) TearDownTest() {
+	s.NoError(s.client.Close())
+}
+
+func (s *StoreTestSuite) TestSetGetDelete() {
+	ctx := context.Background()
+	store := redisstore.New[int, []string](s.client, redisstore.WithPrefix("test:"))
+
+	s.NoError(store.Set(ctx, 1, []string{"a", "b"}, 90*time.Second))
+	s.NoError(store.Set(ctx, 2, nil, 0))
+
+	s.Equal(90*time.Second, s.server.TTL("test:1"))
+
+	v, TTL, ok, err := store.Get(ctx, 1)
+	s.NoError(err)
+	s.True(ok)
+	s.Equal([]string{"a", "b"}, v)
+	s.Equal(90*time.Second, TTL)
+
+	_, TTL, ok, _ = store.Get(ctx, 2)
+	s.True(ok)
+	s.Zero(TTL)
+
+	s.server.FastForward(90 * time.Second)
+	_, _, ok, err = store.Get(ctx, 1)
+	s.NoError(err)
+	s.False(ok)
+
+	s.NoError(store.Delete(ctx, 2))
+	s.False(s.server.Exists("test:2"))
+}
+
+func (s *StoreTestSuite) TestExpiredBetweenGetAndPTTL() {
+	ctx := context.Background()
+	store := redisstore.New[int, string](s.client)
+
+	s.NoError(store.Set(ctx, 1, "a", time.Minute))
+	s.client.AddHook(expiredPTTLHook{})
+
+	_, _, ok, err := store.Get(ctx, 1)
+	s.NoError(err)
+	s.False(ok)
+}
+
+// expiredPTTLHook makes every PTTL in a pipeline report that the key does not exist, as if it
+// expired just after the GET before it.
+type expiredPTTLHook struct{}
+
+func (expiredPTTLHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (expiredPTTLHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return next
+}
+
+func (expiredPTTLHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+
+		for _, cmd := range cmds {
+			if pttl, ok := cmd.(*redis.DurationCmd); ok && cmd.Name() == "pttl" {
+				pttl.SetVal(-2)
+			}
+		}
+
+		return err
+	}
+}
+
+func (s *StoreTestSuite) TestSharedBetweenMaps() {
+	store := redisstore.New[string, int](s.client)
+
+	newMap := func() *ttl.Map[string, int] {
+		return ttl.New[string, int](
+			ttl.WithTTL(time.Minute),
+			ttl.WithWriteThrough[string, int](store),
+			ttl.WithReadThrough[string, int](store))
+	}
+
+	first := newMap()
+	defer first.Close()
+
+	second := newMap()
+	defer second.Close()
+
+	first.Store("a", 1)
+
+	v, ok := second.Load("a")
+	s.True(ok)
+	s.Equal(1, v)
+	s.Equal(1, second.Length())
+
+	remaining, _ := second.TTL("a")
+	s.InDelta(time.Minute, remaining, float64(time.Second))
+
+	first.Delete("a")
+	second.Delete("a")
+
+	_, ok = second.Load("a")
+	s.False(ok)
+	s.False(s.server.Exists("a"))
+}
+
+func (s *StoreTestSuite) TestUnavailable() {
+	store := redisstore.New[string, int](s.client)
+
+	tm := ttl.New[string, int](ttl.WithReadThrough[string, int](store))
+	defer tm.Close()
+
+	s.server.Close()
+
+	_, ok := tm.Load("a")
+	s.False(ok)
+}
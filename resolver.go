@@ -0,0 +1,159 @@
+package ttl
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"time"
+)
+
+// HostResolver looks up the IP addresses of hosts. It is implemented by [net.Resolver].
+type HostResolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// TTLResolver is a [HostResolver] that also reports the time to live of the DNS records it looks
+// up, such as one that queries DNS servers directly. [net.Resolver] does not report the TTLs of
+// records, so addresses it looks up are cached for the default TTL of the [Resolver].
+type TTLResolver interface {
+	HostResolver
+
+	// LookupNetIPTTL is like LookupNetIP, but also returns the time for which the addresses may be
+	// cached, which is usually the lowest TTL of the records they were taken from.
+	LookupNetIPTTL(ctx context.Context, network, host string) ([]netip.Addr, time.Duration, error)
+}
+
+// Resolver caches the IP addresses of hosts looked up by a [HostResolver], which Go's resolver does
+// not do. Concurrent lookups of a host that is not cached share a single lookup, and failed lookups
+// are not cached. Addresses are cached for the TTL of their records if the [HostResolver] is a
+// [TTLResolver], or otherwise for the default TTL; loading them does not extend their TTL.
+//
+// A Resolver is configured using the same [Option]s as a [Map]. [WithReadThrough] is used to look
+// up hosts, so it must not be among the options.
+//
+// Resolver is safe for concurrent use.
+type Resolver struct {
+	resolver HostResolver
+	m        *Map[resolverKey, []netip.Addr]
+}
+
+// resolverKey is the key of the addresses of host on network.
+type resolverKey struct {
+	network string
+	host    string
+}
+
+// NewResolver returns a new [Resolver] caching the lookups of resolver, configured by opts, like
+// [New]. If resolver is nil, [net.DefaultResolver] is used.
+func NewResolver(resolver HostResolver, opts ...Option) *Resolver {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	r := &Resolver{resolver: resolver}
+
+	loader := LoaderFunc[resolverKey, []netip.Addr](r.lookup)
+	r.m = New[resolverKey, []netip.Addr](append(append([]Option{WithRefreshOnLoad(false)}, opts...),
+		WithReadThrough[resolverKey, []netip.Addr](loader))...)
+
+	return r
+}
+
+// LookupNetIP looks up host for network ("ip", "ip4" or "ip6"), returning the cached addresses if
+// the host has been looked up within their TTL, like [net.Resolver.LookupNetIP]. LookupNetIP is
+// safe for concurrent use.
+func (r *Resolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	addrs, _, err := r.m.LoadContext(ctx, resolverKey{network, host})
+	if err != nil {
+		return nil, err
+	}
+
+	// The addresses are copied, so that callers cannot change those cached
+	return append([]netip.Addr(nil), addrs...), nil
+}
+
+// LookupHost is like [net.Resolver.LookupHost], looking up host using [Resolver.LookupNetIP].
+// LookupHost is safe for concurrent use.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addrs, err := r.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := make([]string, len(addrs))
+	for i, addr := range addrs {
+		hosts[i] = addr.String()
+	}
+
+	return hosts, nil
+}
+
+// LookupIPAddr is like [net.Resolver.LookupIPAddr], looking up host using [Resolver.LookupNetIP].
+// LookupIPAddr is safe for concurrent use.
+func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, err := r.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+
+	ipAddrs := make([]net.IPAddr, len(addrs))
+	for i, addr := range addrs {
+		ipAddrs[i] = net.IPAddr{IP: addr.AsSlice(), Zone: addr.Zone()}
+	}
+
+	return ipAddrs, nil
+}
+
+// Forget removes the cached addresses of host, so that it is looked up again. Forget is safe for
+// concurrent use.
+func (r *Resolver) Forget(host string) {
+	for _, network := range []string{"ip", "ip4", "ip6"} {
+		r.m.Delete(resolverKey{network, host})
+	}
+}
+
+// Clear removes every cached address. Clear is safe for concurrent use.
+func (r *Resolver) Clear() {
+	r.m.Clear()
+}
+
+// Length returns the number of cached lookups, like [Map.Length]. Length is safe for concurrent
+// use.
+func (r *Resolver) Length() int {
+	return r.m.Length()
+}
+
+// Close stops the [Resolver] from pruning, like [Map.Close].
+func (r *Resolver) Close() {
+	r.m.Close()
+}
+
+// CloseWait is like [Resolver.Close], but also waits for the prune goroutine to exit, like
+// [Map.CloseWait].
+func (r *Resolver) CloseWait() {
+	r.m.CloseWait()
+}
+
+// lookup looks up key using the resolver, for use as the read-through store of the addresses.
+func (r *Resolver) lookup(
+	ctx context.Context,
+	key resolverKey,
+) ([]netip.Addr, time.Duration, bool, error) {
+	if tr, ok := r.resolver.(TTLResolver); ok {
+		addrs, TTL, err := tr.LookupNetIPTTL(ctx, key.network, key.host)
+		if err != nil {
+			return nil, 0, false, err
+		}
+
+		// A TTL of zero means the records must not be cached, which the map cannot express, so the
+		// addresses are cached as briefly as possible instead
+		return addrs, max(TTL, 1), true, nil
+	}
+
+	addrs, err := r.resolver.LookupNetIP(ctx, key.network, key.host)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	return addrs, r.m.defaultTTL, true, nil
+}
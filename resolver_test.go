@@ -0,0 +1,140 @@
+package ttl_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+// fakeResolver resolves every host to the addresses in addrs, counting its lookups.
+type fakeResolver struct {
+	mtx     sync.Mutex
+	addrs   map[string][]netip.Addr
+	lookups int
+	TTL     time.Duration // if non-zero, returned by LookupNetIPTTL
+}
+
+func (f *fakeResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.lookups++
+
+	addrs, ok := f.addrs[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	return addrs, nil
+}
+
+// ttlResolver is a fakeResolver that reports the TTL of its records.
+type ttlResolver struct {
+	*fakeResolver
+}
+
+func (r ttlResolver) LookupNetIPTTL(
+	ctx context.Context,
+	network, host string,
+) ([]netip.Addr, time.Duration, error) {
+	addrs, err := r.LookupNetIP(ctx, network, host)
+	return addrs, r.TTL, err
+}
+
+func (s *MapTestSuite) TestResolver() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+	ctx := context.Background()
+
+	fake := &fakeResolver{addrs: map[string][]netip.Addr{
+		"example.com": {netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("2001:db8::1")},
+	}}
+
+	r := ttl.NewResolver(fake,
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0))
+	defer r.Close()
+
+	for i := 0; i < 3; i++ {
+		hosts, err := r.LookupHost(ctx, "example.com")
+		s.Require().NoError(err)
+		s.Equal([]string{"192.0.2.1", "2001:db8::1"}, hosts)
+
+		clock.Advance(15 * time.Second)
+	}
+	s.Equal(1, fake.lookups)
+
+	ipAddrs, err := r.LookupIPAddr(ctx, "example.com")
+	s.Require().NoError(err)
+	s.Equal(net.ParseIP("192.0.2.1").To4(), ipAddrs[0].IP)
+	s.Equal(1, fake.lookups)
+
+	// Changing the addresses returned does not change those cached
+	addrs, _ := r.LookupNetIP(ctx, "ip", "example.com")
+	addrs[0] = netip.Addr{}
+
+	addrs, _ = r.LookupNetIP(ctx, "ip", "example.com")
+	s.Equal(netip.MustParseAddr("192.0.2.1"), addrs[0])
+
+	// The network is part of the key
+	_, _ = r.LookupNetIP(ctx, "ip4", "example.com")
+	s.Equal(2, fake.lookups)
+
+	// Lookups are not extended by use
+	clock.Advance(15 * time.Second)
+	_, _ = r.LookupHost(ctx, "example.com")
+	s.Equal(3, fake.lookups)
+
+	r.Forget("example.com")
+	_, _ = r.LookupHost(ctx, "example.com")
+	s.Equal(4, fake.lookups)
+
+	// Failed lookups are not cached
+	for i := 0; i < 2; i++ {
+		_, err = r.LookupHost(ctx, "unknown.example.com")
+
+		var dnsErr *net.DNSError
+		s.True(errors.As(err, &dnsErr))
+		s.True(dnsErr.IsNotFound)
+	}
+	s.Equal(6, fake.lookups)
+}
+
+func (s *MapTestSuite) TestResolverTTL() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+	ctx := context.Background()
+
+	fake := &fakeResolver{
+		addrs: map[string][]netip.Addr{"example.com": {netip.MustParseAddr("192.0.2.1")}},
+		TTL:   10 * time.Second,
+	}
+
+	r := ttl.NewResolver(ttlResolver{fake},
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0))
+	defer r.Close()
+
+	_, _ = r.LookupHost(ctx, "example.com")
+	_, _ = r.LookupHost(ctx, "example.com")
+	s.Equal(1, fake.lookups)
+
+	clock.Advance(10 * time.Second)
+	_, _ = r.LookupHost(ctx, "example.com")
+	s.Equal(2, fake.lookups)
+
+	// Records with a TTL of zero are not reused
+	fake.TTL = 0
+	r.Clear()
+
+	_, _ = r.LookupHost(ctx, "example.com")
+	clock.Advance(time.Nanosecond)
+	_, _ = r.LookupHost(ctx, "example.com")
+	s.Equal(4, fake.lookups)
+}
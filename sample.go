@@ -0,0 +1,94 @@
+package ttl
+
+import (
+	"math/rand"
+)
+
+// sampleAttempts is the number of keys drawn from the sample index by RandomEntry before it gives
+// up on finding an unexpired one that way.
+const sampleAttempts = 16
+
+// RandomEntry returns a random unexpired entry of the [Map], as well as a bool indicating whether
+// it has any, without visiting every entry. This suits monitoring and auditing the contents of
+// large maps by sampling.
+//
+// If the [Map] was created using [WithSampleIndex], every unexpired entry is equally likely to be
+// returned. Otherwise, the entry is found starting from the random position at which Go starts the
+// iteration of a map, which does not make every entry equally likely.
+//
+// RandomEntry does not update the last access time of the entry and is safe for concurrent use.
+func (m *Map[K, V]) RandomEntry() (key K, value V, ok bool) {
+	now := m.now()
+
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	// Drawing again when the key drawn has expired keeps the unexpired keys equally likely
+	if m.samples != nil && len(m.samples.keys) > 0 {
+		for i := 0; i < sampleAttempts; i++ {
+			key := m.samples.random()
+			if it := m.m[key]; !it.expired(now) {
+				return key, it.value, true
+			}
+		}
+	}
+
+	// Iteration over a Go map starts at a random position
+	for key, it := range m.m {
+		if !it.expired(now) {
+			return key, it.value, true
+		}
+	}
+
+	return
+}
+
+// sampleIndex is an array of the keys of a map, from which keys can be drawn at random.
+type sampleIndex[K comparable] struct {
+	keys      []K
+	positions map[K]int // the index of each key in keys
+}
+
+func newSampleIndex[K comparable]() *sampleIndex[K] {
+	return &sampleIndex[K]{positions: make(map[K]int)}
+}
+
+// insert adds key to the index, if it isn't already.
+func (idx *sampleIndex[K]) insert(key K) {
+	if _, ok := idx.positions[key]; ok {
+		return
+	}
+
+	idx.positions[key] = len(idx.keys)
+	idx.keys = append(idx.keys, key)
+}
+
+// remove removes key from the index, moving the last key into its position.
+func (idx *sampleIndex[K]) remove(key K) {
+	i, ok := idx.positions[key]
+	if !ok {
+		return
+	}
+
+	last := len(idx.keys) - 1
+	idx.keys[i] = idx.keys[last]
+	idx.positions[idx.keys[i]] = i
+
+	var zero K
+	idx.keys[last] = zero
+	idx.keys = idx.keys[:last]
+
+	delete(idx.positions, key)
+}
+
+// random returns a key of the index chosen at random. The index must not be empty.
+func (idx *sampleIndex[K]) random() K {
+	return idx.keys[rand.Intn(len(idx.keys))]
+}
+
+// clear removes every key from the index.
+func (idx *sampleIndex[K]) clear() {
+	clear(idx.keys)
+	idx.keys = idx.keys[:0]
+	clear(idx.positions)
+}
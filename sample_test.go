@@ -0,0 +1,105 @@
+package ttl_test
+
+import (
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestRandomEntry() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	for _, indexed := range []bool{false, true} {
+		opts := []ttl.Option{ttl.WithClock(clock), ttl.WithTTL(time.Minute), ttl.WithPruneInterval(0)}
+		if indexed {
+			opts = append(opts, ttl.WithSampleIndex())
+		}
+
+		m := ttl.New[int, int](opts...)
+
+		_, _, ok := m.RandomEntry()
+		s.False(ok)
+
+		for i := 0; i < 20; i++ {
+			m.Store(i, i*10)
+		}
+
+		// Removed, renamed and expired keys are never returned
+		for i := 10; i < 15; i++ {
+			m.Delete(i)
+		}
+
+		for i := 15; i < 20; i++ {
+			m.Rename(i, i+100)
+		}
+
+		m.StoreWithTTL(200, 0, time.Millisecond)
+		clock.Advance(time.Millisecond)
+
+		seen := make(map[int]int)
+		for i := 0; i < 10000; i++ {
+			key, value, ok := m.RandomEntry()
+			s.Require().True(ok)
+			s.Equal(key%100*10, value)
+			seen[key]++
+		}
+
+		s.ElementsMatch([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 115, 116, 117, 118, 119}, keysOf(seen))
+
+		if indexed {
+			for key, n := range seen {
+				s.InDelta(10000/15, n, 200, "key %d", key)
+			}
+		}
+
+		m.Clear()
+		_, _, ok = m.RandomEntry()
+		s.False(ok)
+
+		m.Close()
+	}
+}
+
+func (s *MapTestSuite) TestShardedRandomEntry() {
+	m := ttl.NewShardedMap[int, int](
+		ttl.WithShards(4),
+		ttl.WithSampleIndex(),
+		ttl.WithPruneInterval(0))
+	defer m.Close()
+
+	_, _, ok := m.RandomEntry()
+	s.False(ok)
+
+	for i := 0; i < 8; i++ {
+		m.Store(i, i)
+	}
+
+	// Keys renamed between shards are moved between their sample indexes
+	for i := 0; i < 8; i++ {
+		m.Rename(i, i+8)
+	}
+
+	seen := make(map[int]int)
+	for i := 0; i < 8000; i++ {
+		key, value, ok := m.RandomEntry()
+		s.Require().True(ok)
+		s.Equal(key-8, value)
+		seen[key]++
+	}
+
+	s.ElementsMatch([]int{8, 9, 10, 11, 12, 13, 14, 15}, keysOf(seen))
+
+	for key, n := range seen {
+		s.InDelta(1000, n, 200, "key %d", key)
+	}
+}
+
+func keysOf[K comparable, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
@@ -0,0 +1,18 @@
+module github.com/glenvan/ttl/v2/sessionstore
+
+go 1.21
+
+require (
+	github.com/glenvan/ttl/v2 v2.1.0
+	github.com/gorilla/securecookie v1.1.2
+	github.com/gorilla/sessions v1.2.2
+	github.com/stretchr/testify v1.8.4
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/glenvan/ttl/v2 => ../
@@ -0,0 +1,149 @@
+// Package sessionstore provides a [sessions.Store] for [Gorilla sessions] backed by a [ttl.Map], so
+// that session values are held on the server and only a signed session ID is sent in the cookie:
+//
+//	m := ttl.New[string, map[any]any](ttl.WithTTL(30 * time.Minute))
+//	defer m.Close()
+//
+//	store := sessionstore.New(m, []byte(os.Getenv("SESSION_KEY")))
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		session, _ := store.Get(r, "session")
+//		session.Values["user"] = "alice"
+//		_ = session.Save(r, w)
+//	}
+//
+// A session expires once it has been idle for the TTL of the Map, as loading it refreshes its last
+// access time (unless the Map is configured with ttl.WithRefreshOnLoad(false)). The Map may be
+// shared between processes using ttl.WithWriteThrough and ttl.WithReadThrough, for example with a
+// redisstore.Store, provided its value codec can encode the session values.
+//
+// [Gorilla sessions]: https://github.com/gorilla/sessions
+package sessionstore
+
+import (
+	"encoding/base32"
+	"maps"
+	"net/http"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+var base32RawStdEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Store is a [sessions.Store] that holds the values of each session in a [ttl.Map], keyed by
+// session ID. Store is safe for concurrent use.
+type Store struct {
+	// Codecs sign (and optionally encrypt) the session ID sent in the cookie.
+	Codecs []securecookie.Codec
+
+	// Options is the default configuration of the cookie of new sessions.
+	Options *sessions.Options
+
+	m *ttl.Map[string, map[any]any]
+}
+
+// New returns a [Store] that holds sessions in m, which remains owned by the caller, who must
+// close it once the Store is no longer needed. keyPairs are the authentication and encryption keys
+// of the session ID cookie, as for [sessions.NewCookieStore].
+func New(m *ttl.Map[string, map[any]any], keyPairs ...[]byte) *Store {
+	return &Store{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:     "/",
+			HttpOnly: true,
+		},
+		m: m,
+	}
+}
+
+// MaxAge sets the maximum age of the session ID cookie of new sessions, in seconds, like
+// [sessions.CookieStore.MaxAge]. The default of zero makes it a browser session cookie. Sessions
+// held by the [Store] expire after the TTL of the Map, whatever the maximum age of their cookie.
+func (s *Store) MaxAge(age int) {
+	s.Options.MaxAge = age
+
+	for _, codec := range s.Codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(age)
+		}
+	}
+}
+
+// Get returns the session name of the request, after adding it to the registry of the request, as
+// required by [sessions.Store]. See [sessions.CookieStore.Get].
+func (s *Store) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New returns the session name of the request, without adding it to the registry, as required by
+// [sessions.Store]. If the request has no valid session ID cookie, or its session has expired, the
+// session returned is new and empty. An error is returned only if the cookie could not be decoded.
+func (s *Store) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	if err := securecookie.DecodeMulti(name, c.Value, &session.ID, s.Codecs...); err != nil {
+		return session, err
+	}
+
+	// Loading the session refreshes it, so that it expires only once it is idle
+	if values, ok := s.m.Load(session.ID); ok {
+		session.Values = maps.Clone(values)
+		session.IsNew = false
+	}
+
+	return session, nil
+}
+
+// Save stores the values of session and sets its session ID cookie in the response, as required by
+// [sessions.Store]. Storing the session restarts its time to live. If the maximum age of session
+// is negative, it is removed from the [Store] and its cookie expired instead.
+func (s *Store) Save(_ *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		s.m.Delete(session.ID)
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = base32RawStdEncoding.EncodeToString(securecookie.GenerateRandomKey(32))
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+
+	// The values are copied, so that changes to the session are only seen once it is saved again
+	s.m.Store(session.ID, maps.Clone(session.Values))
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+
+	return nil
+}
+
+// Destroy removes session from the [Store] and expires its session ID cookie in the response, for
+// example when the user logs out. The values and ID of session are cleared, so that saving it
+// again starts a new session.
+func (s *Store) Destroy(w http.ResponseWriter, session *sessions.Session) {
+	s.m.Delete(session.ID)
+
+	opts := *session.Options
+	opts.MaxAge = -1
+	http.SetCookie(w, sessions.NewCookie(session.Name(), "", &opts))
+
+	clear(session.Values)
+	session.ID = ""
+	session.IsNew = true
+}
+
+var _ sessions.Store = (*Store)(nil)
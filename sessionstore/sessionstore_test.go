@@ -0,0 +1,138 @@
+package sessionstore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/sessionstore"
+	"github.com/glenvan/ttl/v2/ttltest"
+	"github.com/stretchr/testify/suite"
+)
+
+type StoreTestSuite struct {
+	suite.Suite
+
+	clock *ttltest.Clock
+	m     *ttl.Map[string, map[any]any]
+	store *sessionstore.Store
+}
+
+func TestStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(StoreTestSuite))
+}
+
+func (s *StoreTestSuite) SetupTest() {
+	s.clock = ttltest.NewClock(time.Unix(1700000000, 0))
+	s.m = ttl.New[string, map[any]any](
+		ttl.WithClock(s.clock),
+		ttl.WithTTL(30*time.Minute),
+		ttl.WithPruneInterval(0))
+	s.store = sessionstore.New(s.m, []byte("0123456789abcdef0123456789abcdef"))
+}
+
+func (s *StoreTestSuite) TearDownTest() {
+	s.m.Close()
+}
+
+// request returns a request carrying the cookies set by w, if any.
+func (s *StoreTestSuite) request(w *httptest.ResponseRecorder) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if w != nil {
+		for _, c := range w.Result().Cookies() {
+			r.AddCookie(c)
+		}
+	}
+
+	return r
+}
+
+func (s *StoreTestSuite) TestSaveLoad() {
+	session, err := s.store.Get(s.request(nil), "session")
+	s.Require().NoError(err)
+	s.True(session.IsNew)
+
+	session.Values["user"] = "alice"
+
+	w := httptest.NewRecorder()
+	s.Require().NoError(session.Save(s.request(nil), w))
+	s.NotEmpty(session.ID)
+	s.Equal(1, s.m.Length())
+
+	// The cookie carries the session ID, not the values
+	s.NotContains(w.Result().Cookies()[0].Value, "alice")
+
+	// Changes are not seen until the session is saved
+	session.Values["user"] = "bob"
+
+	loaded, err := s.store.New(s.request(w), "session")
+	s.Require().NoError(err)
+	s.False(loaded.IsNew)
+	s.Equal(session.ID, loaded.ID)
+	s.Equal("alice", loaded.Values["user"])
+}
+
+func (s *StoreTestSuite) TestSlidingExpiration() {
+	session, err := s.store.New(s.request(nil), "session")
+	s.Require().NoError(err)
+
+	w := httptest.NewRecorder()
+	s.Require().NoError(s.store.Save(s.request(nil), w, session))
+
+	// Each load refreshes the session
+	for i := 0; i < 3; i++ {
+		s.clock.Advance(20 * time.Minute)
+
+		loaded, err := s.store.New(s.request(w), "session")
+		s.Require().NoError(err)
+		s.False(loaded.IsNew)
+	}
+
+	s.clock.Advance(30 * time.Minute)
+
+	loaded, err := s.store.New(s.request(w), "session")
+	s.Require().NoError(err)
+	s.True(loaded.IsNew)
+	s.Empty(loaded.Values)
+}
+
+func (s *StoreTestSuite) TestDestroy() {
+	session, err := s.store.New(s.request(nil), "session")
+	s.Require().NoError(err)
+
+	session.Values["user"] = "alice"
+
+	w := httptest.NewRecorder()
+	s.Require().NoError(s.store.Save(s.request(nil), w, session))
+
+	destroyed := httptest.NewRecorder()
+	s.store.Destroy(destroyed, session)
+	s.Zero(s.m.Length())
+	s.Empty(session.Values)
+	s.Empty(session.ID)
+	s.Negative(destroyed.Result().Cookies()[0].MaxAge)
+
+	// The old cookie no longer finds the session
+	loaded, err := s.store.New(s.request(w), "session")
+	s.Require().NoError(err)
+	s.True(loaded.IsNew)
+
+	// A negative maximum age also destroys the session on save
+	s.Require().NoError(s.store.Save(s.request(nil), httptest.NewRecorder(), session))
+	s.Equal(1, s.m.Length())
+
+	session.Options.MaxAge = -1
+	s.Require().NoError(s.store.Save(s.request(nil), httptest.NewRecorder(), session))
+	s.Zero(s.m.Length())
+}
+
+func (s *StoreTestSuite) TestInvalidCookie() {
+	r := s.request(nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: "forged"})
+
+	session, err := s.store.New(r, "session")
+	s.Error(err)
+	s.True(session.IsNew)
+}
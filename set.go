@@ -0,0 +1,123 @@
+package ttl
+
+import (
+	"context"
+	"time"
+)
+
+// Set is a "time-to-live" set: an item added to it is removed once its time to live has elapsed
+// since it was last accessed. It behaves like a [Map] whose values are struct{}, which take no
+// space, and accepts the same [Option]s, with [Set.Contains] in place of [Map.Load].
+//
+// Options that concern values, such as [WithMissValue] or [WithWriteThrough], are given the value
+// type struct{}.
+type Set[T comparable] struct {
+	m *Map[T, struct{}]
+}
+
+// NewSet returns a new [Set] configured by opts, like [New].
+func NewSet[T comparable](opts ...Option) *Set[T] {
+	return &Set[T]{m: New[T, struct{}](opts...)}
+}
+
+// Add adds item to the [Set] with the default time to live, like [Map.Store]. If the item is
+// already in the [Set], its last access time is updated. Add is safe for concurrent use.
+func (s *Set[T]) Add(item T) {
+	s.m.Store(item, struct{}{})
+}
+
+// AddWithTTL adds item to the [Set] with a custom time to live, like [Map.StoreWithTTL]. A zero or
+// negative TTL means the item is never removed. AddWithTTL is safe for concurrent use.
+func (s *Set[T]) AddWithTTL(item T, TTL time.Duration) {
+	s.m.StoreWithTTL(item, struct{}{}, TTL)
+}
+
+// Contains reports whether item is in the [Set]. Like [Map.Load], it updates the item's last
+// access time if the [Set] refreshes on load. Contains is safe for concurrent use.
+func (s *Set[T]) Contains(item T) bool {
+	_, ok := s.m.Load(item)
+	return ok
+}
+
+// ContainsPassive is like [Set.Contains], but never updates the item's last access time.
+// ContainsPassive is safe for concurrent use.
+func (s *Set[T]) ContainsPassive(item T) bool {
+	_, ok := s.m.LoadPassive(item)
+	return ok
+}
+
+// TTL returns the time to live remaining for item, like [Map.TTL]. TTL is safe for concurrent use.
+func (s *Set[T]) TTL(item T) (remaining time.Duration, ok bool) {
+	return s.m.TTL(item)
+}
+
+// Touch updates the last access time of item, like [Map.Touch]. Touch returns false if the item is
+// not in the [Set]. Touch is safe for concurrent use.
+func (s *Set[T]) Touch(item T) bool {
+	return s.m.Touch(item)
+}
+
+// Remove removes item from the [Set]. Remove is safe for concurrent use.
+func (s *Set[T]) Remove(item T) {
+	s.m.Delete(item)
+}
+
+// Clear removes every item from the [Set]. Clear is safe for concurrent use.
+func (s *Set[T]) Clear() {
+	s.m.Clear()
+}
+
+// Length returns the number of items in the [Set], like [Map.Length]. Length is safe for concurrent
+// use.
+func (s *Set[T]) Length() int {
+	return s.m.Length()
+}
+
+// Range calls f sequentially for each item in the [Set], like [Map.Range]. If f returns false,
+// Range stops the iteration. Range is safe for concurrent use.
+func (s *Set[T]) Range(f func(item T) bool) {
+	s.m.Range(func(item T, _ struct{}) bool {
+		return f(item)
+	})
+}
+
+// RangeContext is like [Set.Range], stopping with ctx.Err() if ctx is done, like
+// [Map.RangeContext].
+func (s *Set[T]) RangeContext(ctx context.Context, f func(item T) bool) error {
+	return s.m.RangeContext(ctx, func(item T, _ struct{}) bool {
+		return f(item)
+	})
+}
+
+// Items returns the items in the [Set], in no particular order. Items is safe for concurrent use.
+func (s *Set[T]) Items() []T {
+	items := make([]T, 0, s.m.Length())
+	s.Range(func(item T) bool {
+		items = append(items, item)
+		return true
+	})
+
+	return items
+}
+
+// Prune removes the items whose time to live has elapsed, like [Map.Prune], returning the number
+// removed.
+func (s *Set[T]) Prune() int {
+	return s.m.Prune()
+}
+
+// Stats returns the statistics of the [Set], like [Map.Stats].
+func (s *Set[T]) Stats() Stats {
+	return s.m.Stats()
+}
+
+// Close stops the [Set] from pruning, like [Map.Close].
+func (s *Set[T]) Close() {
+	s.m.Close()
+}
+
+// CloseWait is like [Set.Close], but also waits for the background goroutines of the [Set] to
+// exit, like [Map.CloseWait].
+func (s *Set[T]) CloseWait() {
+	s.m.CloseWait()
+}
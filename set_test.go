@@ -0,0 +1,56 @@
+package ttl_test
+
+import (
+	"sort"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestSet() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	set := ttl.NewSet[string](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0))
+	defer set.Close()
+
+	set.Add("a")
+	set.Add("b")
+	set.AddWithTTL("c", 0)
+
+	s.True(set.Contains("a"))
+	s.False(set.Contains("d"))
+	s.Equal(3, set.Length())
+
+	set.Remove("b")
+	s.False(set.ContainsPassive("b"))
+
+	clock.Advance(30 * time.Second)
+	s.True(set.Contains("a"))
+
+	remaining, ok := set.TTL("a")
+	s.True(ok)
+	s.Equal(time.Minute, remaining)
+
+	clock.Advance(time.Minute)
+	s.False(set.Contains("a"))
+	s.Equal(1, set.Prune())
+
+	set.Add("e")
+	items := set.Items()
+	sort.Strings(items)
+	s.Equal([]string{"c", "e"}, items)
+
+	n := 0
+	set.Range(func(item string) bool {
+		n++
+		return false
+	})
+	s.Equal(1, n)
+
+	set.Clear()
+	s.Zero(set.Length())
+}
@@ -0,0 +1,864 @@
+package ttl
+
+import (
+	"context"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShardedMap is a "time-to-live" map with the same behaviour as [Map], that partitions its keys
+// across a number of independently locked shards. Operations on keys in different shards do not
+// contend with each other, so a ShardedMap scales better than a [Map] under write-heavy concurrent
+// use. A single goroutine prunes every shard.
+//
+// Operations that involve every key, such as [ShardedMap.Range], [ShardedMap.Length] and
+// [ShardedMap.Snapshot], visit one shard at a time, so they do not observe a single consistent
+// state of the map while it is being modified concurrently.
+//
+// ShardedMap is safe for concurrent use.
+type ShardedMap[K comparable, V any] struct {
+	shards   []*Map[K, V]
+	hash     func(K) uint64
+	stop     chan bool
+	done     chan struct{}
+	finished []<-chan struct{} // closed when the other background goroutines have exited
+	closed   atomic.Bool
+	paused   atomic.Bool
+
+	cancelMtx   sync.Mutex
+	cancelStops []func() bool // unregister functions for contexts added with AlsoCancelOn
+}
+
+// NewShardedMap returns a new [ShardedMap] configured by opts, with the same options and defaults
+// as [New]. The number of shards and the assignment of keys to shards may be configured with
+// [WithShards] and [WithHasher]. The capacity set by [WithCapacity] is divided between the shards.
+//
+// ShardedMap objects returned by NewShardedMap must be closed with [ShardedMap.Close] when they're
+// no longer needed, unless a context was provided using [WithContext] and it is guaranteed to be
+// cancelled.
+func NewShardedMap[K comparable, V any](opts ...Option) (s *ShardedMap[K, V]) {
+	o := defaultOptions()
+	o.apply(opts)
+	o.logMisconfiguration()
+
+	s = newShardedMap[K, V](o)
+	s.start(o)
+
+	return
+}
+
+// newShardedMap returns a new [ShardedMap] configured by o, without starting its background
+// goroutines.
+func newShardedMap[K comparable, V any](o options) (s *ShardedMap[K, V]) {
+	n := o.shards
+	if n <= 0 {
+		n = defaultShards()
+	}
+
+	s = &ShardedMap[K, V]{
+		shards: make([]*Map[K, V], n),
+		hash:   typedOption[func(K) uint64]("WithHasher", o.hasher),
+		stop:   make(chan bool),
+		done:   make(chan struct{}),
+	}
+
+	if s.hash == nil {
+		s.hash = defaultHasher[K]()
+	}
+
+	// The shards are saved together by the ShardedMap
+	shardOptions := o
+	shardOptions.snapshotPath = ""
+	shardOptions.capacity = max(o.capacity, 0) / n
+	if o.maxEntries > 0 {
+		shardOptions.maxEntries = max(o.maxEntries/n, 1)
+	}
+	if o.maxCost > 0 {
+		shardOptions.maxCost = max(o.maxCost/int64(n), 1)
+	}
+
+	var expired *expiredStream[K, V]
+	if o.expiredBuffer > 0 {
+		expired = newExpiredStream[K, V](o.expiredBuffer)
+	}
+
+	// The shards share a single write-behind queue
+	var behind *writeBehind[K, V]
+	if o.writeBehind {
+		store := typedOption[Store[K, V]]("WithWriteBehind", o.store)
+		behind = newWriteBehind(store, context.WithoutCancel(o.ctx), o.logger)
+	}
+
+	for i := range s.shards {
+		s.shards[i] = newMap[K, V](shardOptions)
+		s.shards[i].expired = expired
+		s.shards[i].behind = behind
+		close(s.shards[i].done)
+	}
+
+	return
+}
+
+// start is like [Map.start].
+func (s *ShardedMap[K, V]) start(o options) {
+	if o.pruneInterval > 0 {
+		go s.prune(o.ctx, o.clock.NewTicker(o.jitteredPruneInterval()), o.pruneInterval)
+	} else {
+		close(s.done)
+	}
+
+	if o.snapshotPath != "" {
+		s.finished = append(s.finished, startAutoSave(o, s, s.stop))
+	}
+
+	if behind := s.shards[0].behind; behind != nil {
+		s.finished = append(s.finished, behind.start(o, s.Close, s.stop))
+	}
+}
+
+// defaultShards returns the default number of shards: four per processor, so that concurrent
+// operations rarely contend for a shard.
+func defaultShards() int {
+	return 4 * runtime.GOMAXPROCS(0)
+}
+
+// Shards returns the number of shards of the map.
+func (s *ShardedMap[K, V]) Shards() int {
+	return len(s.shards)
+}
+
+// ShardOf returns the index, between 0 and [ShardedMap.Shards] - 1, of the shard that holds key.
+// Operations on keys in different shards never contend with each other, so applications can use
+// ShardOf to align their own per-shard workers or queues with the map's sharding.
+func (s *ShardedMap[K, V]) ShardOf(key K) int {
+	return s.shardIndex(key)
+}
+
+// shard returns the shard that holds key.
+func (s *ShardedMap[K, V]) shard(key K) *Map[K, V] {
+	return s.shards[s.shardIndex(key)]
+}
+
+// shardIndex returns the index of the shard that holds key.
+func (s *ShardedMap[K, V]) shardIndex(key K) int {
+	return int(s.hash(key) % uint64(len(s.shards)))
+}
+
+// lockShards write-locks the shards with indexes i and j, which must differ, in index order so that
+// concurrent callers cannot deadlock. It returns a function that unlocks them, reporting their
+// removals once both are unlocked.
+func (s *ShardedMap[K, V]) lockShards(i int, j int) (unlock func()) {
+	first, second := s.shards[min(i, j)], s.shards[max(i, j)]
+
+	first.lock()
+	second.lock()
+
+	return func() {
+		unlockAll(second, first)
+	}
+}
+
+// unlockAll releases the write locks of maps, then makes the writes of each to its store, then
+// reports the removals of each, so that no callback runs while any of the locks is held.
+func unlockAll[K comparable, V any](maps ...*Map[K, V]) {
+	rs := make([]released[K, V], len(maps))
+	for i, m := range maps {
+		rs[i] = m.release()
+	}
+
+	for _, r := range rs {
+		r.write()
+	}
+
+	for _, r := range rs {
+		r.report()
+	}
+}
+
+// prune prunes every shard on each tick of ticker, which ticks every pruneInterval, until the map
+// is closed or ctx is cancelled.
+func (s *ShardedMap[K, V]) prune(ctx context.Context, ticker Ticker, pruneInterval time.Duration) {
+	defer close(s.done)
+	defer ticker.Stop()
+
+	// Strategies that repeat share the prune interval between the shards
+	budget := pruneInterval / time.Duration(len(s.shards))
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.Close()
+			return
+		case <-s.stop:
+			return
+		case <-ticker.C():
+			if s.paused.Load() {
+				continue
+			}
+
+			start := time.Now()
+			now := s.shards[0].clock.Now()
+
+			pruned := 0
+			for _, shard := range s.shards {
+				pruned += shard.pruneTick(now, budget)
+			}
+
+			if logger := s.shards[0].logger; logger != nil {
+				logger.Debug("prune pass", "pruned", pruned, "length", s.Length(), "duration", time.Since(start))
+			}
+		}
+	}
+}
+
+// Prune is like [Map.Prune], running a full prune pass over every shard.
+func (s *ShardedMap[K, V]) Prune() (pruned int) {
+	for _, shard := range s.shards {
+		pruned += shard.Prune()
+	}
+
+	return
+}
+
+// Close is like [Map.Close].
+func (s *ShardedMap[K, V]) Close() {
+	if s.closed.CompareAndSwap(false, true) {
+		close(s.stop)
+
+		if expired := s.shards[0].expired; expired != nil {
+			expired.close()
+		}
+
+		s.cancelMtx.Lock()
+		for _, stop := range s.cancelStops {
+			stop()
+		}
+		s.cancelStops = nil
+		s.cancelMtx.Unlock()
+	}
+}
+
+// CloseWait is like [Map.CloseWait].
+func (s *ShardedMap[K, V]) CloseWait() {
+	s.Close()
+	<-s.done
+
+	for _, finished := range s.finished {
+		<-finished
+	}
+}
+
+// AlsoCancelOn is like [Map.AlsoCancelOn].
+func (s *ShardedMap[K, V]) AlsoCancelOn(ctx context.Context) {
+	s.cancelMtx.Lock()
+	defer s.cancelMtx.Unlock()
+
+	if s.closed.Load() {
+		return
+	}
+
+	s.cancelStops = append(s.cancelStops, context.AfterFunc(ctx, s.Close))
+}
+
+// PausePruning is like [Map.PausePruning].
+func (s *ShardedMap[K, V]) PausePruning() {
+	s.paused.Store(true)
+}
+
+// ResumePruning is like [Map.ResumePruning].
+func (s *ShardedMap[K, V]) ResumePruning() {
+	s.paused.Store(false)
+}
+
+// Length returns the number of items in the map, summed across the shards.
+func (s *ShardedMap[K, V]) Length() (n int) {
+	for _, shard := range s.shards {
+		n += shard.Length()
+	}
+
+	return
+}
+
+// Load is like [Map.Load].
+func (s *ShardedMap[K, V]) Load(key K) (value V, ok bool) {
+	return s.shard(key).Load(key)
+}
+
+// LoadOrStore is like [Map.LoadOrStore].
+func (s *ShardedMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	return s.shard(key).LoadOrStore(key, value)
+}
+
+// StoreMany is like [Map.StoreMany], acquiring the lock on each shard once.
+func (s *ShardedMap[K, V]) StoreMany(entries map[K]V) {
+	for i, batch := range partition(s, entries) {
+		s.shards[i].StoreMany(batch)
+	}
+}
+
+// StoreManyWithTTL is like [Map.StoreManyWithTTL], acquiring the lock on each shard once.
+func (s *ShardedMap[K, V]) StoreManyWithTTL(entries map[K]TTLValue[V]) {
+	for i, batch := range partition(s, entries) {
+		s.shards[i].StoreManyWithTTL(batch)
+	}
+}
+
+// StoreTagged is like [Map.StoreTagged].
+func (s *ShardedMap[K, V]) StoreTagged(key K, value V, tags ...string) {
+	s.shard(key).StoreTagged(key, value, tags...)
+}
+
+// DeleteByTag is like [Map.DeleteByTag], deleting from one shard at a time.
+func (s *ShardedMap[K, V]) DeleteByTag(tag string) (deleted int) {
+	for _, shard := range s.shards {
+		deleted += shard.DeleteByTag(tag)
+	}
+
+	return
+}
+
+// StoreInGroup is like [Map.StoreInGroup].
+func (s *ShardedMap[K, V]) StoreInGroup(group string, key K, value V) {
+	s.shard(key).StoreInGroup(group, key, value)
+}
+
+// ExpireGroup is like [Map.ExpireGroup], expiring entries in one shard at a time.
+func (s *ShardedMap[K, V]) ExpireGroup(group string) (expired int) {
+	for _, shard := range s.shards {
+		expired += shard.ExpireGroup(group)
+	}
+
+	return
+}
+
+// LoadAndDelete is like [Map.LoadAndDelete].
+func (s *ShardedMap[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	return s.shard(key).LoadAndDelete(key)
+}
+
+// LoadContext is like [Map.LoadContext].
+func (s *ShardedMap[K, V]) LoadContext(ctx context.Context, key K) (value V, ok bool, err error) {
+	return s.shard(key).LoadContext(ctx, key)
+}
+
+// LoadPassive is like [Map.LoadPassive].
+func (s *ShardedMap[K, V]) LoadPassive(key K) (value V, ok bool) {
+	return s.shard(key).LoadPassive(key)
+}
+
+// LoadStale is like [Map.LoadStale].
+func (s *ShardedMap[K, V]) LoadStale(key K) (value V, stale bool, ok bool) {
+	return s.shard(key).LoadStale(key)
+}
+
+// Touch is like [Map.Touch].
+func (s *ShardedMap[K, V]) Touch(key K) bool {
+	return s.shard(key).Touch(key)
+}
+
+// Extend is like [Map.Extend].
+func (s *ShardedMap[K, V]) Extend(key K, extra time.Duration) bool {
+	return s.shard(key).Extend(key, extra)
+}
+
+// SetTTL is like [Map.SetTTL].
+func (s *ShardedMap[K, V]) SetTTL(key K, TTL time.Duration) bool {
+	return s.shard(key).SetTTL(key, TTL)
+}
+
+// SetDefaultTTL is like [Map.SetDefaultTTL]. Each shard is updated in turn.
+func (s *ShardedMap[K, V]) SetDefaultTTL(TTL time.Duration, updateExisting bool) {
+	for _, shard := range s.shards {
+		shard.SetDefaultTTL(TTL, updateExisting)
+	}
+}
+
+// TTL is like [Map.TTL].
+func (s *ShardedMap[K, V]) TTL(key K) (remaining time.Duration, ok bool) {
+	return s.shard(key).TTL(key)
+}
+
+// ExpirationTime is like [Map.ExpirationTime].
+func (s *ShardedMap[K, V]) ExpirationTime(key K) (expireAt time.Time, ok bool) {
+	return s.shard(key).ExpirationTime(key)
+}
+
+// RandomEntry is like [Map.RandomEntry], choosing a shard with a probability proportional to its
+// length, so that every entry is equally likely if [WithSampleIndex] is used and no entries have
+// expired but have not been pruned yet.
+func (s *ShardedMap[K, V]) RandomEntry() (key K, value V, ok bool) {
+	lengths := make([]int, len(s.shards))
+	total := 0
+
+	for i, shard := range s.shards {
+		lengths[i] = shard.Length()
+		total += lengths[i]
+	}
+
+	if total == 0 {
+		return
+	}
+
+	first := 0
+	for r := rand.Intn(total); r >= lengths[first]; first++ {
+		r -= lengths[first]
+	}
+
+	// If every entry of the shard has expired, the next shards are tried
+	for i := range s.shards {
+		if key, value, ok = s.shards[(first+i)%len(s.shards)].RandomEntry(); ok {
+			return
+		}
+	}
+
+	return
+}
+
+// NextExpiration is like [Map.NextExpiration], returning the soonest of the shards.
+func (s *ShardedMap[K, V]) NextExpiration() (expireAt time.Time, ok bool) {
+	for _, shard := range s.shards {
+		if t, found := shard.NextExpiration(); found && (!ok || t.Before(expireAt)) {
+			expireAt, ok = t, true
+		}
+	}
+
+	return
+}
+
+// KeysExpiringBefore is like [Map.KeysExpiringBefore].
+func (s *ShardedMap[K, V]) KeysExpiringBefore(t time.Time) (keys []K) {
+	for _, shard := range s.shards {
+		keys = append(keys, shard.KeysExpiringBefore(t)...)
+	}
+
+	return
+}
+
+// KeysExpiringAfter is like [Map.KeysExpiringAfter].
+func (s *ShardedMap[K, V]) KeysExpiringAfter(t time.Time) (keys []K) {
+	for _, shard := range s.shards {
+		keys = append(keys, shard.KeysExpiringAfter(t)...)
+	}
+
+	return
+}
+
+// Store is like [Map.Store].
+func (s *ShardedMap[K, V]) Store(key K, value V) {
+	s.shard(key).Store(key, value)
+}
+
+// StoreWithTTL is like [Map.StoreWithTTL].
+func (s *ShardedMap[K, V]) StoreWithTTL(key K, value V, TTL time.Duration) {
+	s.shard(key).StoreWithTTL(key, value, TTL)
+}
+
+// StoreWithPolicy is like [Map.StoreWithPolicy].
+func (s *ShardedMap[K, V]) StoreWithPolicy(key K, value V, TTL time.Duration, policy RefreshPolicy) {
+	s.shard(key).StoreWithPolicy(key, value, TTL, policy)
+}
+
+// StoreWithExpireAt is like [Map.StoreWithExpireAt].
+func (s *ShardedMap[K, V]) StoreWithExpireAt(key K, value V, expireAt time.Time) {
+	s.shard(key).StoreWithExpireAt(key, value, expireAt)
+}
+
+// StoreDerived is like [Map.StoreDerived]. If newKey and fromKey are in different shards, both
+// shards are locked for the duration of the store.
+func (s *ShardedMap[K, V]) StoreDerived(newKey K, value V, fromKey K) bool {
+	to, from := s.shardIndex(newKey), s.shardIndex(fromKey)
+	if to == from {
+		return s.shards[to].StoreDerived(newKey, value, fromKey)
+	}
+
+	dst := s.shards[to]
+	if q := dst.stats.quantiles; q != nil {
+		defer q.storeLatency.observeSince(time.Now())
+	}
+
+	unlock := s.lockShards(to, from)
+	defer unlock()
+
+	it, ok := s.shards[from].liveItemLocked(fromKey)
+	if !ok {
+		return false
+	}
+
+	dst.storeDerivedLocked(newKey, value, it)
+
+	return true
+}
+
+// Merge is like [Map.Merge], merging other into the map one shard of other at a time.
+func (s *ShardedMap[K, V]) Merge(other *ShardedMap[K, V], resolve MergeFunc[K, V]) {
+	if other == nil || other == s {
+		return
+	}
+
+	now := s.shards[0].now()
+
+	for _, src := range other.shards {
+		entries := src.liveClones(now)
+
+		byShard := make(map[int][]keyedItem[K, V])
+		for _, e := range entries {
+			i := s.shardIndex(e.key)
+			byShard[i] = append(byShard[i], e)
+		}
+
+		for i, entries := range byShard {
+			dst := s.shards[i]
+
+			dst.lock()
+			for _, e := range entries {
+				dst.mergeItemLocked(e.key, e.it, now, resolve)
+			}
+			dst.unlock()
+		}
+	}
+}
+
+// MergeMap is like [Map.MergeMap].
+func (s *ShardedMap[K, V]) MergeMap(src map[K]V, resolve MergeFunc[K, V]) {
+	byShard := make(map[int]map[K]V)
+	for key, value := range src {
+		i := s.shardIndex(key)
+		if byShard[i] == nil {
+			byShard[i] = make(map[K]V)
+		}
+		byShard[i][key] = value
+	}
+
+	for i, src := range byShard {
+		s.shards[i].MergeMap(src, resolve)
+	}
+}
+
+// Update is like [Map.Update], holding the write lock of every shard while f runs, so it blocks
+// every other operation on the [ShardedMap].
+func (s *ShardedMap[K, V]) Update(f func(tx *Tx[K, V])) {
+	for _, shard := range s.shards {
+		shard.lock()
+	}
+
+	// Every shard is unlocked before any callback runs, since a callback may use any shard
+	defer unlockAll(s.shards...)
+
+	tx := &Tx[K, V]{shard: s.shard}
+	defer tx.finish()
+
+	f(tx)
+}
+
+// Rename is like [Map.Rename]. If oldKey and newKey are in different shards, both shards are locked
+// for the duration of the move, so it remains atomic.
+func (s *ShardedMap[K, V]) Rename(oldKey K, newKey K) bool {
+	from, to := s.shardIndex(oldKey), s.shardIndex(newKey)
+	if from == to {
+		return s.shards[from].Rename(oldKey, newKey)
+	}
+
+	unlock := s.lockShards(from, to)
+	defer unlock()
+
+	src, dst := s.shards[from], s.shards[to]
+
+	it, ok := src.liveItemLocked(oldKey)
+	if !ok {
+		return false
+	}
+
+	if old, ok := dst.m[newKey]; ok {
+		dst.replacedLocked(newKey, old.value)
+		dst.totalCost -= old.cost
+		dst.unlabelLocked(newKey, old)
+	}
+
+	// The cost is added to dst when the value is stored to newKey
+	src.totalCost -= it.cost
+	it.cost = 0
+
+	delete(src.m, oldKey)
+	if src.expiry != nil {
+		src.expiry.Remove(oldKey)
+	}
+	src.notifyLocked(oldKey, Event[V]{Kind: EventRemoved, Value: it.value, Reason: ReasonDeleted})
+	src.deletedLocked(oldKey)
+
+	if src.prefixes != nil {
+		src.prefixes.remove(oldKey)
+		dst.prefixes.insert(newKey)
+	}
+
+	if src.samples != nil {
+		src.samples.remove(oldKey)
+		dst.samples.insert(newKey)
+	}
+
+	tags, group := src.unlabelLocked(oldKey, it)
+
+	dst.m[newKey] = it
+	dst.labelLocked(newKey, it, tags, group)
+	dst.recheckLocked(newKey)
+	dst.storedLocked(newKey)
+
+	return true
+}
+
+// Expire is like [Map.Expire].
+func (s *ShardedMap[K, V]) Expire(key K) bool {
+	return s.shard(key).Expire(key)
+}
+
+// Pin is like [Map.Pin].
+func (s *ShardedMap[K, V]) Pin(key K) bool {
+	return s.shard(key).Pin(key)
+}
+
+// Unpin is like [Map.Unpin].
+func (s *ShardedMap[K, V]) Unpin(key K) bool {
+	return s.shard(key).Unpin(key)
+}
+
+// Delete is like [Map.Delete].
+func (s *ShardedMap[K, V]) Delete(key K) {
+	s.shard(key).Delete(key)
+}
+
+// DeleteFunc is like [Map.DeleteFunc], visiting one shard at a time. Only the shard being visited
+// is locked while del runs.
+func (s *ShardedMap[K, V]) DeleteFunc(del func(key K, value V) bool) {
+	for _, shard := range s.shards {
+		shard.DeleteFunc(del)
+	}
+}
+
+// DeleteFuncContext is like [Map.DeleteFuncContext], visiting one shard at a time.
+func (s *ShardedMap[K, V]) DeleteFuncContext(ctx context.Context, del func(key K, value V) bool) error {
+	for _, shard := range s.shards {
+		if err := shard.DeleteFuncContext(ctx, del); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Clear is like [Map.Clear], clearing one shard at a time.
+func (s *ShardedMap[K, V]) Clear() {
+	for _, shard := range s.shards {
+		shard.Clear()
+	}
+}
+
+// Range is like [Map.Range], visiting one shard at a time. Only the shard being visited is locked
+// while f runs, but as with [Map.Range], f must not call any method of the ShardedMap.
+func (s *ShardedMap[K, V]) Range(f func(key K, value V) bool) {
+	stopped := false
+
+	for _, shard := range s.shards {
+		shard.Range(func(key K, value V) bool {
+			stopped = !f(key, value)
+			return !stopped
+		})
+
+		if stopped {
+			return
+		}
+	}
+}
+
+// RangeContext is like [Map.RangeContext], visiting one shard at a time.
+func (s *ShardedMap[K, V]) RangeContext(ctx context.Context, f func(key K, value V) bool) error {
+	stopped := false
+
+	for _, shard := range s.shards {
+		err := shard.RangeContext(ctx, func(key K, value V) bool {
+			stopped = !f(key, value)
+			return !stopped
+		})
+
+		if err != nil || stopped {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Snapshot is like [Map.Snapshot], capturing one shard at a time.
+func (s *ShardedMap[K, V]) Snapshot() (snapshot Snapshot[K, V]) {
+	snapshot.Taken = s.shards[0].clock.Now()
+
+	for _, shard := range s.shards {
+		snapshot.Entries = append(snapshot.Entries, shard.Snapshot().Entries...)
+	}
+
+	return
+}
+
+// Entries is like [Map.Entries], listing one shard at a time.
+func (s *ShardedMap[K, V]) Entries() (entries []Entry[K, V]) {
+	for _, shard := range s.shards {
+		entries = append(entries, shard.Entries()...)
+	}
+
+	return
+}
+
+// Oldest is like [Map.Oldest], comparing the oldest entry of each shard.
+func (s *ShardedMap[K, V]) Oldest() (entry Entry[K, V], ok bool) {
+	for _, shard := range s.shards {
+		if e, found := shard.Oldest(); found && (!ok || e.LastAccess.Before(entry.LastAccess)) {
+			entry, ok = e, true
+		}
+	}
+
+	return
+}
+
+// Newest is like [Map.Newest], comparing the newest entry of each shard.
+func (s *ShardedMap[K, V]) Newest() (entry Entry[K, V], ok bool) {
+	for _, shard := range s.shards {
+		if e, found := shard.Newest(); found && (!ok || e.LastAccess.After(entry.LastAccess)) {
+			entry, ok = e, true
+		}
+	}
+
+	return
+}
+
+// RangeByExpiry is like [Map.RangeByExpiry], ordering the entries of every shard together.
+func (s *ShardedMap[K, V]) RangeByExpiry(f func(key K, value V, remaining time.Duration) bool) {
+	_ = s.RangeByExpiryContext(context.Background(), f)
+}
+
+// RangeByExpiryContext is like [Map.RangeByExpiryContext], ordering the entries of every shard
+// together.
+func (s *ShardedMap[K, V]) RangeByExpiryContext(
+	ctx context.Context,
+	f func(key K, value V, remaining time.Duration) bool,
+) error {
+	return rangeByExpiry(ctx, s.Entries(), f)
+}
+
+// Filter is like [Map.Filter], returning a new [ShardedMap] configured by opts like
+// [NewShardedMap].
+func (s *ShardedMap[K, V]) Filter(
+	pred func(key K, value V) bool,
+	opts ...Option,
+) *ShardedMap[K, V] {
+	filtered := NewShardedMap[K, V](opts...)
+	filtered.Restore(filterSnapshot(s.Snapshot(), pred), RebasePolicy{Mode: RebaseResume})
+
+	return filtered
+}
+
+// Restore is like [Map.Restore], restoring one shard at a time.
+func (s *ShardedMap[K, V]) Restore(snapshot Snapshot[K, V], policy RebasePolicy) {
+	byShard := make(map[int][]SnapshotEntry[K, V])
+	for _, e := range snapshot.Entries {
+		i := s.shardIndex(e.Key)
+		byShard[i] = append(byShard[i], e)
+	}
+
+	for i, entries := range byShard {
+		s.shards[i].Restore(Snapshot[K, V]{Taken: snapshot.Taken, Entries: entries}, policy)
+	}
+}
+
+// Metadata is like [Map.Metadata].
+func (s *ShardedMap[K, V]) Metadata(key K) (info EntryInfo, ok bool) {
+	return s.shard(key).Metadata(key)
+}
+
+// Watch is like [Map.Watch].
+func (s *ShardedMap[K, V]) Watch(key K) (events <-chan Event[V], cancel func()) {
+	return s.shard(key).Watch(key)
+}
+
+// Expired is like [Map.Expired]. A single channel receives the entries that expire from every
+// shard.
+func (s *ShardedMap[K, V]) Expired() <-chan Entry[K, V] {
+	return s.shards[0].Expired()
+}
+
+// Stats is like [Map.Stats], combining the statistics of every shard.
+func (s *ShardedMap[K, V]) Stats() (stats Stats) {
+	var evictionAge, loadLatency, storeLatency [histogramBuckets]uint64
+
+	for _, shard := range s.shards {
+		stats.Length += shard.Length()
+		stats.Hits += shard.stats.hits.Load()
+		stats.Misses += shard.stats.misses.Load()
+		stats.Stores += shard.stats.stores.Load()
+		stats.Removals = stats.Removals.add(shard.stats.removalCounts())
+
+		if shard.costOf != nil {
+			shard.mtx.RLock()
+			stats.Cost += shard.totalCost
+			shard.mtx.RUnlock()
+		}
+
+		if q := shard.stats.quantiles; q != nil {
+			q.evictionAge.addTo(&evictionAge)
+			q.loadLatency.addTo(&loadLatency)
+			q.storeLatency.addTo(&storeLatency)
+		}
+
+		if shard.statsScope != nil {
+			if stats.Scopes == nil {
+				stats.Scopes = make(map[string]ScopeStats)
+			}
+
+			for name, scope := range shard.scopeStats() {
+				total := stats.Scopes[name]
+				total.Entries += scope.Entries
+				total.Hits += scope.Hits
+				total.Misses += scope.Misses
+				total.Evictions += scope.Evictions
+				stats.Scopes[name] = total
+			}
+		}
+	}
+
+	stats.EvictionAge = quantilesOf(&evictionAge)
+	stats.LoadLatency = quantilesOf(&loadLatency)
+	stats.StoreLatency = quantilesOf(&storeLatency)
+
+	if expired := s.shards[0].expired; expired != nil {
+		stats.ExpiredDropped = expired.dropped.Load()
+	}
+
+	return
+}
+
+// ResetStats is like [Map.ResetStats], resetting the statistics of every shard.
+func (s *ShardedMap[K, V]) ResetStats() {
+	for _, shard := range s.shards {
+		shard.stats.reset()
+	}
+
+	if expired := s.shards[0].expired; expired != nil {
+		expired.dropped.Store(0)
+	}
+}
+
+// partition splits entries by the index of the shard of s their keys belong to.
+func partition[K comparable, V any, E any](s *ShardedMap[K, V], entries map[K]E) map[int]map[K]E {
+	byShard := make(map[int]map[K]E)
+
+	for key, e := range entries {
+		i := s.shardIndex(key)
+		if byShard[i] == nil {
+			byShard[i] = make(map[K]E)
+		}
+
+		byShard[i][key] = e
+	}
+
+	return byShard
+}
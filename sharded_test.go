@@ -0,0 +1,308 @@
+package ttl_test
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) newShardedMap(opts ...ttl.Option) *ttl.ShardedMap[int, int] {
+	return ttl.NewShardedMap[int, int](append([]ttl.Option{
+		ttl.WithTTL(s.maxTTL),
+		ttl.WithPruneInterval(s.pruneInterval),
+	}, opts...)...)
+}
+
+func (s *MapTestSuite) TestShardedMap() {
+	tm := s.newShardedMap()
+	defer tm.Close()
+
+	for i := 0; i < 100; i++ {
+		tm.Store(i, i)
+	}
+	tm.StoreWithTTL(100, 100, time.Minute)
+
+	s.Equal(101, tm.Length())
+
+	v, ok := tm.Load(42)
+	s.True(ok)
+	s.Equal(42, v)
+
+	tm.Delete(42)
+	_, ok = tm.Load(42)
+	s.False(ok)
+
+	time.Sleep(s.sleepTime)
+
+	s.Equal(1, tm.Length())
+
+	stats := tm.Stats()
+	s.Equal(1, stats.Length)
+	s.Equal(uint64(99), stats.Removals.Expired)
+	s.Equal(uint64(1), stats.Removals.Deleted)
+}
+
+func (s *MapTestSuite) TestShardedMapAcrossShards() {
+	tm := s.newShardedMap(ttl.WithTTL(time.Minute))
+	defer tm.Close()
+
+	for i := 0; i < 100; i++ {
+		tm.Store(i, i)
+	}
+
+	// With many keys, some of these operations are bound to span two shards
+	for i := 0; i < 50; i++ {
+		s.True(tm.Rename(i, i+1000))
+		s.True(tm.StoreDerived(i+2000, i, i+50))
+	}
+
+	for i := 0; i < 50; i++ {
+		_, ok := tm.LoadPassive(i)
+		s.False(ok)
+
+		v, ok := tm.LoadPassive(i + 1000)
+		s.True(ok)
+		s.Equal(i, v)
+
+		derivedExpiry, _ := tm.ExpirationTime(i + 2000)
+		fromExpiry, _ := tm.ExpirationTime(i + 50)
+		s.WithinDuration(fromExpiry, derivedExpiry, 10*time.Millisecond)
+	}
+
+	s.False(tm.Rename(0, 1))
+	s.Equal(150, tm.Length())
+}
+
+func (s *MapTestSuite) TestShardedMapRenameCallback() {
+	var tm *ttl.ShardedMap[int, int]
+	var reasons []ttl.RemovalReason
+
+	tm = s.newShardedMap(
+		ttl.WithShards(4),
+		ttl.WithHasher(func(key int) uint64 { return uint64(key) }),
+		ttl.WithOnRemove(func(_ int, _ int, reason ttl.RemovalReason) {
+			// Both shards of the rename are unlocked before callbacks run
+			tm.Load(1)
+			reasons = append(reasons, reason)
+		}))
+	defer tm.Close()
+
+	tm.Store(1, 1)
+	tm.Store(3, 3)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.True(tm.Rename(1, 3))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		s.FailNow("Rename deadlocked")
+	}
+
+	s.Equal([]ttl.RemovalReason{ttl.ReasonReplaced}, reasons)
+}
+
+func (s *MapTestSuite) TestShardedMapRange() {
+	tm := s.newShardedMap(ttl.WithTTL(time.Minute))
+	defer tm.Close()
+
+	for i := 0; i < 100; i++ {
+		tm.Store(i, i)
+	}
+
+	sum := 0
+	tm.Range(func(_ int, value int) bool {
+		sum += value
+		return true
+	})
+	s.Equal(4950, sum)
+
+	visited := 0
+	tm.Range(func(int, int) bool {
+		visited++
+		return visited < 10
+	})
+	s.Equal(10, visited)
+
+	tm.DeleteFunc(func(key int, _ int) bool {
+		return key%2 == 0
+	})
+	s.Equal(50, tm.Length())
+
+	tm.Clear()
+	s.Zero(tm.Length())
+}
+
+func (s *MapTestSuite) TestShardedMapMergeAndRestore() {
+	src := s.newShardedMap(ttl.WithTTL(time.Minute))
+	defer src.Close()
+
+	for i := 0; i < 100; i++ {
+		src.Store(i, i)
+	}
+
+	merged := s.newShardedMap(ttl.WithTTL(time.Minute))
+	defer merged.Close()
+
+	merged.MergeMap(map[int]int{1000: 1000}, nil)
+	merged.Merge(src, nil)
+	s.Equal(101, merged.Length())
+
+	restored := s.newShardedMap()
+	defer restored.Close()
+
+	restored.Restore(src.Snapshot(), ttl.RebasePolicy{Mode: ttl.RebaseResume})
+	s.Equal(100, restored.Length())
+
+	v, ok := restored.Load(99)
+	s.True(ok)
+	s.Equal(99, v)
+}
+
+func (s *MapTestSuite) TestShardedMapContext() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	tm := s.newShardedMap(ttl.WithContext(ctx))
+	cancel()
+
+	tm.CloseWait()
+}
+
+func (s *MapTestSuite) TestShardedMapModel() {
+	tm := ttl.NewShardedMap[int, int](ttl.WithTTL(time.Hour), ttl.WithPruneInterval(time.Minute))
+	defer tm.Close()
+
+	for seed := int64(0); seed < 5; seed++ {
+		tm.Clear()
+		ttltest.Check(s.T(), tm, ttltest.RandomOps(seed, 500, 8))
+	}
+
+	tm.Clear()
+	ttltest.CheckConcurrent(s.T(), tm, 8, 2000, 1)
+}
+
+func (s *MapTestSuite) TestShardedMapWithShardsAndHasher() {
+	const shards = 4
+
+	tm := s.newShardedMap(
+		ttl.WithShards(shards),
+		ttl.WithHasher(func(key int) uint64 {
+			return uint64(key / 100)
+		}))
+	defer tm.Close()
+
+	s.Equal(shards, tm.Shards())
+	s.Equal(0, tm.ShardOf(99))
+	s.Equal(1, tm.ShardOf(100))
+	s.Equal(0, tm.ShardOf(400))
+
+	tm.Store(150, 1)
+	v, ok := tm.Load(150)
+	s.True(ok)
+	s.Equal(1, v)
+
+	defaulted := ttl.NewShardedMap[string, int](ttl.WithShards(0))
+	defer defaulted.Close()
+
+	s.Positive(defaulted.Shards())
+	s.Equal(defaulted.ShardOf("a"), defaulted.ShardOf("a"))
+}
+
+func (s *MapTestSuite) TestShardedMapShardOfAlignment() {
+	const shards = 8
+
+	hash := func(key int) uint64 {
+		return uint64(key) * 0x9e3779b97f4a7c15
+	}
+
+	tm := s.newShardedMap(ttl.WithShards(shards), ttl.WithHasher(hash))
+	defer tm.Close()
+
+	// Keys are placed by the hasher, so placement can be computed without the map
+	for key := 0; key < 1000; key++ {
+		s.Equal(int(hash(key)%shards), tm.ShardOf(key))
+	}
+
+	// One worker per shard, each handling only the keys of its shard
+	queues := make([]chan int, tm.Shards())
+	for i := range queues {
+		queues[i] = make(chan int, 1000)
+	}
+
+	for key := 0; key < 1000; key++ {
+		queues[tm.ShardOf(key)] <- key
+	}
+
+	var wg sync.WaitGroup
+	for i, queue := range queues {
+		close(queue)
+
+		wg.Add(1)
+		go func(shard int, queue <-chan int) {
+			defer wg.Done()
+
+			for key := range queue {
+				s.Equal(shard, tm.ShardOf(key))
+				tm.Store(key, key)
+			}
+		}(i, queue)
+	}
+	wg.Wait()
+
+	s.Equal(1000, tm.Length())
+}
+
+func (s *MapTestSuite) TestShardedMapPointerKeys() {
+	type conn struct {
+		n int
+	}
+
+	tm := ttl.NewShardedMap[*conn, string](ttl.WithShards(16))
+	defer tm.Close()
+
+	// Pointer keys are placed by address, so mutating their target does not move them
+	c := &conn{n: 1}
+	tm.Store(c, "x")
+	c.n = 2
+
+	v, ok := tm.Load(c)
+	s.True(ok)
+	s.Equal("x", v)
+
+	tm.Store(c, "y")
+	s.Equal(1, tm.Length())
+
+	// As are pointers held by interface keys
+	anyKeys := ttl.NewShardedMap[any, string](ttl.WithShards(16))
+	defer anyKeys.Close()
+
+	anyKeys.Store(c, "x")
+	c.n = 3
+
+	v, ok = anyKeys.Load(c)
+	s.True(ok)
+	s.Equal("x", v)
+
+	// Struct keys holding equal values are placed together
+	type point struct {
+		x, y float64
+	}
+
+	points := ttl.NewShardedMap[point, int](ttl.WithShards(16))
+	defer points.Close()
+
+	s.Equal(points.ShardOf(point{x: 0, y: 1}), points.ShardOf(point{x: math.Copysign(0, -1), y: 1}))
+}
+
+func (s *MapTestSuite) TestShardedMapWithHasherTypeMismatch() {
+	s.Panics(func() {
+		ttl.NewShardedMap[int, int](ttl.WithHasher(func(string) uint64 { return 0 }))
+	})
+}
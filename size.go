@@ -0,0 +1,118 @@
+package ttl
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// EstimateSize returns an estimate of the number of bytes of memory used by v, including the memory
+// it references through pointers, slices, strings, maps and interfaces. Memory referenced more than
+// once (for example, by two pointers to the same struct) is only counted once, and cycles are
+// followed only once. Channels, functions and unsafe pointers are counted as their own size only.
+//
+// The estimate does not include allocator overhead or the internal structure of maps, so the real
+// usage is typically somewhat higher. EstimateSize uses reflection and visits everything that v
+// references, so it is comparatively slow for large values. It is used by [WithMaxMemory], and may
+// be useful for writing a cost function for [WithMaxCost].
+func EstimateSize(v any) int64 {
+	if v == nil {
+		return 0
+	}
+
+	rv := reflect.ValueOf(v)
+	return int64(rv.Type().Size()) + referencedSize(rv, make(map[uintptr]bool))
+}
+
+// referencedSize returns the number of bytes of memory referenced by v, not including the size of v
+// itself. Memory at the addresses in seen has already been counted and is skipped.
+func referencedSize(v reflect.Value, seen map[uintptr]bool) (size int64) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() || seen[v.Pointer()] {
+			return 0
+		}
+
+		seen[v.Pointer()] = true
+		elem := v.Elem()
+
+		return int64(elem.Type().Size()) + referencedSize(elem, seen)
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+
+		elem := v.Elem()
+
+		return int64(elem.Type().Size()) + referencedSize(elem, seen)
+
+	case reflect.String:
+		if v.Len() == 0 {
+			return 0
+		}
+
+		data := uintptr(unsafe.Pointer(unsafe.StringData(v.String())))
+		if seen[data] {
+			return 0
+		}
+
+		seen[data] = true
+
+		return int64(v.Len())
+
+	case reflect.Slice:
+		if v.IsNil() || v.Cap() == 0 || seen[v.Pointer()] {
+			return 0
+		}
+
+		seen[v.Pointer()] = true
+		size = int64(v.Cap()) * int64(v.Type().Elem().Size())
+
+		for i := 0; i < v.Len(); i++ {
+			size += referencedSize(v.Index(i), seen)
+		}
+
+		return size
+
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			size += referencedSize(v.Index(i), seen)
+		}
+
+		return size
+
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			size += referencedSize(v.Field(i), seen)
+		}
+
+		return size
+
+	case reflect.Map:
+		if v.IsNil() || seen[v.Pointer()] {
+			return 0
+		}
+
+		seen[v.Pointer()] = true
+		entry := int64(v.Type().Key().Size() + v.Type().Elem().Size())
+
+		for iter := v.MapRange(); iter.Next(); {
+			size += entry + referencedSize(iter.Key(), seen) + referencedSize(iter.Value(), seen)
+		}
+
+		return size
+
+	default:
+		return 0
+	}
+}
+
+// memoryCost returns a cost function for [WithMaxMemory] that estimates the memory used by an item
+// of a map, including its key, value and bookkeeping.
+func memoryCost[K comparable, V any]() func(K, V) int64 {
+	overhead := int64(unsafe.Sizeof(mapItem[V]{})) - int64(unsafe.Sizeof(*new(V)))
+
+	return func(key K, value V) int64 {
+		return overhead + EstimateSize(key) + EstimateSize(value)
+	}
+}
@@ -0,0 +1,58 @@
+package ttl_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/glenvan/ttl/v2"
+)
+
+type SizeTestSuite struct {
+	suite.Suite
+}
+
+func TestSizeTestSuite(t *testing.T) {
+	suite.Run(t, new(SizeTestSuite))
+}
+
+func (s *SizeTestSuite) TestEstimateSize() {
+	type node struct {
+		name string
+		next *node
+	}
+
+	s.Equal(int64(0), ttl.EstimateSize(nil))
+	s.Equal(int64(8), ttl.EstimateSize(int64(1)))
+	s.Equal(int64(16+4), ttl.EstimateSize("abcd"))
+	s.Equal(int64(24+16), ttl.EstimateSize(make([]byte, 10, 16)))
+	s.Equal(int64(8+8), ttl.EstimateSize(new(int64)))
+
+	// A cycle is only counted once
+	a := &node{name: "a"}
+	b := &node{name: "b", next: a}
+	a.next = b
+	s.Equal(int64(8+2*(16+8)+2), ttl.EstimateSize(a))
+
+	// Shared memory is only counted once
+	shared := make([]byte, 100)
+	s.Equal(int64(2*24+100), ttl.EstimateSize([2][]byte{shared, shared}))
+}
+
+func (s *SizeTestSuite) TestWithMaxMemory() {
+	tm := ttl.New[int, []byte](
+		ttl.WithPruneInterval(0),
+		ttl.WithMaxMemory(10_000))
+	defer tm.Close()
+
+	for i := 0; i < 100; i++ {
+		tm.Store(i, make([]byte, 1000))
+	}
+
+	stats := tm.Stats()
+
+	s.LessOrEqual(stats.Cost, int64(10_000))
+	s.Greater(stats.Cost, int64(8_000))
+	s.Less(tm.Length(), 10)
+	s.Equal(uint64(100-tm.Length()), stats.Removals.Evicted)
+}
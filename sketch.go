@@ -0,0 +1,105 @@
+package ttl
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+const (
+	// sketchDepth is the number of counters, each chosen by a different hash, that are incremented
+	// for each key. The estimated frequency of a key is the smallest of its counters.
+	sketchDepth = 4
+
+	// sketchMaxCount is the largest value of a 4-bit counter.
+	sketchMaxCount = 15
+
+	// sketchResetFactor is the number of increments, as a multiple of the capacity of the map,
+	// after which every counter is halved so that the sketch favours recent activity.
+	sketchResetFactor = 10
+)
+
+// frequencySketch is a count-min sketch that estimates how often each key has been used, as used by
+// TinyLFU. Counters are four bits wide and packed sixteen to a word, with a word for each entry of
+// the map it serves, so that few keys share all of their counters.
+//
+// A frequencySketch is safe for concurrent use. Concurrent updates may occasionally be lost, which
+// only makes the estimates slightly less accurate.
+type frequencySketch[K comparable] struct {
+	hash       func(K) uint64
+	table      []atomic.Uint64
+	additions  atomic.Int64
+	sampleSize int64
+}
+
+// newFrequencySketch returns a sketch sized for a map holding up to capacity entries.
+func newFrequencySketch[K comparable](capacity int, hash func(K) uint64) *frequencySketch[K] {
+	// One word (sixteen counters) per entry, rounded up to a power of two
+	words := 1 << bits.Len(uint(max(capacity-1, 1)))
+
+	return &frequencySketch[K]{
+		hash:       hash,
+		table:      make([]atomic.Uint64, words),
+		sampleSize: int64(max(capacity, 1)) * sketchResetFactor,
+	}
+}
+
+// counter returns the index of the word and the bit offset within it of the counter of key for the
+// given row.
+func (s *frequencySketch[K]) counter(h uint64, row int) (word int, shift uint) {
+	// Derive a hash per row from the two halves of h (Kirsch-Mitzenmacher)
+	h = h + uint64(row)*(h>>32|h<<32) + uint64(row)
+	h *= 0x9e3779b97f4a7c15
+
+	return int(h>>32) & (len(s.table) - 1), uint(h&15) * 4
+}
+
+// increment records a use of key, halving every counter once enough uses have been recorded.
+func (s *frequencySketch[K]) increment(key K) {
+	h := s.hash(key)
+
+	for row := 0; row < sketchDepth; row++ {
+		word, shift := s.counter(h, row)
+
+		for {
+			old := s.table[word].Load()
+			if (old>>shift)&sketchMaxCount == sketchMaxCount {
+				break
+			}
+
+			if s.table[word].CompareAndSwap(old, old+1<<shift) {
+				break
+			}
+		}
+	}
+
+	if s.additions.Add(1) == s.sampleSize {
+		s.reset()
+	}
+}
+
+// frequency returns the estimated number of uses of key, up to 15.
+func (s *frequencySketch[K]) frequency(key K) int {
+	h := s.hash(key)
+	freq := sketchMaxCount
+
+	for row := 0; row < sketchDepth; row++ {
+		word, shift := s.counter(h, row)
+		freq = min(freq, int(s.table[word].Load()>>shift)&sketchMaxCount)
+	}
+
+	return freq
+}
+
+// reset halves every counter.
+func (s *frequencySketch[K]) reset() {
+	for i := range s.table {
+		for {
+			old := s.table[i].Load()
+			if s.table[i].CompareAndSwap(old, (old>>1)&0x7777777777777777) {
+				break
+			}
+		}
+	}
+
+	s.additions.Store(0)
+}
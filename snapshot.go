@@ -0,0 +1,167 @@
+package ttl
+
+import (
+	"slices"
+	"time"
+)
+
+// SnapshotEntry is a single key/value pair captured by [Map.Snapshot], along with the TTL state
+// needed to restore it.
+type SnapshotEntry[K comparable, V any] struct {
+	Key        K
+	Value      V
+	TTL        time.Duration
+	LastAccess time.Time
+	Policy     RefreshPolicy
+	Pinned     bool
+}
+
+// Snapshot is a point-in-time copy of the unexpired contents of a [Map], suitable for warming a
+// new [Map] with [Map.Restore] (for example, after a restart).
+type Snapshot[K comparable, V any] struct {
+	Taken   time.Time
+	Entries []SnapshotEntry[K, V]
+}
+
+// RebaseMode determines how the TTLs stored in a [Snapshot] are rebased when it is restored.
+type RebaseMode int
+
+const (
+	// RebaseResume restores each entry with the time to live it had remaining when the snapshot
+	// was taken. The time between the snapshot and the restore is not counted against it.
+	RebaseResume RebaseMode = iota
+
+	// RebaseReset restores each entry with the [Map]'s default TTL, starting from the time of the
+	// restore.
+	RebaseReset
+
+	// RebaseExpireIfOlderThan drops any entry that was last accessed more than
+	// [RebasePolicy].MaxAge before the restore. The remaining entries are restored as with
+	// RebaseResume.
+	RebaseExpireIfOlderThan
+)
+
+// RebasePolicy controls how [Map.Restore] rebases the TTLs of restored entries.
+type RebasePolicy struct {
+	Mode RebaseMode
+
+	// MaxAge is only used by RebaseExpireIfOlderThan.
+	MaxAge time.Duration
+}
+
+// Snapshot returns a copy of all unexpired key/value pairs in the [Map] along with their TTL
+// state. Values are copied as-is, so reference types will share memory with the [Map]. Snapshot
+// does not update the last access time of any entry and is safe for concurrent use.
+func (m *Map[K, V]) Snapshot() Snapshot[K, V] {
+	now := m.clock.Now()
+	nowNano := now.UnixNano()
+
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	s := Snapshot[K, V]{
+		Taken:   now,
+		Entries: make([]SnapshotEntry[K, V], 0, len(m.m)),
+	}
+
+	for key, it := range m.m {
+		if it.expired(nowNano) {
+			continue
+		}
+
+		s.Entries = append(s.Entries, SnapshotEntry[K, V]{
+			Key:        key,
+			Value:      it.value,
+			TTL:        it.itemTTL,
+			LastAccess: time.Unix(0, it.lastAccess.Load()),
+			Policy:     it.policy,
+			Pinned:     it.pinned,
+		})
+	}
+
+	return s
+}
+
+// Restore stores the entries of s in the [Map], rebasing their TTLs according to policy. Restored
+// entries replace any existing entries with the same key. Entries that have no time to live
+// remaining after rebasing are skipped.
+//
+// Restore is safe for concurrent use.
+func (m *Map[K, V]) Restore(s Snapshot[K, V], policy RebasePolicy) {
+	m.lock()
+	defer m.unlock()
+
+	m.restoreLocked(s, policy)
+}
+
+// Filter returns a new [Map], configured by opts like [New], holding the unexpired entries of the
+// [Map] for which pred returns true, with the time to live they have remaining, for example to
+// carve the entries of one tenant out of a shared [Map]. Their [RefreshPolicy] and whether they
+// are pinned are kept as well. pred is called without the lock on the [Map] held. The new [Map]
+// must be closed when it is no longer needed. Filter does not update the last access time of any
+// entry and is safe for concurrent use.
+func (m *Map[K, V]) Filter(pred func(key K, value V) bool, opts ...Option) *Map[K, V] {
+	filtered := New[K, V](opts...)
+	filtered.Restore(filterSnapshot(m.Snapshot(), pred), RebasePolicy{Mode: RebaseResume})
+
+	return filtered
+}
+
+// restoreLocked is like [Map.Restore]. The caller must hold the write lock.
+func (m *Map[K, V]) restoreLocked(s Snapshot[K, V], policy RebasePolicy) {
+	now := m.clock.Now()
+	nowNano := now.UnixNano()
+
+	for _, e := range s.Entries {
+		it := newMapItem[V](nowNano)
+		it.value = e.Value
+		it.itemTTL = e.TTL
+		it.policy = e.Policy
+		it.pinned = e.Pinned
+
+		switch policy.Mode {
+		case RebaseReset:
+			it.itemTTL = m.jittered(m.defaultTTL)
+			it.defaulted = true
+
+		case RebaseExpireIfOlderThan:
+			if now.Sub(e.LastAccess) > policy.MaxAge {
+				continue
+			}
+			fallthrough
+
+		default:
+			// Shift the last access so that the residual TTL resumes from now
+			elapsed := s.Taken.Sub(e.LastAccess)
+			it.lastAccess.Store(nowNano - int64(elapsed))
+		}
+
+		if it.expired(nowNano) {
+			continue
+		}
+
+		if old, ok := m.m[e.Key]; ok {
+			m.replacedLocked(e.Key, old.value)
+			m.totalCost -= old.cost
+			m.unlabelLocked(e.Key, old)
+		} else {
+			m.addedLocked(e.Key)
+		}
+
+		m.m[e.Key] = it
+		m.storedLocked(e.Key)
+		m.recheckLocked(e.Key)
+	}
+}
+
+// filterSnapshot returns s holding only the entries for which pred returns true.
+func filterSnapshot[K comparable, V any](
+	s Snapshot[K, V],
+	pred func(key K, value V) bool,
+) Snapshot[K, V] {
+	s.Entries = slices.DeleteFunc(s.Entries, func(e SnapshotEntry[K, V]) bool {
+		return !pred(e.Key, e.Value)
+	})
+
+	return s
+}
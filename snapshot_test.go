@@ -0,0 +1,317 @@
+package ttl_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestSnapshotRestore() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	tm.Store("one", 1)
+	tm.StoreWithTTL("two", 2, time.Minute)
+
+	snap := tm.Snapshot()
+	s.Len(snap.Entries, 2)
+
+	restored := ttl.NewMap[string, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer restored.Close()
+
+	restored.Restore(snap, ttl.RebasePolicy{Mode: ttl.RebaseResume})
+	s.Equal(2, restored.Length())
+
+	v, ok := restored.LoadPassive("two")
+	if s.True(ok) {
+		s.Equal(2, v)
+	}
+
+	time.Sleep(s.sleepTime)
+
+	s.Equal(1, restored.Length())
+}
+
+func (s *MapTestSuite) TestRestoreResumeIgnoresGap() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	taken := time.Now().Add(-time.Hour)
+	snap := ttl.Snapshot[string, int]{
+		Taken: taken,
+		Entries: []ttl.SnapshotEntry[string, int]{
+			{Key: "resumed", Value: 1, TTL: time.Minute, LastAccess: taken.Add(-30 * time.Second)},
+			{Key: "spent", Value: 2, TTL: time.Minute, LastAccess: taken.Add(-2 * time.Minute)},
+		},
+	}
+
+	tm.Restore(snap, ttl.RebasePolicy{Mode: ttl.RebaseResume})
+
+	s.Equal(1, tm.Length())
+	_, ok := tm.LoadPassive("resumed")
+	s.True(ok)
+}
+
+func (s *MapTestSuite) TestRestoreReset() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	taken := time.Now().Add(-time.Hour)
+	snap := ttl.Snapshot[string, int]{
+		Taken: taken,
+		Entries: []ttl.SnapshotEntry[string, int]{
+			{Key: "spent", Value: 1, TTL: time.Minute, LastAccess: taken.Add(-2 * time.Minute)},
+		},
+	}
+
+	tm.Restore(snap, ttl.RebasePolicy{Mode: ttl.RebaseReset})
+
+	_, ok := tm.LoadPassive("spent")
+	s.True(ok)
+
+	time.Sleep(s.sleepTime)
+
+	s.Zero(tm.Length())
+}
+
+func (s *MapTestSuite) TestRestoreExpireIfOlderThan() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	now := time.Now()
+	snap := ttl.Snapshot[string, int]{
+		Taken: now.Add(-time.Minute),
+		Entries: []ttl.SnapshotEntry[string, int]{
+			{Key: "recent", Value: 1, TTL: time.Hour, LastAccess: now.Add(-2 * time.Minute)},
+			{Key: "stale", Value: 2, TTL: time.Hour, LastAccess: now.Add(-20 * time.Minute)},
+		},
+	}
+
+	tm.Restore(snap, ttl.RebasePolicy{Mode: ttl.RebaseExpireIfOlderThan, MaxAge: 10 * time.Minute})
+
+	s.Equal(1, tm.Length())
+	_, ok := tm.LoadPassive("recent")
+	s.True(ok)
+}
+
+func (s *MapTestSuite) TestFilter() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	tm := ttl.New[string, int](ttl.WithClock(clock), ttl.WithTTL(time.Minute), ttl.WithPruneInterval(0))
+	defer tm.Close()
+
+	tm.Store("tenant:1:a", 1)
+	tm.StoreWithTTL("tenant:1:b", 2, 0)
+	tm.StoreWithTTL("tenant:1:expired", 3, time.Second)
+	tm.Store("tenant:2:a", 4)
+	clock.Advance(10 * time.Second)
+
+	filtered := tm.Filter(func(key string, _ int) bool {
+		return strings.HasPrefix(key, "tenant:1:")
+	}, ttl.WithClock(clock), ttl.WithPruneInterval(0))
+	defer filtered.Close()
+
+	s.Equal(2, filtered.Length())
+
+	remaining, ok := filtered.TTL("tenant:1:a")
+	s.True(ok)
+	s.Equal(50*time.Second, remaining)
+
+	remaining, ok = filtered.TTL("tenant:1:b")
+	s.True(ok)
+	s.Equal(ttl.NoExpiry, remaining)
+
+	// The maps are independent
+	filtered.Delete("tenant:1:a")
+	s.Equal(4, tm.Length())
+
+	sharded := ttl.NewShardedMap[int, int](ttl.WithShards(4), ttl.WithPruneInterval(0))
+	defer sharded.Close()
+
+	for i := 0; i < 10; i++ {
+		sharded.Store(i, i)
+	}
+
+	even := sharded.Filter(func(_ int, value int) bool {
+		return value%2 == 0
+	}, ttl.WithShards(2), ttl.WithPruneInterval(0))
+	defer even.Close()
+
+	s.Equal(5, even.Length())
+}
+
+func (s *MapTestSuite) TestJSONRoundTrip() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	tm := ttl.New[int, string](ttl.WithClock(clock), ttl.WithTTL(time.Minute), ttl.WithPruneInterval(0))
+	defer tm.Close()
+
+	tm.Store(1, "one")
+	tm.StoreWithTTL(2, "two", time.Hour)
+	tm.StoreWithTTL(3, "forever", 0)
+	tm.StoreWithTTL(4, "pinned", time.Second)
+	tm.Pin(4)
+
+	clock.Advance(20 * time.Second)
+
+	data, err := json.Marshal(tm)
+	s.Require().NoError(err)
+
+	// Restore an hour later: each entry resumes with the TTL it had remaining when it was encoded
+	later := ttltest.NewClock(clock.Now().Add(time.Hour))
+
+	restored := ttl.New[int, string](ttl.WithClock(later), ttl.WithPruneInterval(0))
+	defer restored.Close()
+
+	s.Require().NoError(json.Unmarshal(data, restored))
+	s.Equal(4, restored.Length())
+
+	remaining, _ := restored.TTL(1)
+	s.Equal(40*time.Second, remaining)
+
+	remaining, _ = restored.TTL(2)
+	s.Equal(time.Hour-20*time.Second, remaining)
+
+	remaining, _ = restored.TTL(3)
+	s.Equal(ttl.NoExpiry, remaining)
+
+	info, _ := restored.Metadata(4)
+	s.True(info.Pinned)
+
+	later.Advance(time.Minute)
+	s.Equal(1, restored.Prune())
+
+	var unconstructed *ttl.Map[int, string]
+	s.Error(json.Unmarshal(data, &unconstructed))
+}
+
+func (s *MapTestSuite) TestGobRoundTrip() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	tm := ttl.New[string, []int](ttl.WithClock(clock), ttl.WithTTL(time.Minute), ttl.WithPruneInterval(0))
+	defer tm.Close()
+
+	tm.Store("a", []int{1, 2})
+	tm.StoreWithTTL("b", nil, 0)
+	tm.StoreWithPolicy("c", []int{3}, time.Hour, ttl.RefreshNever)
+
+	clock.Advance(15 * time.Second)
+
+	var buf bytes.Buffer
+	s.Require().NoError(gob.NewEncoder(&buf).Encode(tm))
+
+	later := ttltest.NewClock(clock.Now().Add(24 * time.Hour))
+
+	restored := ttl.New[string, []int](ttl.WithClock(later), ttl.WithPruneInterval(0))
+	defer restored.Close()
+
+	s.Require().NoError(gob.NewDecoder(&buf).Decode(restored))
+	s.Equal(3, restored.Length())
+
+	v, _ := restored.LoadPassive("a")
+	s.Equal([]int{1, 2}, v)
+
+	remaining, _ := restored.TTL("a")
+	s.Equal(45*time.Second, remaining)
+
+	remaining, _ = restored.TTL("b")
+	s.Equal(ttl.NoExpiry, remaining)
+
+	info, _ := restored.Metadata("c")
+	s.Equal(ttl.RefreshNever, info.Policy)
+}
+
+func (s *MapTestSuite) TestSaveToLoadFrom() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	tm := ttl.New[int, string](ttl.WithClock(clock), ttl.WithTTL(time.Minute), ttl.WithPruneInterval(0))
+	defer tm.Close()
+
+	const n = 3000
+	for i := 0; i < n; i++ {
+		tm.Store(i, "value")
+	}
+	tm.StoreWithTTL(n, "forever", 0)
+
+	clock.Advance(10 * time.Second)
+
+	var buf bytes.Buffer
+	s.Require().NoError(tm.SaveTo(&buf))
+
+	later := ttltest.NewClock(clock.Now().Add(time.Hour))
+
+	restored := ttl.New[int, string](ttl.WithClock(later), ttl.WithPruneInterval(0))
+	defer restored.Close()
+
+	s.Require().NoError(restored.LoadFrom(&buf))
+	s.Equal(n+1, restored.Length())
+
+	remaining, _ := restored.TTL(0)
+	s.Equal(50*time.Second, remaining)
+
+	remaining, _ = restored.TTL(n)
+	s.Equal(ttl.NoExpiry, remaining)
+
+	s.ErrorIs(restored.LoadFrom(bytes.NewReader([]byte("not a snapshot"))), ttl.ErrSnapshotFormat)
+	s.ErrorIs(restored.LoadFrom(bytes.NewReader([]byte("TTLS\x02\x00"))), ttl.ErrSnapshotFormat)
+}
+
+func (s *MapTestSuite) TestSnapshotFile() {
+	path := filepath.Join(s.T().TempDir(), "cache.snapshot")
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	tm, err := ttl.NewFromSnapshotFile[string, int](
+		ttl.WithSnapshotFile(path, time.Minute),
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Hour),
+		ttl.WithPruneInterval(0))
+	s.Require().NoError(err)
+
+	tm.StoreWithTTL("short", 1, 2*time.Minute)
+	tm.StoreWithTTL("long", 2, time.Hour)
+
+	// The tick from advancing the clock saves the file in the background
+	clock.Advance(time.Minute)
+	s.Eventually(func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, time.Millisecond)
+
+	tm.Store("saved on close", 3)
+	tm.CloseWait()
+
+	// Restart ten minutes later: the time since the save counts against the entries' TTLs
+	later := ttltest.NewClock(clock.Now().Add(10 * time.Minute))
+
+	restored, err := ttl.NewFromSnapshotFile[string, int](
+		ttl.WithSnapshotFile(path, 0),
+		ttl.WithClock(later),
+		ttl.WithTTL(time.Hour),
+		ttl.WithPruneInterval(0))
+	s.Require().NoError(err)
+	defer restored.Close()
+
+	_, ok := restored.LoadPassive("short")
+	s.False(ok)
+
+	remaining, _ := restored.TTL("long")
+	s.Equal(49*time.Minute, remaining)
+
+	v, _ := restored.LoadPassive("saved on close")
+	s.Equal(3, v)
+
+	s.Require().NoError(os.WriteFile(path, []byte("corrupt"), 0o600))
+	_, err = ttl.NewFromSnapshotFile[string, int](ttl.WithSnapshotFile(path, 0))
+	s.ErrorIs(err, ttl.ErrSnapshotFormat)
+}
@@ -0,0 +1,184 @@
+package ttl
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SaveFile writes the unexpired entries of the [Map] to the file at path, in the format written by
+// [Map.SaveTo]. The file is replaced atomically: the entries are written to a temporary file in the
+// same directory, which is synced and then renamed over path, so a crash while saving leaves the
+// previous file intact. A new file is created with permissions 0600.
+func (m *Map[K, V]) SaveFile(path string) error {
+	return saveFile(path, m.SaveTo)
+}
+
+// LoadFile reads a file written by [Map.SaveFile] and stores its entries in the [Map] like
+// [Map.LoadFrom], except that the time since the file was saved is counted against each entry's
+// time to live, so entries that expired while no process held them are dropped. If the file does
+// not exist, LoadFile returns an error satisfying errors.Is(err, fs.ErrNotExist).
+func (m *Map[K, V]) LoadFile(path string) error {
+	return loadFile(path, func(r io.Reader) error {
+		return loadSnapshot(r, m.clock.Now(), true, func(s Snapshot[K, V]) {
+			m.Restore(s, RebasePolicy{Mode: RebaseResume})
+		})
+	})
+}
+
+// SaveFile is like [Map.SaveFile].
+func (s *ShardedMap[K, V]) SaveFile(path string) error {
+	return saveFile(path, s.SaveTo)
+}
+
+// LoadFile is like [Map.LoadFile].
+func (s *ShardedMap[K, V]) LoadFile(path string) error {
+	return loadFile(path, func(r io.Reader) error {
+		return loadSnapshot(r, s.shards[0].clock.Now(), true, func(snapshot Snapshot[K, V]) {
+			s.Restore(snapshot, RebasePolicy{Mode: RebaseResume})
+		})
+	})
+}
+
+// NewFromSnapshotFile is like [New], but first restores the [Map] from the file set using
+// [WithSnapshotFile], if it exists, using [Map.LoadFile]. Entries that expired since the file was
+// saved are dropped. An error is returned if the file exists but cannot be read.
+//
+// The [Map] is restored before its background goroutines start, so the file is not saved over
+// until it has been read.
+func NewFromSnapshotFile[K comparable, V any](opts ...Option) (*Map[K, V], error) {
+	o := defaultOptions()
+	o.apply(opts)
+	o.logMisconfiguration()
+
+	m := newMap[K, V](o)
+
+	if o.snapshotPath != "" {
+		if err := m.LoadFile(o.snapshotPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	m.start(o)
+
+	return m, nil
+}
+
+// NewShardedMapFromSnapshotFile is like [NewFromSnapshotFile], returning a [ShardedMap].
+func NewShardedMapFromSnapshotFile[K comparable, V any](opts ...Option) (*ShardedMap[K, V], error) {
+	o := defaultOptions()
+	o.apply(opts)
+	o.logMisconfiguration()
+
+	s := newShardedMap[K, V](o)
+
+	if o.snapshotPath != "" {
+		if err := s.LoadFile(o.snapshotPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	s.start(o)
+
+	return s, nil
+}
+
+// fileSaver is implemented by [Map] and [ShardedMap].
+type fileSaver interface {
+	SaveFile(path string) error
+	Close()
+}
+
+// startAutoSave starts a goroutine that saves m to the file set using WithSnapshotFile, as
+// configured by o, until stop is closed or the context of o is done, then saves it once more. It
+// returns a channel that is closed after the final save.
+func startAutoSave(o options, m fileSaver, stop <-chan bool) <-chan struct{} {
+	saved := make(chan struct{})
+
+	// The ticker is created before the goroutine starts, like the prune ticker
+	var ticker Ticker
+	var tick <-chan time.Time
+	if o.snapshotInterval > 0 {
+		ticker = o.clock.NewTicker(o.snapshotInterval)
+		tick = ticker.C()
+	}
+
+	save := func() {
+		if err := m.SaveFile(o.snapshotPath); err != nil && o.logger != nil {
+			o.logger.Error("saving snapshot file", "path", o.snapshotPath, "error", err)
+		}
+	}
+
+	go func() {
+		defer close(saved)
+
+		if ticker != nil {
+			defer ticker.Stop()
+		}
+
+		for {
+			select {
+			case <-o.ctx.Done():
+				m.Close()
+				save()
+				return
+			case <-stop:
+				save()
+				return
+			case <-tick:
+				save()
+			}
+		}
+	}()
+
+	return saved
+}
+
+// saveFile atomically replaces the file at path with the output of save.
+func saveFile(path string, save func(io.Writer) error) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(f)
+	if err = save(w); err != nil {
+		return
+	}
+
+	if err = w.Flush(); err != nil {
+		return
+	}
+
+	if err = f.Sync(); err != nil {
+		return
+	}
+
+	if err = f.Close(); err != nil {
+		return
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// loadFile opens the file at path and passes it to load.
+func loadFile(path string, load func(io.Reader) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return load(f)
+}
@@ -0,0 +1,334 @@
+package ttl
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// RemovalReason describes why an entry left a [Map]. It is passed to the callback set using
+// [WithOnRemove].
+type RemovalReason int
+
+const (
+	// ReasonExpired means the entry's TTL elapsed, or it was expired using [Map.Expire].
+	ReasonExpired RemovalReason = iota
+
+	// ReasonDeleted means the entry was removed by [Map.Delete] or [Map.DeleteFunc].
+	ReasonDeleted
+
+	// ReasonCleared means the entry was removed by [Map.Clear].
+	ReasonCleared
+
+	// ReasonEvicted means the entry was removed to satisfy a capacity limit.
+	ReasonEvicted
+
+	// ReasonReplaced means the entry's value was overwritten by a store to its key.
+	ReasonReplaced
+
+	numRemovalReasons
+)
+
+// String returns the name of the reason, such as "expired".
+func (r RemovalReason) String() string {
+	switch r {
+	case ReasonExpired:
+		return "expired"
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonCleared:
+		return "cleared"
+	case ReasonEvicted:
+		return "evicted"
+	case ReasonReplaced:
+		return "replaced"
+	default:
+		return "RemovalReason(" + strconv.Itoa(int(r)) + ")"
+	}
+}
+
+// Removals breaks down the number of entries removed from a [Map] by the reason they were removed.
+type Removals struct {
+	// Expired counts entries removed because their TTL elapsed.
+	Expired uint64
+
+	// Deleted counts entries removed by [Map.Delete] or [Map.DeleteFunc].
+	Deleted uint64
+
+	// Cleared counts entries removed by [Map.Clear].
+	Cleared uint64
+
+	// Evicted counts entries removed to satisfy a capacity limit.
+	Evicted uint64
+
+	// Replaced counts values that were overwritten by a store to an existing key.
+	Replaced uint64
+}
+
+// Total returns the total number of removals across all reasons.
+func (r Removals) Total() uint64 {
+	return r.Expired + r.Deleted + r.Cleared + r.Evicted + r.Replaced
+}
+
+// Stats is a point-in-time summary of a [Map]'s contents and activity, returned by [Map.Stats].
+// Cumulative counts are since the [Map] was created or since the last call to [Map.ResetStats].
+type Stats struct {
+	// Length is the number of entries in the [Map] when the Stats were taken.
+	Length int
+
+	// Hits is the cumulative number of loads that found their key. It is only collected if the
+	// [Map] was created using [WithStats].
+	Hits uint64
+
+	// Misses is the cumulative number of loads that did not find their key. It is only collected
+	// if the [Map] was created using [WithStats].
+	Misses uint64
+
+	// Stores is the cumulative number of values stored. It is only collected if the [Map] was
+	// created using [WithStats].
+	Stores uint64
+
+	// Removals is the cumulative number of entries removed from the [Map], by reason.
+	Removals Removals
+
+	// EvictionAge is the distribution of the age of entries (the time since they were first
+	// stored) when they expired or were evicted. It is only collected if the [Map] was created
+	// using [WithQuantileStats].
+	EvictionAge Quantiles
+
+	// LoadLatency is the distribution of the time taken by loads. It is only collected if the
+	// [Map] was created using [WithQuantileStats].
+	LoadLatency Quantiles
+
+	// StoreLatency is the distribution of the time taken by stores. It is only collected if the
+	// [Map] was created using [WithQuantileStats].
+	StoreLatency Quantiles
+
+	// Cost is the total cost of the entries in the [Map] when the Stats were taken. It is only
+	// collected if the [Map] was created using [WithMaxCost] or [WithMaxMemory].
+	Cost int64
+
+	// ExpiredDropped is the cumulative number of expired entries that were not sent on the channel
+	// returned by [Map.Expired] because its buffer was full.
+	ExpiredDropped uint64
+
+	// Scopes breaks down entries and activity by scope. It is only collected if the [Map] was
+	// created using [WithStatsScope], and is nil otherwise.
+	Scopes map[string]ScopeStats
+}
+
+// HitRatio returns the fraction of loads that found their key, or zero if there have been no loads
+// or the [Map] was not created using [WithStats].
+func (s Stats) HitRatio() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+
+	return 0
+}
+
+// ScopeStats summarizes the entries and activity of a single scope of a [Map], as determined by
+// the function passed to [WithStatsScope].
+type ScopeStats struct {
+	// Entries is the number of entries in the scope when the Stats were taken.
+	Entries int
+
+	// Hits is the cumulative number of loads that found a key in the scope.
+	Hits uint64
+
+	// Misses is the cumulative number of loads that did not find a key in the scope.
+	Misses uint64
+
+	// Evictions is the cumulative number of entries in the scope that expired or were evicted.
+	Evictions uint64
+}
+
+// HitRatio returns the fraction of loads in the scope that found their key, or zero if there have
+// been no loads.
+func (s ScopeStats) HitRatio() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+
+	return 0
+}
+
+type mapStats struct {
+	counting  bool // hits, misses and stores are only counted if enabled
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	stores    atomic.Uint64
+	removals  [numRemovalReasons]atomic.Uint64
+	quantiles *quantileStats // nil unless enabled
+	scopes    sync.Map       // string -> *scopeCounters, only used if a scope function is set
+}
+
+type scopeCounters struct {
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+func (s *mapStats) scope(name string) *scopeCounters {
+	if c, ok := s.scopes.Load(name); ok {
+		return c.(*scopeCounters)
+	}
+
+	c, _ := s.scopes.LoadOrStore(name, &scopeCounters{})
+	return c.(*scopeCounters)
+}
+
+type quantileStats struct {
+	evictionAge  histogram
+	loadLatency  histogram
+	storeLatency histogram
+}
+
+func (s *mapStats) removed(reason RemovalReason, n int) {
+	s.removals[reason].Add(uint64(n))
+}
+
+func (s *mapStats) removalCounts() Removals {
+	return Removals{
+		Expired:  s.removals[ReasonExpired].Load(),
+		Deleted:  s.removals[ReasonDeleted].Load(),
+		Cleared:  s.removals[ReasonCleared].Load(),
+		Evicted:  s.removals[ReasonEvicted].Load(),
+		Replaced: s.removals[ReasonReplaced].Load(),
+	}
+}
+
+// add returns the sum of r and o.
+func (r Removals) add(o Removals) Removals {
+	return Removals{
+		Expired:  r.Expired + o.Expired,
+		Deleted:  r.Deleted + o.Deleted,
+		Cleared:  r.Cleared + o.Cleared,
+		Evicted:  r.Evicted + o.Evicted,
+		Replaced: r.Replaced + o.Replaced,
+	}
+}
+
+// Stats returns a summary of the [Map]'s contents and cumulative activity. Stats is safe for
+// concurrent use.
+func (m *Map[K, V]) Stats() (s Stats) {
+	s = Stats{
+		Length:   m.Length(),
+		Hits:     m.stats.hits.Load(),
+		Misses:   m.stats.misses.Load(),
+		Stores:   m.stats.stores.Load(),
+		Removals: m.stats.removalCounts(),
+	}
+
+	if q := m.stats.quantiles; q != nil {
+		s.EvictionAge = q.evictionAge.quantiles()
+		s.LoadLatency = q.loadLatency.quantiles()
+		s.StoreLatency = q.storeLatency.quantiles()
+	}
+
+	if m.statsScope != nil {
+		s.Scopes = m.scopeStats()
+	}
+
+	if m.costOf != nil {
+		m.mtx.RLock()
+		s.Cost = m.totalCost
+		m.mtx.RUnlock()
+	}
+
+	if m.expired != nil {
+		s.ExpiredDropped = m.expired.dropped.Load()
+	}
+
+	return
+}
+
+func (m *Map[K, V]) scopeStats() map[string]ScopeStats {
+	scopes := make(map[string]ScopeStats)
+
+	m.stats.scopes.Range(func(name any, c any) bool {
+		counters := c.(*scopeCounters)
+		scopes[name.(string)] = ScopeStats{
+			Hits:      counters.hits.Load(),
+			Misses:    counters.misses.Load(),
+			Evictions: counters.evictions.Load(),
+		}
+		return true
+	})
+
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	for key := range m.m {
+		name := m.statsScope(key)
+		scope := scopes[name]
+		scope.Entries++
+		scopes[name] = scope
+	}
+
+	return scopes
+}
+
+// ResetStats resets the cumulative counts and distributions reported by [Map.Stats] to zero, so
+// that subsequent Stats cover the period since the reset (for example, to report the hit ratio of
+// each minute). ResetStats is safe for concurrent use, but activity that is concurrent with the
+// reset may or may not be counted.
+func (m *Map[K, V]) ResetStats() {
+	m.stats.reset()
+
+	if m.expired != nil {
+		m.expired.dropped.Store(0)
+	}
+}
+
+// reset zeroes every counter and distribution.
+func (s *mapStats) reset() {
+	s.hits.Store(0)
+	s.misses.Store(0)
+	s.stores.Store(0)
+
+	for i := range s.removals {
+		s.removals[i].Store(0)
+	}
+
+	if q := s.quantiles; q != nil {
+		q.evictionAge.reset()
+		q.loadLatency.reset()
+		q.storeLatency.reset()
+	}
+
+	s.scopes.Range(func(name any, _ any) bool {
+		s.scopes.Delete(name)
+		return true
+	})
+}
+
+// recordStore counts a store, if counting is enabled.
+func (m *Map[K, V]) recordStore() {
+	if m.stats.counting {
+		m.stats.stores.Add(1)
+	}
+}
+
+// recordLoad records a hit or miss, if counting is enabled, and for key's scope, if scoped stats
+// are enabled.
+func (m *Map[K, V]) recordLoad(key K, hit bool) {
+	if m.stats.counting {
+		if hit {
+			m.stats.hits.Add(1)
+		} else {
+			m.stats.misses.Add(1)
+		}
+	}
+
+	if m.statsScope == nil {
+		return
+	}
+
+	c := m.stats.scope(m.statsScope(key))
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+}
@@ -0,0 +1,206 @@
+package ttl_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+)
+
+func (s *MapTestSuite) TestStatsRemovals() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[int, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	tm.Store(0, 0)
+	tm.Store(0, 1) // replaced
+	tm.StoreWithTTL(1, 1, time.Minute)
+	tm.Store(2, 2)
+	tm.Store(3, 3)
+	tm.Store(4, 4)
+
+	tm.Delete(1)
+	tm.Delete(100) // not present, not counted
+	tm.DeleteFunc(func(key int, _ int) bool {
+		return key == 2
+	})
+
+	time.Sleep(s.sleepTime) // 0, 3 and 4 expire
+
+	tm.StoreWithTTL(5, 5, time.Minute)
+	tm.StoreWithTTL(6, 6, time.Minute)
+	tm.Clear()
+
+	stats := tm.Stats()
+	s.Zero(stats.Length)
+	s.Equal(ttl.Removals{
+		Expired:  3,
+		Deleted:  2,
+		Cleared:  2,
+		Replaced: 1,
+	}, stats.Removals)
+	s.Equal(uint64(8), stats.Removals.Total())
+}
+
+func (s *MapTestSuite) TestStatsQuantiles() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[int, int](
+		s.maxTTL,
+		s.startSize,
+		s.pruneInterval,
+		refreshOnLoad,
+		ttl.WithQuantileStats(true))
+	defer tm.Close()
+
+	for i := 0; i < 100; i++ {
+		tm.Store(i, i)
+		tm.Load(i)
+		tm.LoadPassive(i)
+	}
+
+	time.Sleep(s.sleepTime)
+
+	stats := tm.Stats()
+	s.Equal(uint64(100), stats.StoreLatency.Count)
+	s.Equal(uint64(200), stats.LoadLatency.Count)
+	s.LessOrEqual(stats.LoadLatency.P50, stats.LoadLatency.P99)
+
+	if s.Equal(uint64(100), stats.EvictionAge.Count) {
+		// Entries expire after the TTL, and are pruned at most one prune interval later
+		tolerance := s.maxTTL / 8
+		s.GreaterOrEqual(stats.EvictionAge.P50, s.maxTTL-tolerance)
+		s.LessOrEqual(stats.EvictionAge.P99, s.maxTTL+s.pruneInterval+tolerance+tolerance)
+	}
+}
+
+func (s *MapTestSuite) TestStatsQuantilesDisabled() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[int, int](s.maxTTL, s.startSize, s.pruneInterval, refreshOnLoad)
+	defer tm.Close()
+
+	tm.Store(0, 0)
+	tm.Load(0)
+
+	stats := tm.Stats()
+	s.Zero(stats.StoreLatency)
+	s.Zero(stats.LoadLatency)
+	s.Zero(stats.EvictionAge)
+}
+
+func (s *MapTestSuite) TestStatsScopes() {
+	refreshOnLoad := true
+	tm := ttl.NewMap[string, int](
+		s.maxTTL,
+		s.startSize,
+		s.pruneInterval,
+		refreshOnLoad,
+		ttl.WithStatsScope(func(key string) string {
+			tenant, _, _ := strings.Cut(key, ":")
+			return tenant
+		}))
+	defer tm.Close()
+
+	tm.Store("a:1", 1)
+	tm.Store("a:2", 2)
+	tm.StoreWithTTL("b:1", 3, time.Minute)
+
+	tm.Load("a:1")
+	tm.Load("a:3")
+	tm.LoadPassive("b:1")
+	tm.LoadPassive("b:2")
+	tm.LoadPassive("b:3")
+
+	time.Sleep(s.sleepTime)
+
+	stats := tm.Stats()
+	s.Equal(map[string]ttl.ScopeStats{
+		"a": {Entries: 0, Hits: 1, Misses: 1, Evictions: 2},
+		"b": {Entries: 1, Hits: 1, Misses: 2, Evictions: 0},
+	}, stats.Scopes)
+	s.InDelta(0.5, stats.Scopes["a"].HitRatio(), 0.001)
+	s.Zero(ttl.ScopeStats{}.HitRatio())
+}
+
+func (s *MapTestSuite) TestStatsCounters() {
+	tm := ttl.New[string, int](
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0),
+		ttl.WithStats(true))
+	defer tm.Close()
+
+	tm.Store("a", 1)
+	tm.Store("a", 2)
+	tm.StoreWithTTL("b", 3, time.Minute)
+
+	tm.Load("a")
+	tm.LoadPassive("b")
+	tm.Load("missing")
+
+	stats := tm.Stats()
+	s.Equal(uint64(2), stats.Hits)
+	s.Equal(uint64(1), stats.Misses)
+	s.Equal(uint64(3), stats.Stores)
+	s.InDelta(2.0/3, stats.HitRatio(), 0.001)
+	s.Equal(uint64(1), stats.Removals.Replaced)
+
+	tm.ResetStats()
+
+	stats = tm.Stats()
+	s.Equal(2, stats.Length)
+	s.Zero(stats.Hits + stats.Misses + stats.Stores)
+	s.Zero(stats.Removals.Total())
+	s.Zero(stats.HitRatio())
+
+	tm.Load("a")
+	s.Equal(uint64(1), tm.Stats().Hits)
+}
+
+func (s *MapTestSuite) TestStatsCountersDisabled() {
+	tm := ttl.New[string, int](ttl.WithPruneInterval(0))
+	defer tm.Close()
+
+	tm.Store("a", 1)
+	tm.Load("a")
+	tm.Load("missing")
+
+	stats := tm.Stats()
+	s.Zero(stats.Hits + stats.Misses + stats.Stores)
+}
+
+func (s *MapTestSuite) TestPublishExpvar() {
+	tm := ttl.New[string, int](
+		ttl.WithPruneInterval(0),
+		ttl.WithStats(true))
+	defer tm.Close()
+
+	// Published variables cannot be removed, so the name must be unique if the test is repeated
+	name := fmt.Sprintf("TestPublishExpvar-%d", time.Now().UnixNano())
+	ttl.PublishExpvar(name, tm)
+
+	tm.Store("a", 1)
+	tm.Store("b", 2)
+	tm.Load("a")
+	tm.Load("missing")
+	tm.Delete("b")
+
+	var published struct {
+		Length   int               `json:"length"`
+		Hits     uint64            `json:"hits"`
+		Misses   uint64            `json:"misses"`
+		Stores   uint64            `json:"stores"`
+		HitRatio float64           `json:"hit_ratio"`
+		Removals map[string]uint64 `json:"removals"`
+	}
+
+	s.Require().NoError(json.Unmarshal([]byte(expvar.Get(name).String()), &published))
+	s.Equal(1, published.Length)
+	s.Equal(uint64(1), published.Hits)
+	s.Equal(uint64(1), published.Misses)
+	s.Equal(uint64(2), published.Stores)
+	s.Equal(0.5, published.HitRatio)
+	s.Equal(uint64(1), published.Removals["deleted"])
+	s.Zero(published.Removals["expired"])
+}
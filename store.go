@@ -0,0 +1,279 @@
+package ttl
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Store is a backing store for a [Map], such as a database or a remote cache, to which the changes
+// made to the [Map] are written using [WithWriteThrough] or [WithWriteBehind]. Implementations
+// must be safe for concurrent use.
+type Store[K comparable, V any] interface {
+	// Get returns the value stored under key and the time to live it has remaining, which is zero
+	// if it never expires. ok is false if key is not found or has expired.
+	Get(ctx context.Context, key K) (value V, TTL time.Duration, ok bool, err error)
+
+	// Set stores value under key for the given time to live. A zero or negative TTL means the
+	// value never expires.
+	Set(ctx context.Context, key K, value V, TTL time.Duration) error
+
+	// Delete removes key from the store. It is not an error if key is not found.
+	Delete(ctx context.Context, key K) error
+}
+
+// RangeStore is a [Store] whose entries can be listed, so that a [Map] can be warmed from it using
+// [Map.Warm].
+type RangeStore[K comparable, V any] interface {
+	Store[K, V]
+
+	// Range calls f for each unexpired entry in the store, with the time to live it has remaining
+	// (zero if it never expires), until f returns false.
+	Range(ctx context.Context, f func(key K, value V, TTL time.Duration) bool) error
+}
+
+// LoaderFunc adapts a function that loads the value of a key, such as a database query, to a
+// read-only [Store] for use with [WithReadThrough]. The function returns the time to live of the
+// value (zero if it never expires) and whether the key was found. Set and Delete do nothing.
+type LoaderFunc[K comparable, V any] func(ctx context.Context, key K) (value V, TTL time.Duration, ok bool, err error)
+
+// Get calls f(ctx, key).
+func (f LoaderFunc[K, V]) Get(ctx context.Context, key K) (value V, TTL time.Duration, ok bool, err error) {
+	return f(ctx, key)
+}
+
+// Set does nothing.
+func (f LoaderFunc[K, V]) Set(ctx context.Context, key K, value V, TTL time.Duration) error {
+	return nil
+}
+
+// Delete does nothing.
+func (f LoaderFunc[K, V]) Delete(ctx context.Context, key K) error {
+	return nil
+}
+
+// write is a change to the map, queued to be written to its store once the write lock is released.
+type write[K comparable, V any] struct {
+	key     K
+	value   V
+	TTL     time.Duration
+	deleted bool
+}
+
+// deletedLocked records that key has been deleted, so that it can be deleted from the store once
+// the write lock is released. The caller must hold the write lock.
+func (m *Map[K, V]) deletedLocked(key K) {
+	if m.store != nil {
+		m.writes = append(m.writes, write[K, V]{key: key, deleted: true})
+	}
+}
+
+// resolveWritesLocked returns the writes queued while the write lock was held, reading the values
+// and remaining TTLs of the keys stored to. A key stored to and then removed is not written, as its
+// removal follows it. The caller must hold the write lock.
+func (m *Map[K, V]) resolveWritesLocked() []write[K, V] {
+	if len(m.writes) == 0 {
+		return nil
+	}
+
+	writes := m.writes[:0]
+	now := m.now()
+
+	for _, w := range m.writes {
+		if !w.deleted {
+			it, ok := m.m[w.key]
+			if !ok {
+				continue
+			}
+
+			w.value = it.value
+			if !it.immortal() {
+				w.TTL = max(it.remaining(now), 1)
+			}
+		}
+
+		writes = append(writes, w)
+	}
+
+	m.writes = nil
+
+	return writes
+}
+
+// writeThrough makes writes to the store, or queues them if the map uses write-behind. The caller
+// must hold storeMtx.
+func (m *Map[K, V]) writeThrough(writes []write[K, V]) {
+	if m.behind != nil && m.behind.enqueue(writes) {
+		return
+	}
+
+	for _, w := range writes {
+		writeStore(m.storeCtx, m.store, m.logger, w)
+	}
+}
+
+// writeStore makes w to store, logging it to logger, if not nil, if it fails.
+func writeStore[K comparable, V any](
+	ctx context.Context,
+	store Store[K, V],
+	logger *slog.Logger,
+	w write[K, V],
+) {
+	var err error
+	if w.deleted {
+		err = store.Delete(ctx, w.key)
+	} else {
+		err = store.Set(ctx, w.key, w.value, w.TTL)
+	}
+
+	if err != nil && logger != nil {
+		logger.Error("writing to store", "key", w.key, "deleted", w.deleted, "error", err)
+	}
+}
+
+// loadCall is a load of a key from the read-through store, shared by the loads of that key that
+// start while it is in progress.
+type loadCall[V any] struct {
+	done  chan struct{} // closed once value, ok and err are set
+	value V
+	ok    bool
+	err   error
+}
+
+// loadThrough loads key from the read-through store after it was not found in the map, storing
+// its value in the map with the time to live it has remaining in the store. Concurrent loads of
+// the same key share a single read from the store, made with the context of the first; the others
+// stop waiting for it if their own ctx is done.
+func (m *Map[K, V]) loadThrough(ctx context.Context, key K) (value V, ok bool, err error) {
+	m.loadMtx.Lock()
+
+	if c, loading := m.loading[key]; loading {
+		m.loadMtx.Unlock()
+
+		select {
+		case <-c.done:
+			return c.value, c.ok, c.err
+		case <-ctx.Done():
+			return value, false, ctx.Err()
+		}
+	}
+
+	if m.loading == nil {
+		m.loading = make(map[K]*loadCall[V])
+	}
+
+	c := &loadCall[V]{done: make(chan struct{})}
+	m.loading[key] = c
+	m.loadMtx.Unlock()
+
+	defer func() {
+		m.loadMtx.Lock()
+		delete(m.loading, key)
+		m.loadMtx.Unlock()
+
+		close(c.done)
+	}()
+
+	c.value, c.ok, c.err = m.readThroughStore(ctx, key)
+
+	return c.value, c.ok, c.err
+}
+
+// readThroughStore reads key from the read-through store and, if it is found, stores it in the map.
+func (m *Map[K, V]) readThroughStore(ctx context.Context, key K) (value V, ok bool, err error) {
+	value, TTL, ok, err := m.readThrough.Get(ctx, key)
+	if err != nil || !ok {
+		return value, false, err
+	}
+
+	m.lock()
+	defer m.unlock()
+
+	// The key may have been stored while the lock was not held, in which case that value is newer
+	if it, live := m.liveItemLocked(key); live {
+		return it.value, true, nil
+	}
+
+	n := len(m.writes)
+
+	it, _ := m.storeItemLocked(key)
+	it.value = value
+	it.itemTTL = TTL
+	it.policy = RefreshDefault
+	it.defaulted = false
+	it.touch(m.now())
+
+	m.writes = m.writes[:n]
+
+	return value, true, nil
+}
+
+// Warm stores the unexpired entries of store in the [Map], each with the time to live it has
+// remaining in store, replacing any existing entries with the same key. It is intended to warm a
+// [Map] that writes through to store using [WithWriteThrough] when it is created, so the entries
+// are not written back to store. Entries are stored in batches as store lists them.
+//
+// If Warm returns an error, the entries listed before the error may have been stored. Warm is safe
+// for concurrent use.
+func (m *Map[K, V]) Warm(ctx context.Context, store RangeStore[K, V]) error {
+	return warm(ctx, store, m.clock.Now, m.warmBatch)
+}
+
+// Warm is like [Map.Warm].
+func (s *ShardedMap[K, V]) Warm(ctx context.Context, store RangeStore[K, V]) error {
+	return warm(ctx, store, s.shards[0].clock.Now, func(batch Snapshot[K, V]) {
+		byShard := make(map[int][]SnapshotEntry[K, V])
+		for _, e := range batch.Entries {
+			i := s.shardIndex(e.Key)
+			byShard[i] = append(byShard[i], e)
+		}
+
+		for i, entries := range byShard {
+			s.shards[i].warmBatch(Snapshot[K, V]{Taken: batch.Taken, Entries: entries})
+		}
+	})
+}
+
+// warmBatch restores batch without writing its entries to the store.
+func (m *Map[K, V]) warmBatch(batch Snapshot[K, V]) {
+	m.lock()
+	defer m.unlock()
+
+	n := len(m.writes)
+	m.restoreLocked(batch, RebasePolicy{Mode: RebaseResume})
+	m.writes = m.writes[:n]
+}
+
+// warm lists the entries of store, passing them to restore in batches, each as a Snapshot taken
+// at the time returned by now.
+func warm[K comparable, V any](
+	ctx context.Context,
+	store RangeStore[K, V],
+	now func() time.Time,
+	restore func(Snapshot[K, V]),
+) error {
+	batch := Snapshot[K, V]{Taken: now()}
+
+	err := store.Range(ctx, func(key K, value V, TTL time.Duration) bool {
+		batch.Entries = append(batch.Entries, SnapshotEntry[K, V]{
+			Key:        key,
+			Value:      value,
+			TTL:        TTL,
+			LastAccess: batch.Taken,
+		})
+
+		if len(batch.Entries) == loadBatch {
+			restore(batch)
+			batch.Entries = batch.Entries[:0]
+			batch.Taken = now()
+		}
+
+		return true
+	})
+
+	if len(batch.Entries) > 0 {
+		restore(batch)
+	}
+
+	return err
+}
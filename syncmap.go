@@ -0,0 +1,107 @@
+package ttl
+
+// SyncMap has the method set of [sync.Map], with type parameters in place of any, backed by a
+// [Map], so that code using a sync.Map can adopt expiry by changing only its declaration and
+// construction:
+//
+//	var sessions sync.Map
+//
+// becomes
+//
+//	sessions := ttl.NewSyncMap[string, *Session](ttl.WithTTL(30 * time.Minute))
+//	defer sessions.Close()
+//
+// Entries are stored with the default time to live, and loading them extends it unless the
+// options include WithRefreshOnLoad(false).
+//
+// SyncMap is safe for concurrent use.
+type SyncMap[K comparable, V any] struct {
+	m *Map[K, V]
+}
+
+// NewSyncMap returns a new, empty [SyncMap] configured by opts, like [New].
+func NewSyncMap[K comparable, V any](opts ...Option) *SyncMap[K, V] {
+	return &SyncMap[K, V]{
+		m: New[K, V](opts...),
+	}
+}
+
+// Load returns the value of key, as well as a bool indicating whether it was found, like
+// [Map.Load].
+func (sm *SyncMap[K, V]) Load(key K) (value V, ok bool) {
+	return sm.m.Load(key)
+}
+
+// Store stores value for key, like [Map.Store].
+func (sm *SyncMap[K, V]) Store(key K, value V) {
+	sm.m.Store(key, value)
+}
+
+// LoadOrStore returns the value of key if it is present. Otherwise, it stores and returns value.
+// The loaded result is true if the value was loaded, false if stored. See [Map.LoadOrStore].
+func (sm *SyncMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	return sm.m.LoadOrStore(key, value)
+}
+
+// LoadAndDelete deletes key, returning its previous value if any. The loaded result reports
+// whether the key was present. See [Map.LoadAndDelete].
+func (sm *SyncMap[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	return sm.m.LoadAndDelete(key)
+}
+
+// Delete deletes key, like [Map.Delete].
+func (sm *SyncMap[K, V]) Delete(key K) {
+	sm.m.Delete(key)
+}
+
+// Swap stores value for key and returns its previous value if any. The loaded result reports
+// whether the key was present.
+func (sm *SyncMap[K, V]) Swap(key K, value V) (previous V, loaded bool) {
+	return sm.m.swap(key, value)
+}
+
+// CompareAndSwap stores value for key if its current value is equal to old, and reports whether
+// it did. Values are compared as any(old) == any(current), so, as with [sync.Map], CompareAndSwap
+// panics if V's dynamic type is not comparable.
+func (sm *SyncMap[K, V]) CompareAndSwap(key K, old, value V) (swapped bool) {
+	return sm.m.compareAndSwap(key, old, value)
+}
+
+// CompareAndDelete deletes key if its current value is equal to old, and reports whether it did.
+// Values are compared as for [SyncMap.CompareAndSwap].
+func (sm *SyncMap[K, V]) CompareAndDelete(key K, old V) (deleted bool) {
+	return sm.m.compareAndDelete(key, old)
+}
+
+// Range calls f sequentially for each key and value present in the map. If f returns false, Range
+// stops the iteration. Unlike [Map.Range], Range iterates over a copy of the entries taken when it
+// is called, so f may call any method of the [SyncMap], as it may with a sync.Map.
+func (sm *SyncMap[K, V]) Range(f func(key K, value V) bool) {
+	for _, e := range sm.m.Entries() {
+		if !f(e.Key, e.Value) {
+			return
+		}
+	}
+}
+
+// Clear deletes every entry, like [Map.Clear].
+func (sm *SyncMap[K, V]) Clear() {
+	sm.m.Clear()
+}
+
+// Map returns the [Map] holding the entries, for access to the methods that sync.Map lacks, such
+// as [Map.StoreWithTTL].
+func (sm *SyncMap[K, V]) Map() *Map[K, V] {
+	return sm.m
+}
+
+// Close stops the [SyncMap] from pruning expired entries, like [Map.Close].
+func (sm *SyncMap[K, V]) Close() {
+	sm.m.Close()
+}
+
+// CloseWait is like [SyncMap.Close], but also waits for the prune goroutine to exit, like
+// [Map.CloseWait].
+func (sm *SyncMap[K, V]) CloseWait() {
+	sm.m.CloseWait()
+}
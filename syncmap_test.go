@@ -0,0 +1,133 @@
+package ttl_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestSyncMap() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	sm := ttl.NewSyncMap[string, int](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0))
+	defer sm.Close()
+
+	sm.Store("a", 1)
+
+	v, ok := sm.Load("a")
+	s.True(ok)
+	s.Equal(1, v)
+
+	actual, loaded := sm.LoadOrStore("a", 2)
+	s.True(loaded)
+	s.Equal(1, actual)
+
+	actual, loaded = sm.LoadOrStore("b", 2)
+	s.False(loaded)
+	s.Equal(2, actual)
+
+	previous, loaded := sm.Swap("b", 3)
+	s.True(loaded)
+	s.Equal(2, previous)
+
+	_, loaded = sm.Swap("c", 4)
+	s.False(loaded)
+
+	seen := make(map[string]int)
+	sm.Range(func(key string, value int) bool {
+		seen[key] = value
+		return true
+	})
+	s.Equal(map[string]int{"a": 1, "b": 3, "c": 4}, seen)
+
+	v, loaded = sm.LoadAndDelete("a")
+	s.True(loaded)
+	s.Equal(1, v)
+
+	_, loaded = sm.LoadAndDelete("a")
+	s.False(loaded)
+
+	sm.Delete("b")
+	_, ok = sm.Load("b")
+	s.False(ok)
+
+	// Entries expire
+	clock.Advance(time.Minute)
+	_, ok = sm.Load("c")
+	s.False(ok)
+
+	_, loaded = sm.LoadAndDelete("c")
+	s.False(loaded)
+	s.Zero(sm.Map().Length())
+
+	// Only one of concurrent calls loads and deletes a key
+	sm.Store("d", 5)
+
+	var deleted atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, loaded := sm.LoadAndDelete("d"); loaded {
+				deleted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	s.Equal(int64(1), deleted.Load())
+}
+
+func (s *MapTestSuite) TestSyncMapCompare() {
+	sm := ttl.NewSyncMap[string, int](ttl.WithTTL(time.Minute), ttl.WithPruneInterval(0))
+	defer sm.Close()
+
+	sm.Store("a", 1)
+
+	s.False(sm.CompareAndSwap("a", 2, 3))
+	s.False(sm.CompareAndSwap("b", 0, 3))
+	s.True(sm.CompareAndSwap("a", 1, 3))
+
+	v, _ := sm.Load("a")
+	s.Equal(3, v)
+
+	s.False(sm.CompareAndDelete("a", 1))
+	s.True(sm.CompareAndDelete("a", 3))
+
+	_, ok := sm.Load("a")
+	s.False(ok)
+
+	sm.Store("b", 1)
+	sm.Store("c", 2)
+	sm.Clear()
+	s.Zero(sm.Map().Length())
+}
+
+func (s *MapTestSuite) TestSyncMapRangeModify() {
+	sm := ttl.NewSyncMap[string, int](ttl.WithTTL(time.Minute), ttl.WithPruneInterval(0))
+	defer sm.Close()
+
+	sm.Store("a", 1)
+	sm.Store("b", 2)
+
+	// f may call other methods without deadlocking
+	sm.Range(func(key string, value int) bool {
+		sm.Delete(key)
+		sm.Store(key+key, value*10)
+		return true
+	})
+
+	seen := make(map[string]int)
+	sm.Range(func(key string, value int) bool {
+		seen[key] = value
+		return true
+	})
+	s.Equal(map[string]int{"aa": 10, "bb": 20}, seen)
+}
@@ -0,0 +1,90 @@
+package ttl
+
+// StoreTagged stores value with the default time to live, like [Map.Store], and associates key
+// with tags, replacing any tags it had, so that it can be removed along with every other entry
+// sharing one of its tags using [Map.DeleteByTag]. This is the usual way to invalidate all the
+// entries derived from a record when the record is written. A key keeps its tags when it is
+// stored again using the other store methods, until it is removed. StoreTagged is safe for
+// concurrent use.
+func (m *Map[K, V]) StoreTagged(key K, value V, tags ...string) {
+	m.lock()
+	defer m.unlock()
+
+	m.storeLocked(key, value)
+	m.tagLocked(key, m.m[key], tags)
+}
+
+// DeleteByTag removes every entry tagged with tag by [Map.StoreTagged] in a single locked
+// operation, returning the number removed. Entries that have expired but have not been pruned yet
+// are removed as expired and are not counted. DeleteByTag is safe for concurrent use.
+func (m *Map[K, V]) DeleteByTag(tag string) (deleted int) {
+	m.lock()
+	defer m.unlock()
+
+	keys := m.tags[tag]
+	if len(keys) == 0 {
+		return 0
+	}
+
+	now := m.now()
+
+	// Removing the entries removes their keys from the tags, so the keys are collected first
+	remove := make([]K, 0, len(keys))
+	for key := range keys {
+		remove = append(remove, key)
+	}
+
+	for _, key := range remove {
+		if m.m[key].expired(now) {
+			m.removeLocked(key, ReasonExpired)
+			continue
+		}
+
+		m.removeLocked(key, ReasonDeleted)
+		deleted++
+	}
+
+	return
+}
+
+// tagLocked replaces the tags of key, whose item is it, with tags. The caller must hold the write
+// lock.
+func (m *Map[K, V]) tagLocked(key K, it *mapItem[V], tags []string) {
+	m.untagLocked(key, it)
+
+	if len(tags) == 0 {
+		return
+	}
+
+	if m.tags == nil {
+		m.tags = make(map[string]map[K]struct{})
+	}
+
+	it.tags = make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		keys, ok := m.tags[tag]
+		if !ok {
+			keys = make(map[K]struct{})
+			m.tags[tag] = keys
+		}
+
+		if _, dup := keys[key]; !dup {
+			keys[key] = struct{}{}
+			it.tags = append(it.tags, tag)
+		}
+	}
+}
+
+// untagLocked removes key, whose item is it, from its tags. The caller must hold the write lock.
+func (m *Map[K, V]) untagLocked(key K, it *mapItem[V]) {
+	for _, tag := range it.tags {
+		delete(m.tags[tag], key)
+
+		if len(m.tags[tag]) == 0 {
+			delete(m.tags, tag)
+		}
+	}
+
+	it.tags = nil
+}
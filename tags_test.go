@@ -0,0 +1,77 @@
+package ttl_test
+
+import (
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestStoreTagged() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	m := ttl.New[string, int](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0))
+	defer m.Close()
+
+	keys := func() (keys []string) {
+		m.Range(func(key string, _ int) bool {
+			keys = append(keys, key)
+			return true
+		})
+		return
+	}
+
+	m.StoreTagged("product:1", 1, "product:1", "catalog")
+	m.StoreTagged("product:2", 2, "product:2", "catalog")
+	m.StoreTagged("listing", 3, "product:1", "product:2", "catalog", "catalog")
+	m.Store("untagged", 4)
+
+	s.Equal(2, m.DeleteByTag("product:1"))
+	s.ElementsMatch([]string{"product:2", "untagged"}, keys())
+	s.Zero(m.DeleteByTag("product:1"))
+
+	// Storing a key again keeps its tags, while StoreTagged replaces them
+	m.Store("product:2", 5)
+	m.StoreTagged("other", 6, "catalog")
+	m.StoreTagged("other", 7, "other")
+	s.Equal(1, m.DeleteByTag("catalog"))
+	s.ElementsMatch([]string{"other", "untagged"}, keys())
+
+	// Renamed keys keep their tags, and keys replaced by a rename lose theirs
+	m.StoreTagged("a", 8, "t")
+	m.StoreTagged("b", 9, "u")
+	m.Rename("a", "b")
+	s.Zero(m.DeleteByTag("u"))
+	s.Equal(1, m.DeleteByTag("t"))
+	s.ElementsMatch([]string{"other", "untagged"}, keys())
+
+	// Expired entries are removed but not counted
+	m.StoreTagged("c", 10, "v")
+	m.Expire("c")
+	s.Zero(m.DeleteByTag("v"))
+	s.Equal(2, m.Length())
+
+	m.Clear()
+	m.Store("other", 11)
+	s.Zero(m.DeleteByTag("other"))
+}
+
+func (s *MapTestSuite) TestShardedStoreTagged() {
+	m := ttl.NewShardedMap[int, int](ttl.WithShards(4), ttl.WithPruneInterval(0))
+	defer m.Close()
+
+	for i := 0; i < 100; i++ {
+		m.StoreTagged(i, i, "all")
+	}
+
+	// Keys renamed between shards keep their tags
+	for i := 0; i < 100; i++ {
+		m.Rename(i, i+100)
+	}
+
+	s.Equal(100, m.DeleteByTag("all"))
+	s.Zero(m.Length())
+}
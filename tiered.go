@@ -0,0 +1,214 @@
+package ttl
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache is the part of the API of [Map] and [ShardedMap] used by [Tiered], which also implements
+// it, so tiers can be nested.
+type Cache[K comparable, V any] interface {
+	Load(key K) (value V, ok bool)
+	LoadPassive(key K) (value V, ok bool)
+	TTL(key K) (remaining time.Duration, ok bool)
+	ExpirationTime(key K) (expireAt time.Time, ok bool)
+	Store(key K, value V)
+	StoreWithTTL(key K, value V, TTL time.Duration)
+	StoreWithExpireAt(key K, value V, expireAt time.Time)
+	Delete(key K)
+	Clear()
+	Length() int
+	Range(f func(key K, value V) bool)
+	Close()
+}
+
+var (
+	_ Cache[string, int] = (*Map[string, int])(nil)
+	_ Cache[string, int] = (*ShardedMap[string, int])(nil)
+	_ Cache[string, int] = (*Tiered[string, int])(nil)
+)
+
+// tieredLocks is the number of locks a Tiered divides its keys between.
+const tieredLocks = 64
+
+// Tiered composes two caches into one: a small, fast first tier (L1), such as a [Map] limited
+// using [WithMaxEntries], in front of a larger second tier (L2), such as a [ShardedMap] or a [Map]
+// using [WithReadThrough] to reach a remote store. L2 holds every entry and is the authority on
+// their times to live; L1 holds copies of the entries most recently loaded or stored.
+//
+// A key found in L2 by [Tiered.Load] is promoted to L1. Entries are copied to L1 with the
+// expiration time they have in L2 as a fixed expiry (see [Map.StoreWithExpireAt]), so a copy
+// never outlives its entry in L2; entries that never expire in L2 are stored in L1 with its default
+// TTL. Loads served by L1 do not extend the lifetime of the entry in L2.
+//
+// Stores write L2 and then copy the entry to L1, and deletes remove the key from both tiers.
+// Promotions and writes of the same key are serialized, so a load racing a store cannot promote a
+// value that the store has replaced. Changes made to either tier directly bypass this, and L1 may
+// then hold a stale copy until it expires.
+//
+// Tiered is safe for concurrent use.
+type Tiered[K comparable, V any] struct {
+	l1    Cache[K, V]
+	l2    Cache[K, V]
+	hash  func(K) uint64
+	locks [tieredLocks]sync.Mutex
+}
+
+// NewTiered returns a [Tiered] that caches the entries of l2 in l1. l1 and l2 must be distinct,
+// and they should have no entries in common when NewTiered is called. [Tiered.Close] closes both.
+func NewTiered[K comparable, V any](l1, l2 Cache[K, V]) *Tiered[K, V] {
+	return &Tiered[K, V]{
+		l1:   l1,
+		l2:   l2,
+		hash: defaultHasher[K](),
+	}
+}
+
+// Load retrieves a value from L1 or, if it is not found there, from L2, promoting it to L1. The
+// bool returned indicates whether the key was found in either. Load is safe for concurrent use.
+func (t *Tiered[K, V]) Load(key K) (value V, ok bool) {
+	if value, ok = t.l1.Load(key); ok {
+		return
+	}
+
+	mtx := t.lock(key)
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	if value, ok = t.l2.Load(key); ok {
+		t.copyLocked(key, value)
+	}
+
+	return
+}
+
+// LoadPassive retrieves a value from L1 or, if it is not found there, from L2, like
+// [Map.LoadPassive]. It does not update the value's time to live in either tier or promote it to
+// L1. LoadPassive is safe for concurrent use.
+func (t *Tiered[K, V]) LoadPassive(key K) (value V, ok bool) {
+	if value, ok = t.l1.LoadPassive(key); ok {
+		return
+	}
+
+	return t.l2.LoadPassive(key)
+}
+
+// TTL returns the time to live remaining for key in L2 or, if it is only found in L1, in L1, like
+// [Map.TTL]. TTL is safe for concurrent use.
+func (t *Tiered[K, V]) TTL(key K) (remaining time.Duration, ok bool) {
+	if remaining, ok = t.l2.TTL(key); ok {
+		return
+	}
+
+	return t.l1.TTL(key)
+}
+
+// ExpirationTime returns the time at which key will expire from L2 or, if it is only found in L1,
+// from L1, like [Map.ExpirationTime]. ExpirationTime is safe for concurrent use.
+func (t *Tiered[K, V]) ExpirationTime(key K) (expireAt time.Time, ok bool) {
+	if expireAt, ok = t.l2.ExpirationTime(key); ok {
+		return
+	}
+
+	return t.l1.ExpirationTime(key)
+}
+
+// Store inserts a value into L2 like [Map.Store], then copies it to L1. Store is safe for
+// concurrent use.
+func (t *Tiered[K, V]) Store(key K, value V) {
+	t.write(key, value, func() {
+		t.l2.Store(key, value)
+	})
+}
+
+// StoreWithTTL inserts a value into L2 like [Map.StoreWithTTL], then copies it to L1.
+// StoreWithTTL is safe for concurrent use.
+func (t *Tiered[K, V]) StoreWithTTL(key K, value V, TTL time.Duration) {
+	t.write(key, value, func() {
+		t.l2.StoreWithTTL(key, value, TTL)
+	})
+}
+
+// StoreWithExpireAt inserts a value into L2 like [Map.StoreWithExpireAt], then copies it to L1.
+// StoreWithExpireAt is safe for concurrent use.
+func (t *Tiered[K, V]) StoreWithExpireAt(key K, value V, expireAt time.Time) {
+	t.write(key, value, func() {
+		t.l2.StoreWithExpireAt(key, value, expireAt)
+	})
+}
+
+// Delete removes key from both tiers. Delete is safe for concurrent use.
+func (t *Tiered[K, V]) Delete(key K) {
+	mtx := t.lock(key)
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	t.l2.Delete(key)
+	t.l1.Delete(key)
+}
+
+// Clear removes every entry from both tiers. Clear is safe for concurrent use.
+func (t *Tiered[K, V]) Clear() {
+	t.l2.Clear()
+	t.l1.Clear()
+}
+
+// Length returns the number of entries in L2, which holds every entry. Length is safe for
+// concurrent use.
+func (t *Tiered[K, V]) Length() int {
+	return t.l2.Length()
+}
+
+// Range calls f for each entry in L2, like [Map.Range]. Range is safe for concurrent use.
+func (t *Tiered[K, V]) Range(f func(key K, value V) bool) {
+	t.l2.Range(f)
+}
+
+// Close closes both tiers.
+func (t *Tiered[K, V]) Close() {
+	t.l1.Close()
+	t.l2.Close()
+}
+
+// CloseWait closes both tiers, then waits for their background goroutines to exit, using the
+// CloseWait method of each tier that has one, such as [Map.CloseWait]. Tiers without one are
+// closed using Close.
+func (t *Tiered[K, V]) CloseWait() {
+	for _, tier := range []Cache[K, V]{t.l1, t.l2} {
+		if w, ok := tier.(interface{ CloseWait() }); ok {
+			w.CloseWait()
+		} else {
+			tier.Close()
+		}
+	}
+}
+
+// write makes a change to key in L2 using store, then copies value to L1.
+func (t *Tiered[K, V]) write(key K, value V, store func()) {
+	mtx := t.lock(key)
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	store()
+	t.copyLocked(key, value)
+}
+
+// copyLocked copies value to L1 with the expiration time key has in L2. If key is no longer in L2,
+// it is removed from L1. The caller must hold the lock of key.
+func (t *Tiered[K, V]) copyLocked(key K, value V) {
+	expireAt, ok := t.l2.ExpirationTime(key)
+
+	switch {
+	case !ok:
+		t.l1.Delete(key)
+	case expireAt.IsZero():
+		t.l1.Store(key, value)
+	default:
+		t.l1.StoreWithExpireAt(key, value, expireAt)
+	}
+}
+
+// lock returns the lock of key.
+func (t *Tiered[K, V]) lock(key K) *sync.Mutex {
+	return &t.locks[t.hash(key)%tieredLocks]
+}
@@ -0,0 +1,97 @@
+package ttl_test
+
+import (
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestTiered() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	l1 := ttl.New[string, int](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Minute),
+		ttl.WithPruneInterval(0),
+		ttl.WithMaxEntries(2))
+	l2 := ttl.NewShardedMap[string, int](
+		ttl.WithClock(clock),
+		ttl.WithTTL(time.Hour),
+		ttl.WithPruneInterval(0))
+
+	tiered := ttl.NewTiered[string, int](l1, l2)
+	defer tiered.Close()
+
+	// Stores are spaced out so that L1 evicts the least recently accessed
+	tiered.StoreWithTTL("a", 1, 10*time.Minute)
+	clock.Advance(time.Second)
+	tiered.Store("b", 2)
+	clock.Advance(time.Second)
+	tiered.Store("c", 3)
+
+	s.Equal(3, tiered.Length())
+	s.Equal(2, l1.Length())
+
+	// Copies in L1 expire with their entries in L2
+	expireAt, ok := l1.ExpirationTime("c")
+	s.True(ok)
+	s.Equal(clock.Now().Add(time.Hour), expireAt)
+
+	// A key evicted from L1 is promoted again when it is loaded from L2
+	_, ok = l1.LoadPassive("a")
+	s.False(ok)
+
+	v, ok := tiered.Load("a")
+	s.True(ok)
+	s.Equal(1, v)
+
+	expireAt, ok = l1.ExpirationTime("a")
+	s.True(ok)
+	s.Equal(clock.Now().Add(10*time.Minute), expireAt)
+
+	// Entries stored directly in L2 are promoted too, while LoadPassive does not promote
+	l2.StoreWithTTL("d", 4, 0)
+
+	v, ok = tiered.LoadPassive("d")
+	s.True(ok)
+	s.Equal(4, v)
+	_, ok = l1.LoadPassive("d")
+	s.False(ok)
+
+	clock.Advance(time.Second)
+	_, ok = tiered.Load("d")
+	s.True(ok)
+	remaining, ok := l1.TTL("d")
+	s.True(ok)
+	s.Equal(time.Minute, remaining)
+
+	remaining, ok = tiered.TTL("d")
+	s.True(ok)
+	s.Equal(ttl.NoExpiry, remaining)
+
+	tiered.Delete("a")
+	_, ok = tiered.Load("a")
+	s.False(ok)
+
+	clock.Advance(10 * time.Minute)
+
+	_, ok = tiered.Load("d")
+	s.True(ok)
+
+	tiered.Clear()
+	s.Zero(l1.Length())
+	s.Zero(l2.Length())
+}
+
+func (s *MapTestSuite) TestTieredCloseWait() {
+	l1 := ttl.New[string, int](ttl.WithPruneInterval(time.Millisecond))
+	l2 := ttl.NewShardedMap[string, int](ttl.WithPruneInterval(time.Millisecond))
+
+	tiered := ttl.NewTiered[string, int](l1, l2)
+	tiered.Store("a", 1)
+
+	// The goroutines of both tiers have exited when the suite checks for leaks
+	tiered.CloseWait()
+	tiered.CloseWait()
+}
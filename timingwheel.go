@@ -0,0 +1,187 @@
+package ttl
+
+const (
+	// wheelBits is the number of bits of the tick count resolved by each level of a timingWheel.
+	wheelBits = 6
+
+	// wheelSlots is the number of slots in each level of a timingWheel.
+	wheelSlots = 1 << wheelBits
+
+	// wheelLevels is the number of levels of a timingWheel. With 64 slots per level, four levels
+	// cover 2^24 ticks: over 194 days with a one second tick. Keys due later than that are parked
+	// in the last slot of the top level and cascaded again when it is reached.
+	wheelLevels = 4
+
+	// wheelDue is the level of a key that is due and waiting to be popped.
+	wheelDue = -1
+)
+
+// expiryIndex orders the keys of a [Map] by expiration time for strategies that only check the
+// items that are due to expire. It is implemented by [ExpiryList] and timingWheel.
+type expiryIndex[K comparable] interface {
+	set(key K, expireAt int64)
+	Remove(key K) bool
+	Clear()
+	popExpired(now int64, limit int) []K
+}
+
+// timingWheel is a hierarchical timing wheel: an expiryIndex whose set and remove operations are
+// O(1), regardless of the number of keys, at the cost of only resolving expiration times to the
+// nearest tick. Each level has wheelSlots slots, each covering wheelSlots times as many ticks as a
+// slot of the level below. Keys are placed in the lowest level that can hold their expiration time
+// and move down a level (cascade) as the wheel turns, until they reach the bottom level and become
+// due.
+//
+// A timingWheel is not safe for concurrent use.
+type timingWheel[K comparable] struct {
+	tick   int64 // nanoseconds per tick
+	cur    int64 // ticks since the Unix epoch that the wheel has turned to
+	levels [wheelLevels][wheelSlots]map[K]struct{}
+	due    map[K]struct{}
+	pos    map[K]wheelPos
+}
+
+// wheelPos is the position of a key in a timingWheel.
+type wheelPos struct {
+	expireAt int64 // Unix nanoseconds
+	level    int
+	slot     int
+}
+
+// newTimingWheel returns an empty timingWheel with the given tick, in nanoseconds, turned to now,
+// expressed in Unix nanoseconds.
+func newTimingWheel[K comparable](tick int64, now int64) *timingWheel[K] {
+	return &timingWheel[K]{
+		tick: tick,
+		cur:  now / tick,
+		due:  make(map[K]struct{}),
+		pos:  make(map[K]wheelPos),
+	}
+}
+
+// set adds key to the wheel with the given expiration time, or moves it if it is already present.
+func (w *timingWheel[K]) set(key K, expireAt int64) {
+	w.Remove(key)
+	w.place(key, expireAt)
+}
+
+// place adds key, which must not be present, to the wheel.
+func (w *timingWheel[K]) place(key K, expireAt int64) {
+	// Round up, so that a key is never due before its expiration time
+	t := expireAt / w.tick
+	if expireAt%w.tick > 0 {
+		t++
+	}
+
+	if t <= w.cur {
+		w.due[key] = struct{}{}
+		w.pos[key] = wheelPos{expireAt: expireAt, level: wheelDue}
+		return
+	}
+
+	level := 0
+	for delta := t - w.cur; delta >= 1<<(wheelBits*(level+1)); level++ {
+		if level == wheelLevels-1 {
+			// Too far in the future: park it at the furthest slot and cascade it again later
+			t = w.cur + 1<<(wheelBits*wheelLevels) - 1
+			break
+		}
+	}
+
+	slot := int(t>>(wheelBits*level)) & (wheelSlots - 1)
+	if w.levels[level][slot] == nil {
+		w.levels[level][slot] = make(map[K]struct{})
+	}
+
+	w.levels[level][slot][key] = struct{}{}
+	w.pos[key] = wheelPos{expireAt: expireAt, level: level, slot: slot}
+}
+
+// Remove removes key from the wheel, returning false if it was not present.
+func (w *timingWheel[K]) Remove(key K) bool {
+	p, ok := w.pos[key]
+	if !ok {
+		return false
+	}
+
+	delete(w.pos, key)
+
+	if p.level == wheelDue {
+		delete(w.due, key)
+	} else {
+		delete(w.levels[p.level][p.slot], key)
+	}
+
+	return true
+}
+
+// Clear removes all keys from the wheel.
+func (w *timingWheel[K]) Clear() {
+	for level := range w.levels {
+		for slot := range w.levels[level] {
+			w.levels[level][slot] = nil
+		}
+	}
+
+	clear(w.due)
+	clear(w.pos)
+}
+
+// popExpired turns the wheel to now, expressed in Unix nanoseconds, then removes and returns the
+// keys that are due, in no particular order. If limit is positive, at most limit keys are removed
+// and the rest remain due.
+func (w *timingWheel[K]) popExpired(now int64, limit int) (keys []K) {
+	w.advance(now / w.tick)
+
+	for key := range w.due {
+		if limit > 0 && len(keys) == limit {
+			break
+		}
+
+		delete(w.due, key)
+		delete(w.pos, key)
+		keys = append(keys, key)
+	}
+
+	return
+}
+
+// advance turns the wheel one tick at a time until it reaches target, cascading keys from higher
+// levels as their slots are reached and moving the keys in each bottom level slot to due.
+func (w *timingWheel[K]) advance(target int64) {
+	for w.cur < target {
+		if len(w.pos) == len(w.due) {
+			// Nothing left to cascade, so skip straight to target
+			w.cur = target
+			return
+		}
+
+		w.cur++
+
+		// Cascade from the top down, so keys can fall through several levels in one tick
+		for level := wheelLevels - 1; level > 0; level-- {
+			if w.cur&(1<<(wheelBits*level)-1) == 0 {
+				w.cascade(level, int(w.cur>>(wheelBits*level))&(wheelSlots-1))
+			}
+		}
+
+		slot := int(w.cur) & (wheelSlots - 1)
+		for key := range w.levels[0][slot] {
+			w.due[key] = struct{}{}
+			w.pos[key] = wheelPos{expireAt: w.pos[key].expireAt, level: wheelDue}
+		}
+		w.levels[0][slot] = nil
+	}
+}
+
+// cascade re-places the keys in the given slot, moving them to lower levels.
+func (w *timingWheel[K]) cascade(level int, slot int) {
+	keys := w.levels[level][slot]
+	w.levels[level][slot] = nil
+
+	for key := range keys {
+		expireAt := w.pos[key].expireAt
+		delete(w.pos, key)
+		w.place(key, expireAt)
+	}
+}
@@ -0,0 +1,107 @@
+package ttltest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+)
+
+// Clock is a fake [ttl.Clock] whose time only moves when [Clock.Advance] is called, so that tests
+// can expire items deterministically instead of sleeping:
+//
+//	clock := ttltest.NewClock(time.Now())
+//	m := ttl.New[string, int](ttl.WithClock(clock), ttl.WithTTL(time.Minute))
+//	defer m.Close()
+//
+//	m.Store("a", 1)
+//	clock.Advance(2 * time.Minute)
+//	m.Prune() // removes "a"
+//
+// Advancing the clock past a prune interval sends a tick to the map's pruning goroutine, but the
+// prune pass runs asynchronously, so tests that need to observe its effect should call Prune
+// instead. Clock is safe for concurrent use.
+type Clock struct {
+	mtx     sync.Mutex
+	now     time.Time
+	tickers map[*ticker]struct{}
+}
+
+// NewClock returns a Clock set to now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{
+		now:     now,
+		tickers: make(map[*ticker]struct{}),
+	}
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.now
+}
+
+// NewTicker returns a [ttl.Ticker] that ticks each time the clock is advanced past a multiple of d
+// since the ticker was created.
+func (c *Clock) NewTicker(d time.Duration) ttl.Ticker {
+	if d <= 0 {
+		panic("ttltest: non-positive interval for NewTicker")
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	t := &ticker{
+		clock:  c,
+		ch:     make(chan time.Time, 1),
+		period: d,
+		next:   c.now.Add(d),
+	}
+	c.tickers[t] = struct{}{}
+
+	return t
+}
+
+// Advance moves the clock forward by d and delivers any ticks that are due. Like a [time.Ticker],
+// a ticker whose previous tick has not been received drops ticks rather than blocking.
+func (c *Clock) Advance(d time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.now = c.now.Add(d)
+
+	for t := range c.tickers {
+		if c.now.Before(t.next) {
+			continue
+		}
+
+		select {
+		case t.ch <- c.now:
+		default:
+		}
+
+		for !c.now.Before(t.next) {
+			t.next = t.next.Add(t.period)
+		}
+	}
+}
+
+type ticker struct {
+	clock  *Clock
+	ch     chan time.Time
+	period time.Duration
+	next   time.Time
+}
+
+func (t *ticker) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *ticker) Stop() {
+	t.clock.mtx.Lock()
+	defer t.clock.mtx.Unlock()
+
+	delete(t.clock.tickers, t)
+}
@@ -0,0 +1,163 @@
+package ttltest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+)
+
+// Store is a fake, in-memory [ttl.RangeStore] for testing maps that use a backing store. Entries
+// expire according to the time of its clock, which should be the same clock as the map's. Store
+// is safe for concurrent use.
+type Store[K comparable, V any] struct {
+	clock ttl.Clock
+
+	mtx     sync.Mutex
+	entries map[K]storeEntry[V]
+	err     error
+}
+
+type storeEntry[V any] struct {
+	value    V
+	expireAt time.Time // zero if the entry never expires
+}
+
+// NewStore returns an empty Store whose entries expire according to clock.
+func NewStore[K comparable, V any](clock ttl.Clock) *Store[K, V] {
+	return &Store[K, V]{
+		clock:   clock,
+		entries: make(map[K]storeEntry[V]),
+	}
+}
+
+// Get implements [ttl.Store].
+func (s *Store[K, V]) Get(ctx context.Context, key K) (value V, TTL time.Duration, ok bool, err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.err != nil {
+		return value, 0, false, s.err
+	}
+
+	e, ok := s.live(key)
+	if !ok {
+		return value, 0, false, nil
+	}
+
+	if !e.expireAt.IsZero() {
+		TTL = e.expireAt.Sub(s.clock.Now())
+	}
+
+	return e.value, TTL, true, nil
+}
+
+// Set implements [ttl.Store].
+func (s *Store[K, V]) Set(ctx context.Context, key K, value V, TTL time.Duration) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	e := storeEntry[V]{value: value}
+	if TTL > 0 {
+		e.expireAt = s.clock.Now().Add(TTL)
+	}
+	s.entries[key] = e
+
+	return nil
+}
+
+// Delete implements [ttl.Store].
+func (s *Store[K, V]) Delete(ctx context.Context, key K) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	delete(s.entries, key)
+
+	return nil
+}
+
+// Range implements [ttl.RangeStore]. f is called without the Store locked, so it may call other
+// methods of the Store.
+func (s *Store[K, V]) Range(ctx context.Context, f func(key K, value V, TTL time.Duration) bool) error {
+	type entry struct {
+		key   K
+		value V
+		TTL   time.Duration
+	}
+
+	s.mtx.Lock()
+
+	if s.err != nil {
+		s.mtx.Unlock()
+		return s.err
+	}
+
+	now := s.clock.Now()
+	entries := make([]entry, 0, len(s.entries))
+
+	for key := range s.entries {
+		if e, ok := s.live(key); ok {
+			var TTL time.Duration
+			if !e.expireAt.IsZero() {
+				TTL = e.expireAt.Sub(now)
+			}
+			entries = append(entries, entry{key, e.value, TTL})
+		}
+	}
+
+	s.mtx.Unlock()
+
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !f(e.key, e.value, e.TTL) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Len returns the number of unexpired entries in the Store.
+func (s *Store[K, V]) Len() (n int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for key := range s.entries {
+		if _, ok := s.live(key); ok {
+			n++
+		}
+	}
+
+	return
+}
+
+// Fail makes every subsequent call to the methods of [ttl.RangeStore] return err, until Fail is
+// called with nil.
+func (s *Store[K, V]) Fail(err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.err = err
+}
+
+// live returns the entry of key if it has not expired. The caller must hold the lock.
+func (s *Store[K, V]) live(key K) (storeEntry[V], bool) {
+	e, ok := s.entries[key]
+	if ok && !e.expireAt.IsZero() && !s.clock.Now().Before(e.expireAt) {
+		return e, false
+	}
+
+	return e, ok
+}
@@ -0,0 +1,254 @@
+// Package ttltest provides a model-based test harness for ttl containers. It runs randomized
+// sequences of operations against a container under test and a simple reference implementation,
+// and reports any difference in behaviour. It can be used to validate alternative implementations
+// and storage modes in the same way as the ttl package validates [ttl.Map].
+//
+// The harness does not advance time, so the container under test must use a default TTL that is
+// much longer than the test run. Tests that exercise expiry can use a [Clock] instead, and tests of
+// maps with a backing store can use a [Store].
+package ttltest
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// Target is the set of [ttl.Map] operations exercised by the harness, using int keys and values.
+// *ttl.Map[int, int] satisfies Target.
+type Target interface {
+	Load(key int) (value int, ok bool)
+	LoadPassive(key int) (value int, ok bool)
+	Store(key int, value int)
+	Delete(key int)
+	Rename(oldKey int, newKey int) bool
+	Touch(key int) bool
+	Length() int
+}
+
+// OpKind identifies an operation performed by the harness.
+type OpKind uint8
+
+const (
+	OpLoad OpKind = iota
+	OpLoadPassive
+	OpStore
+	OpDelete
+	OpRename
+	OpTouch
+	OpLength
+
+	numOpKinds
+)
+
+func (k OpKind) String() string {
+	switch k {
+	case OpLoad:
+		return "Load"
+	case OpLoadPassive:
+		return "LoadPassive"
+	case OpStore:
+		return "Store"
+	case OpDelete:
+		return "Delete"
+	case OpRename:
+		return "Rename"
+	case OpTouch:
+		return "Touch"
+	case OpLength:
+		return "Length"
+	default:
+		return fmt.Sprintf("OpKind(%d)", k)
+	}
+}
+
+// Op is a single operation. Key2 is only used by OpRename, and Value only by OpStore.
+type Op struct {
+	Kind  OpKind
+	Key   int
+	Key2  int
+	Value int
+}
+
+func (o Op) String() string {
+	switch o.Kind {
+	case OpStore:
+		return fmt.Sprintf("Store(%d, %d)", o.Key, o.Value)
+	case OpRename:
+		return fmt.Sprintf("Rename(%d, %d)", o.Key, o.Key2)
+	case OpLength:
+		return "Length()"
+	default:
+		return fmt.Sprintf("%s(%d)", o.Kind, o.Key)
+	}
+}
+
+// RandomOps returns n random operations on keys in the range [0, keys), generated from seed.
+func RandomOps(seed int64, n int, keys int) []Op {
+	r := rand.New(rand.NewSource(seed))
+	ops := make([]Op, n)
+
+	for i := range ops {
+		ops[i] = Op{
+			Kind:  OpKind(r.Intn(int(numOpKinds))),
+			Key:   r.Intn(keys),
+			Key2:  r.Intn(keys),
+			Value: r.Int(),
+		}
+	}
+
+	return ops
+}
+
+// DecodeOps interprets data as a sequence of operations on keys in the range [0, keys). It is
+// intended for use with fuzz tests, so any input produces a valid sequence.
+func DecodeOps(data []byte, keys int) []Op {
+	ops := make([]Op, 0, len(data)/4)
+
+	for ; len(data) >= 4; data = data[4:] {
+		ops = append(ops, Op{
+			Kind:  OpKind(data[0] % byte(numOpKinds)),
+			Key:   int(data[1]) % keys,
+			Key2:  int(data[2]) % keys,
+			Value: int(data[3]),
+		})
+	}
+
+	return ops
+}
+
+// Model is the reference implementation the harness compares a [Target] against. It has no
+// notion of time and is not safe for concurrent use.
+type Model struct {
+	m map[int]int
+}
+
+// NewModel returns an empty Model.
+func NewModel() *Model {
+	return &Model{m: make(map[int]int)}
+}
+
+// Apply performs op on the model and returns its results. For operations that return a bool but
+// no value, the value returned is zero. Operations that return nothing return zero and true.
+func (m *Model) Apply(op Op) (value int, ok bool) {
+	switch op.Kind {
+	case OpLoad, OpLoadPassive:
+		value, ok = m.m[op.Key]
+		return
+	case OpStore:
+		m.m[op.Key] = op.Value
+	case OpDelete:
+		delete(m.m, op.Key)
+	case OpRename:
+		if value, ok = m.m[op.Key]; !ok {
+			return 0, false
+		}
+		delete(m.m, op.Key)
+		m.m[op.Key2] = value
+		return 0, true
+	case OpTouch:
+		_, ok = m.m[op.Key]
+		return 0, ok
+	case OpLength:
+		return len(m.m), true
+	}
+
+	return 0, true
+}
+
+// apply performs op on target and returns its results in the same form as [Model.Apply].
+func apply(target Target, op Op) (value int, ok bool) {
+	switch op.Kind {
+	case OpLoad:
+		return target.Load(op.Key)
+	case OpLoadPassive:
+		return target.LoadPassive(op.Key)
+	case OpStore:
+		target.Store(op.Key, op.Value)
+	case OpDelete:
+		target.Delete(op.Key)
+	case OpRename:
+		return 0, target.Rename(op.Key, op.Key2)
+	case OpTouch:
+		return 0, target.Touch(op.Key)
+	case OpLength:
+		return target.Length(), true
+	}
+
+	return 0, true
+}
+
+// Check applies ops in order to both target and a new [Model], failing t at the first operation
+// whose results differ. target must be empty.
+func Check(t testing.TB, target Target, ops []Op) {
+	t.Helper()
+
+	model := NewModel()
+
+	for i, op := range ops {
+		if !compare(t, fmt.Sprintf("op %d", i), target, model, op) {
+			return
+		}
+	}
+}
+
+// CheckConcurrent runs workers goroutines that each apply opsPerWorker random operations to target,
+// comparing the results against a [Model] per worker. Each worker operates on its own range of
+// keys, so the sequence of operations on any key is deterministic even though the workers run
+// concurrently. When all workers are done, the length of target is compared against the combined
+// models. target must be empty.
+func CheckConcurrent(t testing.TB, target Target, workers int, opsPerWorker int, seed int64) {
+	t.Helper()
+
+	const keysPerWorker = 16
+
+	models := make([]*Model, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		models[w] = NewModel()
+		ops := RandomOps(seed+int64(w), opsPerWorker, keysPerWorker)
+
+		wg.Add(1)
+		go func(w int, model *Model, ops []Op) {
+			defer wg.Done()
+
+			offset := w * keysPerWorker
+			for i, op := range ops {
+				op.Key += offset
+				op.Key2 += offset
+
+				if op.Kind == OpLength {
+					continue // the length is shared across workers
+				}
+
+				if !compare(t, fmt.Sprintf("worker %d op %d", w, i), target, model, op) {
+					return
+				}
+			}
+		}(w, models[w], ops)
+	}
+	wg.Wait()
+
+	want := 0
+	for _, model := range models {
+		want += len(model.m)
+	}
+
+	if got := target.Length(); got != want {
+		t.Errorf("Length() after concurrent operations = %d, want %d", got, want)
+	}
+}
+
+func compare(t testing.TB, where string, target Target, model *Model, op Op) bool {
+	gotValue, gotOK := apply(target, op)
+	wantValue, wantOK := model.Apply(op)
+
+	if gotOK != wantOK || (wantOK && gotValue != wantValue) {
+		t.Errorf("%s: %s = (%d, %t), want (%d, %t)", where, op, gotValue, gotOK, wantValue, wantOK)
+		return false
+	}
+
+	return true
+}
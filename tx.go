@@ -0,0 +1,80 @@
+package ttl
+
+import (
+	"time"
+)
+
+// Tx is a transaction on a [Map], passed to the function given to [Map.Update]. Its methods read
+// and write the [Map] while its lock is held, so that changes to several keys (such as moving a
+// value from one key to another) are seen by other goroutines all at once. A Tx must not be used
+// after the function it was passed to returns.
+type Tx[K comparable, V any] struct {
+	shard func(key K) *Map[K, V] // returns the Map holding key, whose lock is held
+	done  bool
+}
+
+// Update calls f with a [Tx] whose methods read and write the [Map] while its write lock is held,
+// so that f can change several keys atomically. Removals are reported to the callbacks, and
+// changes are written through, once f has returned and the lock is released. f must not call
+// methods of the [Map] itself, which would deadlock. Update is safe for concurrent use.
+func (m *Map[K, V]) Update(f func(tx *Tx[K, V])) {
+	m.lock()
+	defer m.unlock()
+
+	tx := &Tx[K, V]{shard: func(K) *Map[K, V] { return m }}
+	defer tx.finish()
+
+	f(tx)
+}
+
+// Get returns the value of key, as well as a bool indicating whether it was found, like
+// [Map.Load]. If the [Map] refreshes on load, the last access time of the key is updated.
+func (tx *Tx[K, V]) Get(key K) (value V, ok bool) {
+	m := tx.lookup(key)
+
+	it, ok := m.liveItemLocked(key)
+	if !ok {
+		return
+	}
+
+	it.accesses.Add(1)
+
+	if it.refreshesOnLoad(m.refreshOnLoad) {
+		it.touch(m.now())
+	}
+
+	return it.value, true
+}
+
+// Set stores value with the default time to live, like [Map.Store].
+func (tx *Tx[K, V]) Set(key K, value V) {
+	tx.lookup(key).storeLocked(key, value)
+}
+
+// SetWithTTL stores value with a custom time to live, like [Map.StoreWithTTL].
+func (tx *Tx[K, V]) SetWithTTL(key K, value V, TTL time.Duration) {
+	tx.lookup(key).storeWithTTLLocked(key, value, TTL)
+}
+
+// Delete removes key, like [Map.Delete].
+func (tx *Tx[K, V]) Delete(key K) {
+	m := tx.lookup(key)
+
+	if _, ok := m.m[key]; ok {
+		m.removeLocked(key, ReasonDeleted)
+	}
+}
+
+// lookup returns the Map holding key, panicking if the transaction has finished.
+func (tx *Tx[K, V]) lookup(key K) *Map[K, V] {
+	if tx.done {
+		panic("ttl: Tx used after its Update returned")
+	}
+
+	return tx.shard(key)
+}
+
+// finish marks the transaction as finished.
+func (tx *Tx[K, V]) finish() {
+	tx.done = true
+}
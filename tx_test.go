@@ -0,0 +1,137 @@
+package ttl_test
+
+import (
+	"sync"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestUpdate() {
+	var removed []string
+
+	var m *ttl.Map[string, int]
+	m = ttl.New[string, int](
+		ttl.WithClock(ttltest.NewClock(time.Unix(1700000000, 0))),
+		ttl.WithPruneInterval(0),
+		ttl.WithOnRemove(func(key string, _ int, _ ttl.RemovalReason) {
+			// Callbacks run once the lock is released, so they may use the Map
+			m.Length()
+			removed = append(removed, key)
+		}))
+	defer m.Close()
+
+	m.Store("a", 1)
+
+	m.Update(func(tx *ttl.Tx[string, int]) {
+		v, ok := tx.Get("a")
+		s.True(ok)
+
+		tx.Delete("a")
+		tx.SetWithTTL("b", v, time.Hour)
+
+		_, ok = tx.Get("a")
+		s.False(ok)
+		v, ok = tx.Get("b")
+		s.True(ok)
+		s.Equal(1, v)
+
+		s.Empty(removed)
+	})
+
+	s.Equal([]string{"a"}, removed)
+
+	remaining, ok := m.TTL("b")
+	s.True(ok)
+	s.Equal(time.Hour, remaining)
+
+	var escaped *ttl.Tx[string, int]
+	m.Update(func(tx *ttl.Tx[string, int]) {
+		tx.Set("c", 3)
+		escaped = tx
+	})
+
+	v, _ := m.Load("c")
+	s.Equal(3, v)
+	s.Panics(func() { escaped.Set("d", 4) })
+}
+
+func (s *MapTestSuite) TestShardedUpdate() {
+	m := ttl.NewShardedMap[int, int](ttl.WithShards(4), ttl.WithPruneInterval(0))
+	defer m.Close()
+
+	const keys = 16
+	for i := 0; i < keys; i++ {
+		m.Store(i, 100)
+	}
+
+	var wg sync.WaitGroup
+
+	// Transfers between keys, which are mostly in different shards, keep the total unchanged
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+
+		go func(g int) {
+			defer wg.Done()
+
+			for i := 0; i < 100; i++ {
+				from, to := (g+i)%keys, (g+i*7+1)%keys
+
+				m.Update(func(tx *ttl.Tx[int, int]) {
+					a, _ := tx.Get(from)
+					b, _ := tx.Get(to)
+
+					if from != to && a > 0 {
+						tx.Set(from, a-1)
+						tx.Set(to, b+1)
+					}
+				})
+			}
+		}(g)
+	}
+
+	wg.Wait()
+
+	total := 0
+	m.Range(func(_ int, value int) bool {
+		total += value
+		return true
+	})
+	s.Equal(keys*100, total)
+}
+
+func (s *MapTestSuite) TestShardedUpdateCallback() {
+	var m *ttl.ShardedMap[int, int]
+	var removed []int
+
+	m = ttl.NewShardedMap[int, int](
+		ttl.WithShards(4),
+		ttl.WithHasher(func(key int) uint64 { return uint64(key) }),
+		ttl.WithPruneInterval(0),
+		ttl.WithOnRemove(func(key int, _ int, _ ttl.RemovalReason) {
+			// Every shard is unlocked before callbacks run, including those of lower shards
+			m.Load(0)
+			removed = append(removed, key)
+		}))
+	defer m.Close()
+
+	m.Store(3, 3)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		m.Update(func(tx *ttl.Tx[int, int]) {
+			tx.Delete(3)
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		s.FailNow("Update deadlocked")
+	}
+
+	s.Equal([]int{3}, removed)
+}
@@ -0,0 +1,166 @@
+package ttl
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Value holds a single value that expires once its time to live has elapsed since it was set, for
+// caching one expensive thing (such as a token or a configuration document) without a whole [Map].
+// A Value has no background goroutine, so it needs no closing: an expired value is simply not
+// returned. Loading the value does not extend its time to live.
+//
+// A Value is configured using the same [Option]s as a [Map]. [WithTTL] and [WithClock] apply;
+// other options are ignored.
+//
+// Value is safe for concurrent use.
+type Value[V any] struct {
+	clock      Clock
+	defaultTTL time.Duration
+
+	mtx       sync.Mutex
+	value     V
+	set       bool
+	expireAt  int64        // in Unix nanoseconds, or zero if the value never expires
+	computing *loadCall[V] // the call of GetOrCompute in progress, if any
+}
+
+// NewValue returns a new, empty [Value] configured by opts.
+func NewValue[V any](opts ...Option) *Value[V] {
+	o := defaultOptions()
+	o.apply(opts)
+
+	return &Value[V]{
+		clock:      o.clock,
+		defaultTTL: o.ttl,
+	}
+}
+
+// Set sets the value with the default time to live. Set is safe for concurrent use.
+func (v *Value[V]) Set(value V) {
+	v.SetWithTTL(value, v.defaultTTL)
+}
+
+// SetWithTTL sets the value with a custom time to live. A zero or negative TTL means the value
+// never expires. SetWithTTL is safe for concurrent use.
+func (v *Value[V]) SetWithTTL(value V, TTL time.Duration) {
+	now := v.clock.Now()
+
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	v.setLocked(value, now, TTL)
+}
+
+// Get returns the value, as well as a bool indicating whether it is set and has not expired. Get
+// is safe for concurrent use.
+func (v *Value[V]) Get() (value V, ok bool) {
+	now := v.clock.Now().UnixNano()
+
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	return v.getLocked(now)
+}
+
+// GetOrCompute returns the value if it is set and has not expired. Otherwise, it calls compute and
+// sets the value it returns with the default time to live. If compute returns an error, the value
+// is not set and the error is returned. If compute panics, the value is not set, the panic is
+// propagated, and the calls waiting for compute return an error.
+//
+// Concurrent calls while compute is running wait for it and share its result, so compute is
+// called once however many goroutines find the value expired. GetOrCompute is safe for concurrent
+// use, but compute must not call methods of the [Value].
+func (v *Value[V]) GetOrCompute(compute func() (V, error)) (value V, err error) {
+	v.mtx.Lock()
+
+	if value, ok := v.getLocked(v.clock.Now().UnixNano()); ok {
+		v.mtx.Unlock()
+		return value, nil
+	}
+
+	if c := v.computing; c != nil {
+		v.mtx.Unlock()
+		<-c.done
+		return c.value, c.err
+	}
+
+	c := &loadCall[V]{done: make(chan struct{})}
+	v.computing = c
+	v.mtx.Unlock()
+
+	// The call is finished even if compute panics, so that later calls do not wait for it. The
+	// calls waiting for it get an error rather than a zero value, and the panic is propagated.
+	defer func() {
+		p := recover()
+		if p != nil {
+			c.err = fmt.Errorf("ttl: GetOrCompute's compute panicked: %v", p)
+		}
+
+		v.mtx.Lock()
+		v.computing = nil
+		v.mtx.Unlock()
+
+		close(c.done)
+
+		if p != nil {
+			panic(p)
+		}
+	}()
+
+	c.value, c.err = compute()
+
+	if c.err == nil {
+		v.SetWithTTL(c.value, v.defaultTTL)
+	}
+
+	return c.value, c.err
+}
+
+// TTL returns the time to live remaining for the value, as well as a bool indicating whether it is
+// set and has not expired. If the value never expires, the remaining time returned is [NoExpiry].
+// TTL is safe for concurrent use.
+func (v *Value[V]) TTL() (remaining time.Duration, ok bool) {
+	now := v.clock.Now().UnixNano()
+
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	if _, ok = v.getLocked(now); !ok {
+		return 0, false
+	}
+
+	if v.expireAt == 0 {
+		return NoExpiry, true
+	}
+
+	return time.Duration(v.expireAt - now), true
+}
+
+// Clear unsets the value. Clear is safe for concurrent use.
+func (v *Value[V]) Clear() {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	var zero V
+	v.value, v.set = zero, false
+}
+
+// getLocked returns the value if it is set and has not expired as of now, in Unix nanoseconds.
+// The caller must hold the lock.
+func (v *Value[V]) getLocked(now int64) (value V, ok bool) {
+	if !v.set || expiredAt(v.expireAt, now) {
+		return value, false
+	}
+
+	return v.value, true
+}
+
+// setLocked sets the value to expire TTL after now. The caller must hold the lock.
+func (v *Value[V]) setLocked(value V, now time.Time, TTL time.Duration) {
+	v.value, v.set, v.expireAt = value, true, 0
+	if TTL > 0 {
+		v.expireAt = now.Add(TTL).UnixNano()
+	}
+}
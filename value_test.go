@@ -0,0 +1,140 @@
+package ttl_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/glenvan/ttl/v2"
+	"github.com/glenvan/ttl/v2/ttltest"
+)
+
+func (s *MapTestSuite) TestValue() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	v := ttl.NewValue[string](
+		ttl.WithClock(clock),
+		ttl.WithTTL(30*time.Second))
+
+	_, ok := v.Get()
+	s.False(ok)
+
+	v.Set("a")
+	got, ok := v.Get()
+	s.True(ok)
+	s.Equal("a", got)
+
+	clock.Advance(10 * time.Second)
+	remaining, ok := v.TTL()
+	s.True(ok)
+	s.Equal(20*time.Second, remaining)
+
+	clock.Advance(20 * time.Second)
+	_, ok = v.Get()
+	s.False(ok)
+
+	v.SetWithTTL("b", 0)
+	clock.Advance(time.Hour)
+	remaining, ok = v.TTL()
+	s.True(ok)
+	s.Equal(ttl.NoExpiry, remaining)
+
+	v.Clear()
+	_, ok = v.Get()
+	s.False(ok)
+}
+
+func (s *MapTestSuite) TestValueGetOrCompute() {
+	clock := ttltest.NewClock(time.Unix(1700000000, 0))
+
+	v := ttl.NewValue[int](
+		ttl.WithClock(clock),
+		ttl.WithTTL(30*time.Second))
+
+	var calls atomic.Int64
+	release := make(chan struct{})
+	compute := func() (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	// Concurrent callers share a single computation
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			got, err := v.GetOrCompute(compute)
+			s.NoError(err)
+			s.Equal(42, got)
+		}()
+	}
+
+	s.Eventually(func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	got, err := v.GetOrCompute(compute)
+	s.NoError(err)
+	s.Equal(42, got)
+
+	clock.Advance(30 * time.Second)
+
+	failed := errors.New("failed")
+	_, err = v.GetOrCompute(func() (int, error) {
+		return 0, failed
+	})
+	s.ErrorIs(err, failed)
+
+	_, ok := v.Get()
+	s.False(ok)
+}
+
+func (s *MapTestSuite) TestValueGetOrComputePanic() {
+	v := ttl.NewValue[int]()
+
+	release := make(chan struct{})
+	panicked := make(chan any, 1)
+	go func() {
+		defer func() { panicked <- recover() }()
+
+		_, _ = v.GetOrCompute(func() (int, error) {
+			<-release
+			panic("compute failed")
+		})
+	}()
+
+	// Give the first call time to start computing, so that this call waits for it
+	time.Sleep(s.sleepTime)
+
+	waited := make(chan error, 1)
+	go func() {
+		got, err := v.GetOrCompute(func() (int, error) {
+			return 42, nil
+		})
+		s.Zero(got)
+		waited <- err
+	}()
+
+	time.Sleep(s.sleepTime)
+	close(release)
+
+	s.Equal("compute failed", <-panicked)
+
+	err := <-waited
+	s.Error(err)
+	s.Contains(err.Error(), "compute failed")
+
+	_, ok := v.Get()
+	s.False(ok)
+
+	// Later calls compute the value afresh
+	got, err := v.GetOrCompute(func() (int, error) {
+		return 42, nil
+	})
+	s.NoError(err)
+	s.Equal(42, got)
+}
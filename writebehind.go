@@ -0,0 +1,158 @@
+package ttl
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// WriteBehindQueue is the number of writes queued by a [Map] using [WithWriteBehind] before
+// changes to the [Map] wait for them to be made.
+const WriteBehindQueue = 4096
+
+// writeBehind queues the writes of one or more maps and makes them to a store in the background.
+type writeBehind[K comparable, V any] struct {
+	store  Store[K, V]
+	ctx    context.Context
+	logger *slog.Logger
+	queue  chan write[K, V]
+
+	// mtx is held for reading while writes are queued, and for writing when the queue is closed
+	mtx     sync.RWMutex
+	stopped bool
+}
+
+func newWriteBehind[K comparable, V any](
+	store Store[K, V],
+	ctx context.Context,
+	logger *slog.Logger,
+) *writeBehind[K, V] {
+	return &writeBehind[K, V]{
+		store:  store,
+		ctx:    ctx,
+		logger: logger,
+		queue:  make(chan write[K, V], WriteBehindQueue),
+	}
+}
+
+// enqueue queues writes, waiting for room in the queue if it is full. It returns false, without
+// queueing the writes, if the queue has been closed.
+func (b *writeBehind[K, V]) enqueue(writes []write[K, V]) bool {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+
+	if b.stopped {
+		return false
+	}
+
+	for _, w := range writes {
+		b.queue <- w
+	}
+
+	return true
+}
+
+// start starts a goroutine that makes the queued writes every flush interval of o, until stop is
+// closed or the context of o is done (in which case it calls closeMap), then closes the queue and
+// makes the writes remaining in it. It returns a channel that is closed when the goroutine exits.
+func (b *writeBehind[K, V]) start(o options, closeMap func(), stop <-chan bool) <-chan struct{} {
+	finished := make(chan struct{})
+
+	// The ticker is created before the goroutine starts, like the prune ticker
+	var ticker Ticker
+	var tick <-chan time.Time
+	if o.flushInterval > 0 {
+		ticker = o.clock.NewTicker(o.flushInterval)
+		tick = ticker.C()
+	}
+
+	go func() {
+		defer close(finished)
+
+		if ticker != nil {
+			defer ticker.Stop()
+		}
+
+		var batch []write[K, V]
+
+		for {
+			select {
+			case <-o.ctx.Done():
+				closeMap()
+				b.drain(batch)
+				return
+
+			case <-stop:
+				b.drain(batch)
+				return
+
+			case w := <-b.queue:
+				batch = append(batch, w)
+
+				// Without an interval, or with a full queue, writes are made as soon as nothing
+				// more is queued
+				if tick == nil || len(batch) >= WriteBehindQueue {
+					batch = b.collect(batch)
+					b.flush(batch)
+					batch = batch[:0]
+				}
+
+			case <-tick:
+				b.flush(b.collect(batch))
+				batch = batch[:0]
+			}
+		}
+	}()
+
+	return finished
+}
+
+// collect appends the writes that are queued, without waiting, to batch.
+func (b *writeBehind[K, V]) collect(batch []write[K, V]) []write[K, V] {
+	for {
+		select {
+		case w := <-b.queue:
+			batch = append(batch, w)
+		default:
+			return batch
+		}
+	}
+}
+
+// drain closes the queue, then makes the writes in batch and those remaining in the queue.
+func (b *writeBehind[K, V]) drain(batch []write[K, V]) {
+	// Writers waiting for room in the queue hold the read lock, so the queue is emptied while
+	// waiting for the write lock
+	go func() {
+		b.mtx.Lock()
+		b.stopped = true
+		b.mtx.Unlock()
+
+		close(b.queue)
+	}()
+
+	for w := range b.queue {
+		batch = append(batch, w)
+	}
+
+	b.flush(batch)
+}
+
+// flush makes the last write to each key in batch.
+func (b *writeBehind[K, V]) flush(batch []write[K, V]) {
+	if len(batch) == 0 {
+		return
+	}
+
+	last := make(map[K]int, len(batch))
+	for i, w := range batch {
+		last[w.key] = i
+	}
+
+	for i, w := range batch {
+		if last[w.key] == i {
+			writeStore(b.ctx, b.store, b.logger, w)
+		}
+	}
+}